@@ -0,0 +1,99 @@
+package psmb
+
+// alarmLevel get the limit level of the value; high-high and low-low take precedence.
+func alarmLevel(alarm MbtcpAlarmStatus, val float64) AlarmLevel {
+	switch {
+	case alarm.HighHigh != nil && val >= float64(*alarm.HighHigh):
+		return HighHigh
+	case alarm.LowLow != nil && val <= float64(*alarm.LowLow):
+		return LowLow
+	case alarm.High != nil && val >= float64(*alarm.High):
+		return High
+	case alarm.Low != nil && val <= float64(*alarm.Low):
+		return Low
+	default:
+		return Normal
+	}
+}
+
+// alarmEvent build alarm transition event
+func alarmEvent(alarm MbtcpAlarmStatus, state string) *MbtcpAlarmEvent {
+	return &MbtcpAlarmEvent{
+		TimeStamp: alarm.TimeStamp,
+		Name:      alarm.Name,
+		Poll:      alarm.Poll,
+		State:     state,
+		Level:     alarm.Level,
+		Severity:  alarm.Severity,
+		Value:     alarm.Value,
+		Acked:     alarm.Acked,
+	}
+}
+
+// IsAlarmShelved check whether the alarm is shelved at ts (in nanoseconds).
+func IsAlarmShelved(alarm MbtcpAlarmStatus, ts int64) bool {
+	return alarm.ShelvedUntil > ts
+}
+
+// EvaluateAlarm evaluate the alarm rule against poll data at ts (in nanoseconds),
+// 	return the next alarm state and the transition event (nil if no transition).
+// 	Latched alarms stay active after the condition clears until acknowledged;
+// 	shelved alarms keep tracking state but do not raise events.
+func EvaluateAlarm(alarm MbtcpAlarmStatus, data interface{}, ts int64) (MbtcpAlarmStatus, *MbtcpAlarmEvent, error) {
+	if alarm.HighHigh == nil && alarm.High == nil && alarm.Low == nil && alarm.LowLow == nil {
+		return alarm, nil, ErrAlarmNoLimits
+	}
+
+	vals, err := FilterValues(data)
+	if err != nil {
+		return alarm, nil, err
+	}
+	if alarm.Index < 0 || alarm.Index >= len(vals) {
+		return alarm, nil, ErrFilterInvalidIndex
+	}
+
+	prev := alarm
+	alarm.Value = vals[alarm.Index]
+	alarm.Level = alarmLevel(alarm, alarm.Value)
+	alarm.TimeStamp = ts
+
+	var event *MbtcpAlarmEvent
+	switch {
+	case alarm.Level != Normal && (!prev.Active || prev.Level != alarm.Level):
+		// raise or change level; need to acknowledge again
+		alarm.Active = true
+		alarm.Acked = false
+		event = alarmEvent(alarm, AlarmActive)
+	case alarm.Level == Normal && prev.Active && (!alarm.Latch || prev.Acked):
+		// condition cleared
+		alarm.Active = false
+		alarm.Acked = false
+		event = alarmEvent(alarm, AlarmCleared)
+	}
+
+	if IsAlarmShelved(alarm, ts) {
+		return alarm, nil, nil
+	}
+	return alarm, event, nil
+}
+
+// AckAlarm acknowledge the active alarm at ts (in nanoseconds),
+// 	a latched alarm whose condition already cleared becomes cleared.
+func AckAlarm(alarm MbtcpAlarmStatus, ts int64) (MbtcpAlarmStatus, *MbtcpAlarmEvent, error) {
+	if !alarm.Active {
+		return alarm, nil, ErrAlarmNotActive
+	}
+
+	alarm.Acked = true
+	if alarm.Level != Normal {
+		return alarm, nil, nil // still active
+	}
+
+	alarm.Active = false
+	alarm.Acked = false
+	alarm.TimeStamp = ts
+	if IsAlarmShelved(alarm, ts) {
+		return alarm, nil, nil
+	}
+	return alarm, alarmEvent(alarm, AlarmCleared), nil
+}
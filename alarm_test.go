@@ -0,0 +1,93 @@
+package psmb
+
+import (
+	"testing"
+
+	"github.com/takawang/sugar"
+)
+
+func TestAlarm(t *testing.T) {
+
+	s := sugar.New(t)
+	hh, h, l := float32(90), float32(80), float32(10)
+
+	// --------------------------------------------//
+	s.Title("Alarm evaluation tests")
+
+	s.Assert("Alarm without limits should fail", func(logf sugar.Log) bool {
+		_, _, err := EvaluateAlarm(MbtcpAlarmStatus{Name: "a"}, []uint16{1}, 0)
+		logf(err)
+		return err == ErrAlarmNoLimits
+	})
+
+	s.Assert("Non-latched alarm raises, escalates and clears", func(logf sugar.Log) bool {
+		alarm := MbtcpAlarmStatus{Name: "temp", Poll: "p1", HighHigh: &hh, High: &h, Low: &l}
+		var event *MbtcpAlarmEvent
+
+		alarm, event, _ = EvaluateAlarm(alarm, []float32{50}, 1)
+		if event != nil || alarm.Active {
+			return false
+		}
+		alarm, event, _ = EvaluateAlarm(alarm, []float32{85}, 2)
+		if event == nil || event.State != AlarmActive || event.Level != High {
+			return false
+		}
+		alarm, event, _ = EvaluateAlarm(alarm, []float32{86}, 3)
+		if event != nil { // same level, no transition
+			return false
+		}
+		alarm, event, _ = EvaluateAlarm(alarm, []float32{95}, 4)
+		if event == nil || event.Level != HighHigh {
+			return false
+		}
+		alarm, event, _ = EvaluateAlarm(alarm, []float32{50}, 5)
+		logf(alarm, event)
+		return event != nil && event.State == AlarmCleared && !alarm.Active
+	})
+
+	s.Assert("Latched alarm stays active until acknowledged", func(logf sugar.Log) bool {
+		alarm := MbtcpAlarmStatus{Name: "temp", Poll: "p1", Low: &l, Latch: true}
+		var event *MbtcpAlarmEvent
+
+		alarm, event, _ = EvaluateAlarm(alarm, []int16{-5}, 1)
+		if event == nil || event.Level != Low {
+			return false
+		}
+		alarm, event, _ = EvaluateAlarm(alarm, []int16{50}, 2)
+		if event != nil || !alarm.Active { // latched
+			return false
+		}
+		alarm, event, _ = AckAlarm(alarm, 3)
+		logf(alarm, event)
+		if event == nil || event.State != AlarmCleared || alarm.Active {
+			return false
+		}
+		_, _, err := AckAlarm(alarm, 4)
+		return err == ErrAlarmNotActive
+	})
+
+	s.Assert("Acknowledged latched alarm clears with the condition", func(logf sugar.Log) bool {
+		alarm := MbtcpAlarmStatus{Name: "temp", Poll: "p1", High: &h, Latch: true}
+		var event *MbtcpAlarmEvent
+
+		alarm, _, _ = EvaluateAlarm(alarm, []uint16{81}, 1)
+		alarm, event, _ = AckAlarm(alarm, 2)
+		if event != nil || !alarm.Acked || !alarm.Active {
+			return false
+		}
+		alarm, event, _ = EvaluateAlarm(alarm, []uint16{70}, 3)
+		logf(alarm, event)
+		return event != nil && event.State == AlarmCleared
+	})
+
+	s.Assert("Shelved alarm tracks state without events", func(logf sugar.Log) bool {
+		alarm := MbtcpAlarmStatus{Name: "temp", Poll: "p1", High: &h, Index: 1, ShelvedUntil: 10}
+		alarm, event, _ := EvaluateAlarm(alarm, []uint16{0, 81}, 5)
+		if event != nil || !alarm.Active {
+			return false
+		}
+		_, event, _ = EvaluateAlarm(alarm, []uint16{0, 20}, 11)
+		logf(event)
+		return event != nil && event.State == AlarmCleared
+	})
+}
@@ -204,3 +204,250 @@ func BytesToUInt16s(buf []byte, endian Endian) ([]uint16, error) {
 	}
 	return result, nil
 }
+
+// uint32sToRegisters converts uint32 array to registers/uint16 array in four endian orders,
+// 	the inverse of BytesToUInt32s.
+func uint32sToRegisters(data []uint32, endian Endian) []uint16 {
+	result := make([]uint16, 0, 2*len(data))
+	for _, v := range data {
+		a, b, c, d := uint16(v>>24), uint16(v>>16&0xFF), uint16(v>>8&0xFF), uint16(v&0xFF)
+		switch endian {
+		case DCBA: // little endian
+			result = append(result, d<<8|c, b<<8|a)
+		case BADC: // mid-big endian
+			result = append(result, b<<8|a, d<<8|c)
+		case CDAB: // mid-little endian
+			result = append(result, c<<8|d, a<<8|b)
+		default: // big endian
+			result = append(result, a<<8|b, c<<8|d)
+		}
+	}
+	return result
+}
+
+// Float32sToRegisters converts float32 array to registers/uint16 array in four endian orders. i.e.,
+//	BigEndian (0),
+//	LittleEndian (1)
+//	MidBigEndian (2)
+//	MidLittleEndian (3)
+func Float32sToRegisters(data []float32, endian Endian) ([]uint16, error) {
+	if len(data) == 0 {
+		return nil, ErrFloat32sToRegisters
+	}
+	arr := make([]uint32, len(data))
+	for idx, v := range data {
+		arr[idx] = math.Float32bits(v)
+	}
+	return uint32sToRegisters(arr, endian), nil
+}
+
+// Int32sToRegisters converts Int32 array to registers/uint16 array in four endian orders. i.e.,
+//	BigEndian (0),
+//	LittleEndian (1)
+//	MidBigEndian (2)
+//	MidLittleEndian (3)
+func Int32sToRegisters(data []int32, endian Endian) ([]uint16, error) {
+	if len(data) == 0 {
+		return nil, ErrInt32sToRegisters
+	}
+	arr := make([]uint32, len(data))
+	for idx, v := range data {
+		arr[idx] = uint32(v)
+	}
+	return uint32sToRegisters(arr, endian), nil
+}
+
+// UInt32sToRegisters converts UInt32 array to registers/uint16 array in four endian orders. i.e.,
+//	BigEndian (0),
+//	LittleEndian (1)
+//	MidBigEndian (2)
+//	MidLittleEndian (3)
+func UInt32sToRegisters(data []uint32, endian Endian) ([]uint16, error) {
+	if len(data) == 0 {
+		return nil, ErrUInt32sToRegisters
+	}
+	return uint32sToRegisters(data, endian), nil
+}
+
+// Int16sToRegisters converts Int16 array to registers/uint16 array in two endian orders. i.e.,
+//	BigEndian (0) or LittleEndian (1)
+func Int16sToRegisters(data []int16, endian Endian) ([]uint16, error) {
+	if len(data) == 0 {
+		return nil, ErrInt16sToRegisters
+	}
+	arr := make([]uint16, len(data))
+	for idx, v := range data {
+		arr[idx] = uint16(v)
+	}
+	return UInt16sToRegisters(arr, endian)
+}
+
+// UInt16sToRegisters converts UInt16 array to registers/uint16 array in two endian orders. i.e.,
+// 	BigEndian (0) or LittleEndian (1)
+func UInt16sToRegisters(data []uint16, endian Endian) ([]uint16, error) {
+	if len(data) == 0 {
+		return nil, ErrUInt16sToRegisters
+	}
+	result := make([]uint16, len(data))
+	for idx, v := range data {
+		if endian == LittleEndian {
+			result[idx] = v<<8 | v>>8
+		} else { // BigEndian
+			result[idx] = v
+		}
+	}
+	return result, nil
+}
+
+// order64 byte positions (of big-endian ABCDEFGH) in eight 64-bit endian orders
+var order64 = map[Endian][8]int{
+	ABCDEFGH: {0, 1, 2, 3, 4, 5, 6, 7},
+	HGFEDCBA: {7, 6, 5, 4, 3, 2, 1, 0},
+	BADCFEHG: {1, 0, 3, 2, 5, 4, 7, 6},
+	GHEFCDAB: {6, 7, 4, 5, 2, 3, 0, 1},
+	CDABGHEF: {2, 3, 0, 1, 6, 7, 4, 5},
+	EFGHABCD: {4, 5, 6, 7, 0, 1, 2, 3},
+	DCBAHGFE: {3, 2, 1, 0, 7, 6, 5, 4},
+	FEHGBADC: {5, 4, 7, 6, 1, 0, 3, 2},
+}
+
+// bytesToUInt64s converts byte array to uint64 array in eight endian orders,
+// 	big endian if the order is not set.
+func bytesToUInt64s(buf []byte, endian Endian) ([]uint64, bool) {
+	l := len(buf)
+	if l == 0 || l%8 != 0 {
+		return nil, false
+	}
+	positions, ok := order64[endian]
+	if !ok {
+		positions = order64[ABCDEFGH]
+	}
+	result := make([]uint64, l/8)
+	for idx := 0; idx < l/8; idx++ {
+		var v uint64
+		for i, pos := range positions {
+			v |= uint64(buf[8*idx+i]) << uint(8*(7-pos))
+		}
+		result[idx] = v
+	}
+	return result, true
+}
+
+// uint64sToRegisters converts uint64 array to registers/uint16 array in eight endian orders,
+// 	the inverse of bytesToUInt64s.
+func uint64sToRegisters(data []uint64, endian Endian) []uint16 {
+	positions, ok := order64[endian]
+	if !ok {
+		positions = order64[ABCDEFGH]
+	}
+	result := make([]uint16, 0, 4*len(data))
+	for _, v := range data {
+		var buf [8]byte
+		for i, pos := range positions {
+			buf[i] = byte(v >> uint(8*(7-pos)))
+		}
+		for i := 0; i < 8; i += 2 {
+			result = append(result, uint16(buf[i])<<8|uint16(buf[i+1]))
+		}
+	}
+	return result
+}
+
+// BytesToUInt64s converts byte array to UInt64 array in eight endian orders. i.e.,
+//	ABCDEFGH (1), HGFEDCBA (2), BADCFEHG (3), GHEFCDAB (4),
+//	CDABGHEF (5), EFGHABCD (6), DCBAHGFE (7), FEHGBADC (8)
+func BytesToUInt64s(buf []byte, endian Endian) ([]uint64, error) {
+	result, ok := bytesToUInt64s(buf, endian)
+	if !ok {
+		return nil, ErrBytesToUInt64s
+	}
+	return result, nil
+}
+
+// BytesToInt64s converts byte array to Int64 array in eight endian orders. i.e.,
+//	ABCDEFGH (1), HGFEDCBA (2), BADCFEHG (3), GHEFCDAB (4),
+//	CDABGHEF (5), EFGHABCD (6), DCBAHGFE (7), FEHGBADC (8)
+func BytesToInt64s(buf []byte, endian Endian) ([]int64, error) {
+	arr, ok := bytesToUInt64s(buf, endian)
+	if !ok {
+		return nil, ErrBytesToInt64s
+	}
+	result := make([]int64, len(arr))
+	for idx, v := range arr {
+		result[idx] = int64(v)
+	}
+	return result, nil
+}
+
+// BytesToFloat64s converts byte array to float64 array in eight endian orders. i.e.,
+//	ABCDEFGH (1), HGFEDCBA (2), BADCFEHG (3), GHEFCDAB (4),
+//	CDABGHEF (5), EFGHABCD (6), DCBAHGFE (7), FEHGBADC (8)
+func BytesToFloat64s(buf []byte, endian Endian) ([]float64, error) {
+	arr, ok := bytesToUInt64s(buf, endian)
+	if !ok {
+		return nil, ErrBytesToFloat64s
+	}
+	result := make([]float64, len(arr))
+	for idx, v := range arr {
+		result[idx] = math.Float64frombits(v)
+	}
+	return result, nil
+}
+
+// UInt64sToRegisters converts UInt64 array to registers/uint16 array in eight endian orders. i.e.,
+//	ABCDEFGH (1), HGFEDCBA (2), BADCFEHG (3), GHEFCDAB (4),
+//	CDABGHEF (5), EFGHABCD (6), DCBAHGFE (7), FEHGBADC (8)
+func UInt64sToRegisters(data []uint64, endian Endian) ([]uint16, error) {
+	if len(data) == 0 {
+		return nil, ErrUInt64sToRegisters
+	}
+	return uint64sToRegisters(data, endian), nil
+}
+
+// Int64sToRegisters converts Int64 array to registers/uint16 array in eight endian orders. i.e.,
+//	ABCDEFGH (1), HGFEDCBA (2), BADCFEHG (3), GHEFCDAB (4),
+//	CDABGHEF (5), EFGHABCD (6), DCBAHGFE (7), FEHGBADC (8)
+func Int64sToRegisters(data []int64, endian Endian) ([]uint16, error) {
+	if len(data) == 0 {
+		return nil, ErrInt64sToRegisters
+	}
+	arr := make([]uint64, len(data))
+	for idx, v := range data {
+		arr[idx] = uint64(v)
+	}
+	return uint64sToRegisters(arr, endian), nil
+}
+
+// Float64sToRegisters converts float64 array to registers/uint16 array in eight endian orders. i.e.,
+//	ABCDEFGH (1), HGFEDCBA (2), BADCFEHG (3), GHEFCDAB (4),
+//	CDABGHEF (5), EFGHABCD (6), DCBAHGFE (7), FEHGBADC (8)
+func Float64sToRegisters(data []float64, endian Endian) ([]uint16, error) {
+	if len(data) == 0 {
+		return nil, ErrFloat64sToRegisters
+	}
+	arr := make([]uint64, len(data))
+	for idx, v := range data {
+		arr[idx] = math.Float64bits(v)
+	}
+	return uint64sToRegisters(arr, endian), nil
+}
+
+// Int64sToStrings converts Int64 array to decimal string array,
+// 	JSON numbers beyond 2^53 lose precision in most clients.
+func Int64sToStrings(data []int64) []string {
+	result := make([]string, len(data))
+	for idx, v := range data {
+		result[idx] = strconv.FormatInt(v, 10)
+	}
+	return result
+}
+
+// UInt64sToStrings converts UInt64 array to decimal string array,
+// 	JSON numbers beyond 2^53 lose precision in most clients.
+func UInt64sToStrings(data []uint64) []string {
+	result := make([]string, len(data))
+	for idx, v := range data {
+		result[idx] = strconv.FormatUint(v, 10)
+	}
+	return result
+}
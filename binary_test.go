@@ -1,6 +1,7 @@
 package psmb
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 
@@ -272,6 +273,82 @@ func TestBinaryOps(t *testing.T) {
 		return true
 	})
 
+	// --------------------------------------------//
+	s.Title("Typed values to registers tests")
+
+	// equalRegisters compare registers with the desire
+	equalRegisters := func(logf sugar.Log, desire, result []uint16) bool {
+		logf("desire:%v, result:%v", desire, result)
+		if len(result) != len(desire) {
+			return false
+		}
+		for idx := 0; idx < len(desire); idx++ {
+			if result[idx] != desire[idx] {
+				return false
+			}
+		}
+		return true
+	}
+
+	s.Assert("`UInt16sToRegisters` in big and little endian orders", func(logf sugar.Log) bool {
+		big, _ := UInt16sToRegisters([]uint16{4396, 79, 4660, 22136}, BigEndian)
+		little, _ := UInt16sToRegisters([]uint16{11281, 20224, 13330, 30806}, LittleEndian)
+		return equalRegisters(logf, arr, big) && equalRegisters(logf, arr, little)
+	})
+
+	s.Assert("`Int16sToRegisters` in big endian order", func(logf sugar.Log) bool {
+		result, _ := Int16sToRegisters([]int16{4396, 79, 4660, 22136}, BigEndian)
+		negative, _ := Int16sToRegisters([]int16{-2}, BigEndian)
+		return equalRegisters(logf, arr, result) && equalRegisters(logf, []uint16{65534}, negative)
+	})
+
+	s.Assert("`UInt32sToRegisters` in four endian orders", func(logf sugar.Log) bool {
+		abcd, _ := UInt32sToRegisters([]uint32{288096335, 305419896}, ABCD)
+		dcba, _ := UInt32sToRegisters([]uint32{1325411345, 2018915346}, DCBA)
+		badc, _ := UInt32sToRegisters([]uint32{739331840, 873625686}, BADC)
+		cdab, _ := UInt32sToRegisters([]uint32{5181740, 1450709556}, CDAB)
+		return equalRegisters(logf, arr, abcd) && equalRegisters(logf, arr, dcba) &&
+			equalRegisters(logf, arr, badc) && equalRegisters(logf, arr, cdab)
+	})
+
+	s.Assert("`Int32sToRegisters` in four endian orders", func(logf sugar.Log) bool {
+		abcd, _ := Int32sToRegisters([]int32{288096335, 305419896}, ABCD)
+		dcba, _ := Int32sToRegisters([]int32{1325411345, 2018915346}, DCBA)
+		negative, _ := Int32sToRegisters([]int32{-2}, CDAB)
+		return equalRegisters(logf, arr, abcd) && equalRegisters(logf, arr, dcba) &&
+			equalRegisters(logf, []uint16{65534, 65535}, negative)
+	})
+
+	s.Assert("`Float32sToRegisters` in (ABCD) Big Endian order", func(logf sugar.Log) bool {
+		arr2 := []uint16{17820, 16863, 17668, 46924} // 459C41DF4504B74C
+		result, _ := Float32sToRegisters([]float32{5000.234, 2123.456}, ABCD)
+		return equalRegisters(logf, arr2, result)
+	})
+
+	s.Assert("`Float32sToRegisters` and `BytesToFloat32s` round trip in four endian orders", func(logf sugar.Log) bool {
+		desire := []float32{5000.234, -2123.456, 0.001}
+		for _, order := range []Endian{ABCD, DCBA, BADC, CDAB} {
+			registers, _ := Float32sToRegisters(desire, order)
+			bytes, _ := RegistersToBytes(registers)
+			result, _ := BytesToFloat32s(bytes, order)
+			for idx := 0; idx < len(desire); idx++ {
+				logf("order:%d, desire:%f, result:%f", order, desire[idx], result[idx])
+				if result[idx] != desire[idx] {
+					return false
+				}
+			}
+		}
+		return true
+	})
+
+	s.Assert("Empty typed values should fail to convert", func(logf sugar.Log) bool {
+		_, err1 := Float32sToRegisters(nil, ABCD)
+		_, err2 := UInt32sToRegisters(nil, ABCD)
+		_, err3 := UInt16sToRegisters(nil, BigEndian)
+		logf("err:%v, %v, %v", err1, err2, err3)
+		return err1 != nil && err2 != nil && err3 != nil
+	})
+
 	// --------------------------------------------//
 	s.Title("Bytes/registers utility tests")
 
@@ -501,3 +578,118 @@ func TestBinaryOps(t *testing.T) {
 		return true
 	})
 }
+
+func TestBinary64Ops(t *testing.T) {
+
+	s := sugar.New(t)
+	value := uint64(0x0102030405060708)
+
+	// registers of 0x0102030405060708 in eight 64-bit endian orders
+	cases := []struct {
+		name      string
+		order     Endian
+		registers []uint16
+	}{
+		{"ABCDEFGH", ABCDEFGH, []uint16{0x0102, 0x0304, 0x0506, 0x0708}},
+		{"HGFEDCBA", HGFEDCBA, []uint16{0x0807, 0x0605, 0x0403, 0x0201}},
+		{"BADCFEHG", BADCFEHG, []uint16{0x0201, 0x0403, 0x0605, 0x0807}},
+		{"GHEFCDAB", GHEFCDAB, []uint16{0x0708, 0x0506, 0x0304, 0x0102}},
+		{"CDABGHEF", CDABGHEF, []uint16{0x0304, 0x0102, 0x0708, 0x0506}},
+		{"EFGHABCD", EFGHABCD, []uint16{0x0506, 0x0708, 0x0102, 0x0304}},
+		{"DCBAHGFE", DCBAHGFE, []uint16{0x0403, 0x0201, 0x0807, 0x0605}},
+		{"FEHGBADC", FEHGBADC, []uint16{0x0605, 0x0807, 0x0201, 0x0403}},
+		{"default", 0, []uint16{0x0102, 0x0304, 0x0506, 0x0708}},
+	}
+
+	// --------------------------------------------//
+	s.Title("64-bit endian orders tests")
+
+	for _, c := range cases {
+		c := c
+		s.Assert("`BytesToUInt64s` in ("+c.name+") order", func(logf sugar.Log) bool {
+			bytes, _ := RegistersToBytes(c.registers)
+			result, err := BytesToUInt64s(bytes, c.order)
+			logf("desire:%x, result:%x, err:%v", value, result, err)
+			return err == nil && len(result) == 1 && result[0] == value
+		})
+
+		s.Assert("`UInt64sToRegisters` in ("+c.name+") order", func(logf sugar.Log) bool {
+			result, err := UInt64sToRegisters([]uint64{value}, c.order)
+			logf("desire:%x, result:%x, err:%v", c.registers, result, err)
+			if err != nil || len(result) != len(c.registers) {
+				return false
+			}
+			for idx := range result {
+				if result[idx] != c.registers[idx] {
+					return false
+				}
+			}
+			return true
+		})
+	}
+
+	// --------------------------------------------//
+	s.Title("64-bit value types tests")
+
+	roundTrips := []struct {
+		name   string
+		encode func(Endian) ([]uint16, error)
+		decode func([]byte, Endian) (interface{}, error)
+		desire string
+	}{
+		{
+			"Int64",
+			func(o Endian) ([]uint16, error) { return Int64sToRegisters([]int64{-2, 9007199254740993}, o) },
+			func(b []byte, o Endian) (interface{}, error) { return BytesToInt64s(b, o) },
+			"[-2 9007199254740993]",
+		},
+		{
+			"UInt64",
+			func(o Endian) ([]uint16, error) { return UInt64sToRegisters([]uint64{18446744073709551615, 0}, o) },
+			func(b []byte, o Endian) (interface{}, error) { return BytesToUInt64s(b, o) },
+			"[18446744073709551615 0]",
+		},
+		{
+			"Float64",
+			func(o Endian) ([]uint16, error) { return Float64sToRegisters([]float64{3.141592653589793, -0.5}, o) },
+			func(b []byte, o Endian) (interface{}, error) { return BytesToFloat64s(b, o) },
+			"[3.141592653589793 -0.5]",
+		},
+	}
+
+	for _, r := range roundTrips {
+		r := r
+		s.Assert("`"+r.name+"` round trip in eight endian orders", func(logf sugar.Log) bool {
+			for _, c := range cases {
+				registers, err := r.encode(c.order)
+				if err != nil {
+					logf("err:%v", err)
+					return false
+				}
+				bytes, _ := RegistersToBytes(registers)
+				result, err := r.decode(bytes, c.order)
+				if got := fmt.Sprint(result); err != nil || got != r.desire {
+					logf("order:%s, desire:%s, result:%s, err:%v", c.name, r.desire, got, err)
+					return false
+				}
+			}
+			return true
+		})
+	}
+
+	s.Assert("Invalid length should fail to convert", func(logf sugar.Log) bool {
+		_, err1 := BytesToInt64s([]byte{1, 2, 3, 4}, ABCDEFGH)
+		_, err2 := BytesToUInt64s(nil, ABCDEFGH)
+		_, err3 := BytesToFloat64s(make([]byte, 12), ABCDEFGH)
+		_, err4 := Float64sToRegisters(nil, ABCDEFGH)
+		logf("err:%v, %v, %v, %v", err1, err2, err3, err4)
+		return err1 != nil && err2 != nil && err3 != nil && err4 != nil
+	})
+
+	s.Assert("64-bit integers should convert to decimal strings", func(logf sugar.Log) bool {
+		u := UInt64sToStrings([]uint64{18446744073709551615})
+		i := Int64sToStrings([]int64{-9223372036854775808})
+		logf("uint64:%v, int64:%v", u, i)
+		return u[0] == "18446744073709551615" && i[0] == "-9223372036854775808"
+	})
+}
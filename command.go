@@ -2,30 +2,78 @@ package psmb
 
 // command table for upstream services - TCP
 const (
-	CmdMbtcpOnceRead       = "mbtcp.once.read"
-	CmdMbtcpOnceWrite      = "mbtcp.once.write"
-	CmdMbtcpGetTimeout     = "mbtcp.timeout.read"
-	CmdMbtcpSetTimeout     = "mbtcp.timeout.update"
-	CmdMbtcpCreatePoll     = "mbtcp.poll.create"
-	CmdMbtcpUpdatePoll     = "mbtcp.poll.update"
-	CmdMbtcpGetPoll        = "mbtcp.poll.read"
-	CmdMbtcpDeletePoll     = "mbtcp.poll.delete"
-	CmdMbtcpTogglePoll     = "mbtcp.poll.toggle"
-	CmdMbtcpGetPolls       = "mbtcp.polls.read"
-	CmdMbtcpDeletePolls    = "mbtcp.polls.delete"
-	CmdMbtcpTogglePolls    = "mbtcp.polls.toggle"
-	CmdMbtcpImportPolls    = "mbtcp.polls.import"
-	CmdMbtcpExportPolls    = "mbtcp.polls.export"
-	CmdMbtcpGetPollHistory = "mbtcp.poll.history"
-	CmdMbtcpCreateFilter   = "mbtcp.filter.create"
-	CmdMbtcpUpdateFilter   = "mbtcp.filter.update"
-	CmdMbtcpGetFilter      = "mbtcp.filter.read"
-	CmdMbtcpDeleteFilter   = "mbtcp.filter.delete"
-	CmdMbtcpToggleFilter   = "mbtcp.filter.toggle"
-	CmdMbtcpGetFilters     = "mbtcp.filters.read"
-	CmdMbtcpDeleteFilters  = "mbtcp.filters.delete"
-	CmdMbtcpToggleFilters  = "mbtcp.filters.toggle"
-	CmdMbtcpImportFilters  = "mbtcp.filters.import"
-	CmdMbtcpExportFilters  = "mbtcp.filters.export"
-	CmdMbtcpData           = "mbtcp.data" // Poll data
+	CmdMbtcpOnceRead        = "mbtcp.once.read"
+	CmdMbtcpOnceWrite       = "mbtcp.once.write"
+	CmdMbtcpGetTimeout      = "mbtcp.timeout.read"
+	CmdMbtcpSetTimeout      = "mbtcp.timeout.update"
+	CmdMbtcpCreatePoll      = "mbtcp.poll.create"
+	CmdMbtcpUpdatePoll      = "mbtcp.poll.update"
+	CmdMbtcpGetPoll         = "mbtcp.poll.read"
+	CmdMbtcpDeletePoll      = "mbtcp.poll.delete"
+	CmdMbtcpTogglePoll      = "mbtcp.poll.toggle"
+	CmdMbtcpGetPolls        = "mbtcp.polls.read"
+	CmdMbtcpDeletePolls     = "mbtcp.polls.delete"
+	CmdMbtcpTogglePolls     = "mbtcp.polls.toggle"
+	CmdMbtcpImportPolls     = "mbtcp.polls.import"
+	CmdMbtcpExportPolls     = "mbtcp.polls.export"
+	CmdMbtcpGetPollHistory  = "mbtcp.poll.history"
+	CmdMbtcpCreateFilter    = "mbtcp.filter.create"
+	CmdMbtcpUpdateFilter    = "mbtcp.filter.update"
+	CmdMbtcpGetFilter       = "mbtcp.filter.read"
+	CmdMbtcpDeleteFilter    = "mbtcp.filter.delete"
+	CmdMbtcpToggleFilter    = "mbtcp.filter.toggle"
+	CmdMbtcpGetFilters      = "mbtcp.filters.read"
+	CmdMbtcpDeleteFilters   = "mbtcp.filters.delete"
+	CmdMbtcpToggleFilters   = "mbtcp.filters.toggle"
+	CmdMbtcpImportFilters   = "mbtcp.filters.import"
+	CmdMbtcpExportFilters   = "mbtcp.filters.export"
+	CmdMbtcpCreateAlarm     = "mbtcp.alarm.create"
+	CmdMbtcpDeleteAlarm     = "mbtcp.alarm.delete"
+	CmdMbtcpAckAlarm        = "mbtcp.alarm.ack"
+	CmdMbtcpShelveAlarm     = "mbtcp.alarm.shelve"
+	CmdMbtcpGetAlarms       = "mbtcp.alarms.read"
+	CmdMbtcpGetDevices      = "mbtcp.devices.read"
+	CmdMbtcpGetQueues       = "mbtcp.queues.read"
+	CmdMbtcpCreateTagDevice = "mbtcp.tag.device.create"
+	CmdMbtcpDeleteTagDevice = "mbtcp.tag.device.delete"
+	CmdMbtcpGetTagDevices   = "mbtcp.tag.devices.read"
+	CmdMbtcpCreateTag       = "mbtcp.tag.create"
+	CmdMbtcpDeleteTag       = "mbtcp.tag.delete"
+	CmdMbtcpGetTags         = "mbtcp.tags.read"
+	CmdMbtcpReadTag         = "mbtcp.tag.read"
+	CmdMbtcpWriteTag        = "mbtcp.tag.write"
+	CmdMbtcpSubscribeTag    = "mbtcp.tag.subscribe"
+	CmdMbtcpUnsubscribeTag  = "mbtcp.tag.unsubscribe"
+	CmdMbtcpApplyProfile    = "mbtcp.profile.apply"
+	CmdMbtcpData            = "mbtcp.data"          // Poll data
+	CmdMbtcpAlarm           = "mbtcp.alarm"         // Alarm transitions
+	CmdMbtcpDeviceStatus    = "mbtcp.device.status" // Device health transitions
+)
+
+// command table for upstream services - RTU
+const (
+	CmdMbrtuOnceRead       = "mbrtu.once.read"
+	CmdMbrtuOnceWrite      = "mbrtu.once.write"
+	CmdMbrtuCreatePoll     = "mbrtu.poll.create"
+	CmdMbrtuUpdatePoll     = "mbrtu.poll.update"
+	CmdMbrtuGetPoll        = "mbrtu.poll.read"
+	CmdMbrtuDeletePoll     = "mbrtu.poll.delete"
+	CmdMbrtuTogglePoll     = "mbrtu.poll.toggle"
+	CmdMbrtuGetPolls       = "mbrtu.polls.read"
+	CmdMbrtuDeletePolls    = "mbrtu.polls.delete"
+	CmdMbrtuTogglePolls    = "mbrtu.polls.toggle"
+	CmdMbrtuImportPolls    = "mbrtu.polls.import"
+	CmdMbrtuExportPolls    = "mbrtu.polls.export"
+	CmdMbrtuGetPollHistory = "mbrtu.poll.history"
+	CmdMbrtuCreateFilter   = "mbrtu.filter.create"
+	CmdMbrtuUpdateFilter   = "mbrtu.filter.update"
+	CmdMbrtuGetFilter      = "mbrtu.filter.read"
+	CmdMbrtuDeleteFilter   = "mbrtu.filter.delete"
+	CmdMbrtuToggleFilter   = "mbrtu.filter.toggle"
+	CmdMbrtuGetFilters     = "mbrtu.filters.read"
+	CmdMbrtuDeleteFilters  = "mbrtu.filters.delete"
+	CmdMbrtuToggleFilters  = "mbrtu.filters.toggle"
+	CmdMbrtuImportFilters  = "mbrtu.filters.import"
+	CmdMbrtuExportFilters  = "mbrtu.filters.export"
+	CmdMbrtuData           = "mbrtu.data" // Poll data
 )
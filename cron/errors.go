@@ -17,4 +17,7 @@ var (
 
 	// ErrIntervalNotValid error panicked when the interval is not valid
 	ErrIntervalNotValid = errors.New("the interval must be greater than 0")
+
+	// ErrIncorrectCronExpr is the error when the cron expression is incorrect
+	ErrIncorrectCronExpr = errors.New("the cron expression is incorrect")
 )
@@ -0,0 +1,182 @@
+package cron
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSearchYears give up searching the next run of an expression that never matches
+const maxSearchYears = 5
+
+// field bounds and names of a cron expression
+type bounds struct {
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	minutes = bounds{0, 59, nil}
+	hours   = bounds{0, 23, nil}
+	doms    = bounds{1, 31, nil}
+	months  = bounds{1, 12, map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dows = bounds{0, 7, map[string]uint{ // both 0 and 7 are Sunday
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// macros predefined cron expressions
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Expr standard cron expression: minute, hour, day of month, month and day of week.
+type Expr struct {
+	minute, hour, dom, month, dow uint64 // bit sets
+
+	// domStar, dowStar day of month or day of week is `*`
+	domStar, dowStar bool
+}
+
+// ParseExpr parse standard cron expression (e.g., `*/15 * * * *`, `0 8 * * MON-FRI`) or macro (e.g., `@daily`).
+func ParseExpr(spec string) (*Expr, error) {
+	spec = strings.TrimSpace(spec)
+	if macro, ok := macros[strings.ToLower(spec)]; ok {
+		spec = macro
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, ErrIncorrectCronExpr
+	}
+
+	var e Expr
+	var err error
+	if e.minute, err = parseField(fields[0], minutes); err != nil {
+		return nil, err
+	}
+	if e.hour, err = parseField(fields[1], hours); err != nil {
+		return nil, err
+	}
+	if e.dom, err = parseField(fields[2], doms); err != nil {
+		return nil, err
+	}
+	if e.month, err = parseField(fields[3], months); err != nil {
+		return nil, err
+	}
+	if e.dow, err = parseField(fields[4], dows); err != nil {
+		return nil, err
+	}
+	if e.dow&(1<<7) > 0 { // Sunday
+		e.dow |= 1
+	}
+	e.domStar = strings.HasPrefix(fields[2], "*")
+	e.dowStar = strings.HasPrefix(fields[4], "*")
+	return &e, nil
+}
+
+// parseField parse comma separated list of `*`, values, ranges and steps
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		r, step := item, uint(1)
+		if idx := strings.Index(item, "/"); idx >= 0 {
+			n, err := strconv.ParseUint(item[idx+1:], 10, 8)
+			if err != nil || n == 0 {
+				return 0, ErrIncorrectCronExpr
+			}
+			r, step = item[:idx], uint(n)
+		}
+
+		var start, end uint
+		switch idx := strings.Index(r, "-"); {
+		case r == "*":
+			start, end = b.min, b.max
+		case idx > 0:
+			var err error
+			if start, err = parseValue(r[:idx], b); err != nil {
+				return 0, err
+			}
+			if end, err = parseValue(r[idx+1:], b); err != nil {
+				return 0, err
+			}
+		default:
+			var err error
+			if start, err = parseValue(r, b); err != nil {
+				return 0, err
+			}
+			end = start
+			if step > 1 { // `n/step` runs from n to max
+				end = b.max
+			}
+		}
+		if start > end {
+			return 0, ErrIncorrectCronExpr
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// parseValue parse a number or name within bounds
+func parseValue(s string, b bounds) (uint, error) {
+	if v, ok := b.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.ParseUint(s, 10, 8)
+	if err != nil || uint(v) < b.min || uint(v) > b.max {
+		return 0, ErrIncorrectCronExpr
+	}
+	return uint(v), nil
+}
+
+// Next returns the next matched time after t in the location of t,
+// zero time if no time matches within `maxSearchYears`.
+func (e *Expr) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Add(time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+	limit := t.Year() + maxSearchYears
+
+	for t.Year() <= limit {
+		if e.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !e.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if e.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if e.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchDay check day of month and day of week;
+// either matches if both are restricted, as in standard cron.
+func (e *Expr) matchDay(t time.Time) bool {
+	dom := e.dom&(1<<uint(t.Day())) > 0
+	dow := e.dow&(1<<uint(t.Weekday())) > 0
+	if e.domStar || e.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -2,6 +2,7 @@ package cron
 
 import (
 	"fmt"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -237,6 +238,96 @@ func TestScheduler(t *testing.T) {
 		return false
 	})
 
+	s.Assert("`Milliseconds()` job should run at sub-second intervals", func(logf sugar.Log) bool {
+		s := scheduler{
+			jobMap:    make(map[string]*Job),
+			isStopped: make(chan bool),
+			location:  time.Local,
+		}
+		var count int32
+		s.EveryWithName(100, "fast").Milliseconds().Do(func() { atomic.AddInt32(&count, 1) })
+		s.EveryWithName(1, "slow").Seconds().Do(task)
+
+		s.Start()
+		time.Sleep(1050 * time.Millisecond)
+		s.Stop()
+
+		n := atomic.LoadInt32(&count)
+		logf("runs: %d", n)
+		return n >= 8 && n <= 11 && !s.IsRunning()
+	})
+
+	s.Assert("Emergency jobs should run without waiting for the next tick", func(logf sugar.Log) bool {
+		sch, _ := NewScheduler(nil)
+		sch.EveryWithName(1, "slow").Seconds().Do(task)
+		sch.Start()
+		defer sch.Stop()
+		time.Sleep(50 * time.Millisecond) // the run loop is waiting for the next tick
+
+		done := make(chan time.Time)
+		start := time.Now()
+		sch.Emergency().Do(func() { done <- time.Now() })
+		select {
+		case end := <-done:
+			logf("latency: %v", end.Sub(start))
+			return end.Sub(start) < minTick
+		case <-time.After(maxTick):
+			return false
+		}
+	})
+
+	s.Assert("Emergency jobs should run by priority", func(logf sugar.Log) bool {
+		s := scheduler{
+			jobMap:    make(map[string]*Job),
+			isStopped: make(chan bool),
+			location:  time.Local,
+		}
+		var order []int
+		for _, p := range []int{1, 3, 2, 3} {
+			p := p
+			s.Emergency().Priority(p).Do(func() { order = append(order, p) })
+		}
+		s.runEmergency()
+		logf(order)
+		return fmt.Sprint(order) == "[3 3 2 1]" && len(s.ejobs) == 0
+	})
+
+	s.Assert("Due jobs over the budget should be deferred by priority without starvation", func(logf sugar.Log) bool {
+		s := scheduler{
+			jobMap:    make(map[string]*Job),
+			isStopped: make(chan bool),
+			location:  time.Local,
+		}
+		s.Budget(2)
+		runs := make(map[string]int)
+		for name, p := range map[string]int{"write": 3, "read": 2, "fast": 1, "slow": 0} {
+			name := name
+			s.EveryWithName(1, name).Seconds().Priority(p).Do(func() { runs[name]++ })
+		}
+
+		now := time.Now()
+		for _, job := range s.jobs {
+			job.init(now)
+		}
+		now = now.Add(time.Second)
+		s.runPending(now)
+		logf("first pass: %v", runs)
+		if runs["write"] != 1 || runs["read"] != 1 || runs["fast"]+runs["slow"] != 0 {
+			return false
+		}
+
+		// every job is due at each pass, the deferred jobs should catch up by aging
+		for idx := 0; idx < 10; idx++ {
+			now = now.Add(time.Second)
+			s.runPending(now)
+		}
+		logf("runs: %v", runs)
+		if runs["slow"] == 0 || runs["fast"] == 0 || runs["write"]+runs["read"]+runs["fast"]+runs["slow"] != 22 {
+			return false
+		}
+		return s.UpdatePriorityWithName("slow", 5) && s.jobMap["slow"].priority == 5 && !s.UpdatePriorityWithName("none", 1)
+	})
+
 	s.Assert("`Remove()` should delete desired job", func(logf sugar.Log) bool {
 		s := scheduler{
 			isStopped: make(chan bool),
@@ -329,3 +420,111 @@ func TestScheduler(t *testing.T) {
 	})
 
 }
+
+func TestExpr(t *testing.T) {
+
+	s := sugar.New(t)
+
+	s.Title("Cron expression test")
+
+	taipei := time.FixedZone("CST", 8*60*60)
+	from := time.Date(2016, 8, 16, 10, 7, 30, 0, time.UTC) // Tuesday
+
+	s.Assert("`Next()` should return the next matched time", func(logf sugar.Log) bool {
+		cases := []struct {
+			spec string
+			from time.Time
+			next time.Time
+		}{
+			{"* * * * *", from, time.Date(2016, 8, 16, 10, 8, 0, 0, time.UTC)},
+			{"*/15 * * * *", from, time.Date(2016, 8, 16, 10, 15, 0, 0, time.UTC)},
+			{"0 8 * * MON-FRI", from, time.Date(2016, 8, 17, 8, 0, 0, 0, time.UTC)},
+			{"0 8 * * mon-fri", time.Date(2016, 8, 19, 9, 0, 0, 0, time.UTC), time.Date(2016, 8, 22, 8, 0, 0, 0, time.UTC)},
+			{"30 6,18 * * *", from, time.Date(2016, 8, 16, 18, 30, 0, 0, time.UTC)},
+			{"0 0 1 JAN *", from, time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)},
+			{"0 0 13 * 5", from, time.Date(2016, 8, 19, 0, 0, 0, 0, time.UTC)}, // day of month or Friday
+			{"0 0 * * 7", from, time.Date(2016, 8, 21, 0, 0, 0, 0, time.UTC)},  // Sunday
+			{"@hourly", from, time.Date(2016, 8, 16, 11, 0, 0, 0, time.UTC)},
+			{"0 8 * * *", from.In(taipei), time.Date(2016, 8, 17, 8, 0, 0, 0, taipei)},
+			{"0 0 30 2 *", from, time.Time{}}, // never
+		}
+		for _, c := range cases {
+			expr, err := ParseExpr(c.spec)
+			if err != nil {
+				logf("%s: %v", c.spec, err)
+				return false
+			}
+			if next := expr.Next(c.from); !next.Equal(c.next) {
+				logf("%s: %v, expected %v", c.spec, next, c.next)
+				return false
+			}
+		}
+		return true
+	})
+
+	s.Assert("Incorrect expressions should fail", func(logf sugar.Log) bool {
+		for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "0 0 0 * *", "* * * 13 *", "5-1 * * * *", "*/0 * * * *", "* * * * FOO"} {
+			if _, err := ParseExpr(spec); err != ErrIncorrectCronExpr {
+				logf("%q: %v", spec, err)
+				return false
+			}
+		}
+		return true
+	})
+
+	s.Assert("`CronWithName()` should schedule the job in the location", func(logf sugar.Log) bool {
+		s := scheduler{
+			jobMap:    make(map[string]*Job),
+			isStopped: make(chan bool),
+			location:  time.Local,
+		}
+		if _, err := s.CronWithName("* * *", "bad", nil); err == nil {
+			return false
+		}
+		job, err := s.CronWithName("0 8 * * *", "daily", taipei)
+		if err != nil {
+			return false
+		}
+		job.Do(task)
+		job.init(from)
+		logf(job.nextRun)
+		if !job.nextRun.Equal(time.Date(2016, 8, 17, 8, 0, 0, 0, taipei)) || job.shouldRun(from) {
+			return false
+		}
+		job.run()
+		return job.nextRun.Equal(time.Date(2016, 8, 18, 8, 0, 0, 0, taipei)) && len(s.jobMap) == 1
+	})
+}
+
+// benchmarkEmergency measure the latency of emergency jobs queued by enqueue
+func benchmarkEmergency(b *testing.B, enqueue func(s *scheduler, job *Job)) {
+	sch, _ := NewScheduler(nil)
+	s := sch.(*scheduler)
+	s.EveryWithName(1, "slow").Seconds().Do(task)
+	s.Start()
+	defer s.Stop()
+
+	done := make(chan struct{})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		job := s.Emergency()
+		job.dispatch = nil // queued by enqueue
+		job.Do(func() { done <- struct{}{} })
+		enqueue(s, job)
+		<-done
+	}
+}
+
+// BenchmarkEmergencyDispatch emergency jobs wake the run loop
+func BenchmarkEmergencyDispatch(b *testing.B) {
+	benchmarkEmergency(b, (*scheduler).dispatch)
+}
+
+// BenchmarkEmergencyOnTick emergency jobs wait for the next tick, as before
+func BenchmarkEmergencyOnTick(b *testing.B) {
+	benchmarkEmergency(b, func(s *scheduler, job *Job) {
+		s.mutex.Lock()
+		s.ejobs = append(s.ejobs, job)
+		s.mutex.Unlock()
+	})
+}
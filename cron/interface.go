@@ -11,12 +11,19 @@ type Scheduler interface {
 	// Emergency create a emergency job, and adds it to the `Scheduler`
 	Emergency() *Job
 
+	// Budget sets the maximal number of regular jobs to run at each pass of the run loop,
+	// due jobs over the budget are deferred by priority. Zero means unlimited
+	Budget(n int)
+
 	// Every creates a new job, and adds it to the `Scheduler`
 	Every(interval uint64) *Job
 
 	// EveryWithName creates a new job, and adds it to the `Scheduler` and job Map
 	EveryWithName(interval uint64, name string) *Job
 
+	// CronWithName creates a new job run by cron expression in the location, and adds it to the `Scheduler` and job Map
+	CronWithName(spec string, name string, loc *time.Location) (*Job, error)
+
 	// IsRunning returns true if the job  has started
 	IsRunning() bool
 
@@ -36,6 +43,10 @@ type Scheduler interface {
 	// It returns true if the job was found and update interval
 	UpdateIntervalWithName(name string, interval uint64) bool
 
+	// UpdatePriorityWithName update an individual job's priority from the scheduler by name.
+	// It returns true if the job was found and update priority
+	UpdatePriorityWithName(name string, priority int) bool
+
 	// RemoveWithName removes an individual job from the scheduler by name. It returns true
 	// if the job was found and removed from the `Scheduler`
 	RemoveWithName(string) bool
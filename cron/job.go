@@ -44,6 +44,18 @@ type Job struct {
 	// location the time of the job takes place in
 	location *time.Location
 
+	// optional cron expression, overrides interval and unit
+	expr *Expr
+
+	// dispatch queues an emergency job on its first `Do`
+	dispatch func(*Job)
+
+	// priority higher runs first when jobs are due at once
+	priority int
+
+	// the number of passes deferred by the scheduler budget, ages the priority
+	deferred int
+
 	// should run this job flag
 	enabled bool
 }
@@ -81,6 +93,10 @@ func (j *Job) updateInterval(interval uint64) {
 func (j *Job) shouldRun(now time.Time) bool {
 	// check job is enabled or not
 	if j.enabled {
+		// never run if there is no next run (e.g., the cron expression never matches)
+		if j.nextRun.IsZero() {
+			return false
+		}
 		// current time is after or equal to job's scheduled time
 		return now.After(j.nextRun) || now.Equal(j.nextRun)
 	}
@@ -91,13 +107,27 @@ func (j *Job) shouldRun(now time.Time) bool {
 	return false
 }
 
+// rank returns the priority aged by deferred passes, so that deferred jobs are never starved
+func (j *Job) rank() int {
+	return j.priority + j.deferred
+}
+
 // run the job
 func (j *Job) run() {
+	j.deferred = 0
 	j.lastRun = j.nextRun
 	for i, task := range j.tasks {
 		task.Call(j.tasksParams[i])
 	}
-	j.nextRun = j.lastRun.Add(time.Duration(j.interval) * j.unit)
+	j.nextRun = j.next(j.lastRun)
+}
+
+// next returns the next run after t by cron expression if set, otherwise by interval
+func (j *Job) next(t time.Time) time.Time {
+	if j.expr != nil {
+		return j.expr.Next(t.In(j.location))
+	}
+	return t.Add(time.Duration(j.interval) * j.unit)
 }
 
 // isInit returns true if the the `lastRun` and `nextRun` time
@@ -142,7 +172,7 @@ func (j *Job) init(now time.Time) {
 	}
 
 	// create the nextRun
-	j.nextRun = j.lastRun.Add(time.Duration(j.interval) * j.unit)
+	j.nextRun = j.next(j.lastRun)
 }
 
 // Do specifies the taks that should be called executed and the parameters it should be passed
@@ -173,6 +203,11 @@ func (j *Job) Do(task interface{}, params ...interface{}) *Job {
 	j.tasks = append(j.tasks, taskValue)
 	j.tasksParams = append(j.tasksParams, paramValues)
 
+	// queue the emergency job once the task is set
+	if dispatch := j.dispatch; dispatch != nil {
+		j.dispatch = nil
+		dispatch(j)
+	}
 	return j
 }
 
@@ -201,6 +236,23 @@ func (j *Job) At(t string) *Job {
 	return j
 }
 
+// Milliseconds sets a job to run every `x` number of milliseconds
+//
+// Example
+//
+//  // ...
+//	Every(100).Milliseconds().Do(task) // executes the task func every 100 milliseconds
+//
+func (j *Job) Milliseconds() *Job {
+	j.unit = time.Millisecond
+	return j
+}
+
+// Millisecond is an alias for `Milliseconds`
+func (j *Job) Millisecond() *Job {
+	return j.Milliseconds()
+}
+
 // Seconds sets a job to run every `x` number of seconds
 //
 // Example
@@ -328,6 +380,21 @@ func (j *Job) Week() *Job {
 	return j.Weekday(time.Now().Weekday())
 }
 
+// Priority sets the priority of the job, higher runs first when jobs are due at once.
+// Jobs deferred by the scheduler budget gain one priority per deferred pass.
+// The default priority is zero.
+//
+// Example
+//
+//  // ...
+//	Every(1).Second().Priority(10).Do(task) // runs before lower priority jobs due at the same time
+//	Emergency().Priority(30).Do(task)       // set before `Do` since emergency jobs are queued by `Do`
+//
+func (j *Job) Priority(priority int) *Job {
+	j.priority = priority
+	return j
+}
+
 // Location sets the timezone of the job.
 // Jobs created by `NewJob(...)` have a default location of`time.Local`.
 // Jobs created by `Scheduler.Every(...)` have a default timezone of whatever `Scheduler.Location(...)` is set to.
@@ -6,12 +6,20 @@ import (
 	"time"
 )
 
+const (
+	// maxTick the longest wait of the run loop
+	maxTick = 200 * time.Millisecond
+	// minTick the shortest wait of the run loop
+	minTick = 10 * time.Millisecond
+)
+
 // NewScheduler create a new scheduler.
 // Note: the current implementation is not concurrency safe.
 func NewScheduler(conf map[string]string) (Scheduler, error) {
 	return &scheduler{
 		jobMap:    make(map[string]*Job),
 		isStopped: make(chan bool),
+		wake:      make(chan struct{}, 1),
 		location:  time.Local,
 	}, nil
 }
@@ -23,6 +31,8 @@ type scheduler struct {
 	jobs      []*Job
 	isRunning bool
 	isStopped chan bool
+	wake      chan struct{} // wake the run loop for emergency jobs
+	budget    int           // max regular jobs per pass, zero: unlimited
 	location  *time.Location
 	mutex     sync.Mutex
 }
@@ -45,6 +55,13 @@ func (s *scheduler) Less(i, j int) bool {
 	return s.jobs[j].nextRun.After(s.jobs[i].nextRun)
 }
 
+// byRank sorts jobs by aged priority in descending order
+type byRank []*Job
+
+func (b byRank) Len() int           { return len(b) }
+func (b byRank) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byRank) Less(i, j int) bool { return b[i].rank() > b[j].rank() }
+
 // NextRun returns the job and time when the next job should run
 func (s *scheduler) NextRun() (*Job, time.Time) {
 
@@ -118,7 +135,41 @@ func (s *scheduler) EveryWithName(interval uint64, name string) *Job {
 	return job
 }
 
-// Emergency schedules a new emergency job
+// CronWithName schedules a new job run by cron expression (e.g., `0 8 * * MON-FRI`) with name,
+// in the given location, or the location of the scheduler if nil.
+func (s *scheduler) CronWithName(spec string, name string, loc *time.Location) (*Job, error) {
+	expr, err := ParseExpr(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if loc == nil {
+		loc = s.location
+	}
+	// if job exist, remove it;
+	if oldJob, ok := s.jobMap[name]; ok {
+		for i, job := range s.jobs {
+			if oldJob == job {
+				copy(s.jobs[i:], s.jobs[i+1:])
+				s.jobs[len(s.jobs)-1] = nil
+				s.jobs = s.jobs[:len(s.jobs)-1]
+			}
+		}
+	}
+
+	// cheat the interval
+	job := newJob(1).Location(loc)
+	job.expr = expr
+	s.jobMap[name] = job
+	s.jobs = append(s.jobs, job)
+	return job, nil
+}
+
+// Emergency schedules a new emergency job,
+// the job is queued by `Do` and runs immediately without waiting for the next tick.
 // type: *Job
 func (s *scheduler) Emergency() *Job {
 	s.mutex.Lock()
@@ -126,37 +177,82 @@ func (s *scheduler) Emergency() *Job {
 
 	// cheat the interval
 	job := newJob(1).Location(s.location)
+	job.dispatch = s.dispatch
+	return job
+}
+
+// dispatch queues the emergency job and wakes the run loop
+func (s *scheduler) dispatch(job *Job) {
+	s.mutex.Lock()
 	s.ejobs = append(s.ejobs, job)
+	s.mutex.Unlock()
 
-	return job
+	select {
+	case s.wake <- struct{}{}:
+	default: // already signaled
+	}
 }
 
-// runPending runs all of the jobs pending at this time
-func (s *scheduler) runPending(now time.Time) {
+// runEmergency runs all of the queued emergency jobs
+func (s *scheduler) runEmergency() {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	// run emergency jobs
+	s.runEmergencyJobs()
+}
+
+// runEmergencyJobs runs and clears the emergency jobs queue by priority; the caller should hold the lock.
+func (s *scheduler) runEmergencyJobs() {
+	sort.Stable(byRank(s.ejobs))
 	for _, job := range s.ejobs {
 		job.run()
 	}
 	// clear ejobs queue
 	s.ejobs = []*Job{}
+}
+
+// runPending runs all of the jobs pending at this time
+func (s *scheduler) runPending(now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// run emergency jobs
+	s.runEmergencyJobs()
 
 	sort.Sort(s)
-	// run jobs
+	// collect due jobs
+	var due []*Job
 	for _, job := range s.jobs {
 		if !job.isInit() {
 			// set lastRun and nextRun
 			job.init(now)
 		}
 		if job.shouldRun(now) {
-			job.run()
-		} else {
-			// intend to loop through
-			continue
+			due = append(due, job)
+		}
+	}
+
+	// defer jobs over the budget, earlier scheduled first within the same rank
+	if s.budget > 0 && len(due) > s.budget {
+		sort.Stable(byRank(due))
+		for _, job := range due[s.budget:] {
+			job.deferred++
 		}
+		due = due[:s.budget]
 	}
+
+	// run jobs
+	for _, job := range due {
+		job.run()
+	}
+}
+
+// Budget sets the maximal number of regular jobs to run at each pass
+func (s *scheduler) Budget(n int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.budget = n
 }
 
 // Depricated: RunPending runs all of the jobs that are scheduled to run
@@ -222,6 +318,18 @@ func (s *scheduler) UpdateIntervalWithName(name string, interval uint64) bool {
 	return false
 }
 
+// UpdatePriorityWithName update priority by name
+func (s *scheduler) UpdatePriorityWithName(name string, priority int) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if job, ok := s.jobMap[name]; ok {
+		job.Priority(priority)
+		return true
+	}
+	return false
+}
+
 // PauseWithName disable job by name
 func (s *scheduler) PauseWithName(name string) bool {
 	s.mutex.Lock()
@@ -276,7 +384,8 @@ func (s *scheduler) Clear() {
 }
 
 // Start all the pending jobs
-// Add seconds ticker
+// the run loop wakes up when the next job is due, at least every `maxTick`,
+// and as soon as an emergency job is queued.
 func (s *scheduler) Start() {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
@@ -286,35 +395,64 @@ func (s *scheduler) Start() {
 		return
 	}
 
+	// initialize all of the jobs with the start time
+	// so that they are all in sync with the run loop
+	now := time.Now()
+	for _, job := range s.jobs {
+		job.init(now)
+	}
+	s.isRunning = true
+
 	// start the scheduler
-	isStarted := make(chan bool)
-	ticker := time.NewTicker(200 * time.Millisecond)
+	timer := time.NewTimer(s.wait(now))
 	go func() {
+		defer timer.Stop()
 		for {
 			select {
-			case now := <-ticker.C:
-				if !s.isRunning {
-					// initialize all of the jobs with the first ticker time
-					// so that they are all in sync with the run loop
-					for _, job := range s.jobs {
-						job.init(now)
-					}
-					s.isRunning = true
-					isStarted <- true
-				}
+			case now := <-timer.C:
 				s.runPending(now)
+				timer.Reset(s.nextWait(time.Now()))
+			case <-s.wake: // poll jobs stay on the timer
+				s.runEmergency()
 			case <-s.isStopped:
+				s.mutex.Lock()
 				s.isRunning = false
+				s.mutex.Unlock()
 				// send a confirmation message back to the `Stop()` method
 				s.isStopped <- true
 				return
 			}
 		}
 	}()
+}
+
+// nextWait returns the duration until the next job is due
+func (s *scheduler) nextWait(now time.Time) time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.wait(now)
+}
 
-	// wait until he ticker has been started and all of the jobs
-	// have been initialized
-	<-isStarted
+// wait returns the duration until the next job is due, bounded by `minTick` and `maxTick`;
+// the caller should hold the lock.
+func (s *scheduler) wait(now time.Time) time.Duration {
+	if len(s.ejobs) > 0 {
+		return minTick
+	}
+	wait := maxTick
+	for _, job := range s.jobs {
+		if !job.enabled || !job.isInit() {
+			continue
+		}
+		if d := job.nextRun.Sub(now); d < wait {
+			wait = d
+		}
+	}
+	if wait < minTick {
+		return minTick
+	}
+	return wait
 }
 
 // IsRunning returns true if the scheduler is startes
@@ -327,11 +465,9 @@ func (s *scheduler) IsRunning() bool {
 
 // Stop stops the scheduler
 func (s *scheduler) Stop() {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
-	// only send the stop signal if the scheduler has been started
-	if s.isRunning {
+	// only send the stop signal if the scheduler has been started,
+	// don't hold the lock here since the run loop may be running pending jobs
+	if s.IsRunning() {
 		s.isStopped <- true
 		// wait for the ticker to send a confirmation message back through
 		// the stop channel just before it shuts down the ticker loop
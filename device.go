@@ -0,0 +1,63 @@
+package psmb
+
+// deviceEvent build device health transition event
+func deviceEvent(device MbtcpDeviceStatus, ts int64) *MbtcpDeviceEvent {
+	return &MbtcpDeviceEvent{
+		TimeStamp: ts,
+		IP:        device.IP,
+		Port:      device.Port,
+		Slave:     device.Slave,
+		State:     device.State,
+		Failures:  device.Failures,
+		Status:    device.LastStatus,
+	}
+}
+
+// deviceBackoff get poll backoff after the number of failures beyond the threshold,
+// 	doubled for each failure and capped by maxBackoff.
+func deviceBackoff(exceeded int, backoff, maxBackoff int64) int64 {
+	for ; exceeded > 0 && backoff < maxBackoff; exceeded-- {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}
+
+// UpdateDeviceHealth update device health by the response status at ts (in nanoseconds),
+// 	return the next device status and the transition event (nil if no transition).
+// 	After maxFailures consecutive failures the device goes offline and its polls back off
+// 	exponentially from backoff up to maxBackoff (in nanoseconds); a success brings it back online.
+func UpdateDeviceHealth(device MbtcpDeviceStatus, status string, ts int64, maxFailures int, backoff, maxBackoff int64) (MbtcpDeviceStatus, *MbtcpDeviceEvent) {
+	prev := device.State
+	if prev == "" {
+		prev = DeviceOnline // unknown devices are presumed online, no event on the first success
+	}
+	device.LastStatus = status
+
+	if status == "ok" {
+		device.State = DeviceOnline
+		device.Failures = 0
+		device.LastSuccess = ts
+		device.NextPoll = 0
+	} else {
+		device.Failures++
+		if device.Failures >= maxFailures {
+			device.State = DeviceOffline
+			device.NextPoll = ts + deviceBackoff(device.Failures-maxFailures, backoff, maxBackoff)
+		} else {
+			device.State = DeviceOnline // not offline yet
+		}
+	}
+
+	if device.State != prev {
+		return device, deviceEvent(device, ts)
+	}
+	return device, nil
+}
+
+// IsDevicePollDue check whether polls of the device should be issued at ts (in nanoseconds).
+func IsDevicePollDue(device MbtcpDeviceStatus, ts int64) bool {
+	return device.State != DeviceOffline || device.NextPoll <= ts
+}
@@ -0,0 +1,61 @@
+package psmb
+
+import (
+	"testing"
+
+	"github.com/takawang/sugar"
+)
+
+func TestDeviceHealth(t *testing.T) {
+
+	s := sugar.New(t)
+
+	// --------------------------------------------//
+	s.Title("Device health tests")
+
+	s.Assert("First response should bring the device online silently", func(logf sugar.Log) bool {
+		device, event := UpdateDeviceHealth(MbtcpDeviceStatus{IP: "127.0.0.1", Port: "502", Slave: 1}, "ok", 1, 3, 10, 100)
+		logf(device, event)
+		if device.State != DeviceOnline || device.LastSuccess != 1 || event != nil {
+			return false
+		}
+		device, event = UpdateDeviceHealth(MbtcpDeviceStatus{IP: "127.0.0.1", Port: "502", Slave: 2}, "timeout", 1, 1, 10, 100)
+		logf(device, event)
+		return device.State == DeviceOffline && event != nil && event.State == DeviceOffline
+	})
+
+	s.Assert("Consecutive failures should take the device offline and back off", func(logf sugar.Log) bool {
+		device := MbtcpDeviceStatus{State: DeviceOnline}
+		var event *MbtcpDeviceEvent
+
+		for ts := int64(1); ts <= 2; ts++ {
+			if device, event = UpdateDeviceHealth(device, "timeout", ts, 3, 10, 100); event != nil {
+				return false // not yet
+			}
+		}
+		device, event = UpdateDeviceHealth(device, "timeout", 3, 3, 10, 100)
+		logf(device, event)
+		if event == nil || event.State != DeviceOffline || event.Status != "timeout" || device.NextPoll != 13 {
+			return false
+		}
+		if IsDevicePollDue(device, 12) || !IsDevicePollDue(device, 13) {
+			return false
+		}
+		device, event = UpdateDeviceHealth(device, "timeout", 13, 3, 10, 100)
+		if event != nil || device.NextPoll != 13+20 {
+			return false
+		}
+		for ts := int64(14); ts < 20; ts++ {
+			device, _ = UpdateDeviceHealth(device, "timeout", ts, 3, 10, 100)
+		}
+		logf(device)
+		return device.NextPoll == 19+100 // capped
+	})
+
+	s.Assert("A success should bring the device back online", func(logf sugar.Log) bool {
+		device := MbtcpDeviceStatus{State: DeviceOffline, Failures: 5, NextPoll: 100}
+		device, event := UpdateDeviceHealth(device, "ok", 50, 3, 10, 100)
+		logf(device, event)
+		return event != nil && event.State == DeviceOnline && device.Failures == 0 && IsDevicePollDue(device, 50)
+	})
+}
@@ -35,6 +35,74 @@ var (
 	// ErrBytesToUInt16s is the error of BytesToUInt16s conversion.
 	ErrBytesToUInt16s = errors.New("Fail to convert byte array to UInt16 array in two endian orders")
 
+	// ErrFloat32sToRegisters is the error of Float32sToRegisters conversion.
+	ErrFloat32sToRegisters = errors.New("Fail to convert float32 array to registers/uint16 array in four endian orders")
+
+	// ErrInt32sToRegisters is the error of Int32sToRegisters conversion.
+	ErrInt32sToRegisters = errors.New("Fail to convert Int32 array to registers/uint16 array in four endian orders")
+
+	// ErrUInt32sToRegisters is the error of UInt32sToRegisters conversion.
+	ErrUInt32sToRegisters = errors.New("Fail to convert UInt32 array to registers/uint16 array in four endian orders")
+
+	// ErrInt16sToRegisters is the error of Int16sToRegisters conversion.
+	ErrInt16sToRegisters = errors.New("Fail to convert Int16 array to registers/uint16 array in two endian orders")
+
+	// ErrUInt16sToRegisters is the error of UInt16sToRegisters conversion.
+	ErrUInt16sToRegisters = errors.New("Fail to convert UInt16 array to registers/uint16 array in two endian orders")
+
+	// ErrBytesToInt64s is the error of BytesToInt64s conversion.
+	ErrBytesToInt64s = errors.New("Fail to convert byte array to Int64 array in eight endian orders")
+
+	// ErrBytesToUInt64s is the error of BytesToUInt64s conversion.
+	ErrBytesToUInt64s = errors.New("Fail to convert byte array to UInt64 array in eight endian orders")
+
+	// ErrBytesToFloat64s is the error of BytesToFloat64s conversion.
+	ErrBytesToFloat64s = errors.New("Fail to convert byte array to float64 array in eight endian orders")
+
+	// ErrInt64sToRegisters is the error of Int64sToRegisters conversion.
+	ErrInt64sToRegisters = errors.New("Fail to convert Int64 array to registers/uint16 array in eight endian orders")
+
+	// ErrUInt64sToRegisters is the error of UInt64sToRegisters conversion.
+	ErrUInt64sToRegisters = errors.New("Fail to convert UInt64 array to registers/uint16 array in eight endian orders")
+
+	// ErrFloat64sToRegisters is the error of Float64sToRegisters conversion.
+	ErrFloat64sToRegisters = errors.New("Fail to convert float64 array to registers/uint16 array in eight endian orders")
+
 	// ErrInvalidLengthToConvert is the error of invalid length to convert
 	ErrInvalidLengthToConvert = errors.New("Invalid length to convert")
 )
+
+// Filter
+
+var (
+	// ErrFilterInvalidArgs is the error when the length of filter args is invalid.
+	ErrFilterInvalidArgs = errors.New("Invalid filter args")
+
+	// ErrFilterNoData is the error when there is no data to filter.
+	ErrFilterNoData = errors.New("No data to filter")
+
+	// ErrFilterNoLatestData is the error when there is no latest history to compare with.
+	ErrFilterNoLatestData = errors.New("No latest history to compare with")
+
+	// ErrFilterInvalidIndex is the error when the filter index is out of range.
+	ErrFilterInvalidIndex = errors.New("Filter index out of range")
+
+	// ErrFilterNotANumber is the error when the data is not a numeric slice.
+	ErrFilterNotANumber = errors.New("Data is not a numeric array")
+
+	// ErrFilterInvalidTimestamp is the error when the elapsed time since the latest history is not positive.
+	ErrFilterInvalidTimestamp = errors.New("Invalid timestamp of latest history")
+
+	// ErrFilterInvalidMode is the error when the filter mode is not supported.
+	ErrFilterInvalidMode = errors.New("Invalid filter mode")
+)
+
+// Alarm
+
+var (
+	// ErrAlarmNotActive is the error when acknowledging an inactive alarm.
+	ErrAlarmNotActive = errors.New("Alarm is not active")
+
+	// ErrAlarmNoLimits is the error when no limit is set.
+	ErrAlarmNoLimits = errors.New("No alarm limits")
+)
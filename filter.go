@@ -0,0 +1,276 @@
+package psmb
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+)
+
+// FilterState runtime state of a stateful filter, kept in memory by the service,
+// 	never stored with the filter nor exposed to clients.
+type FilterState struct {
+	// Published last published values of deadband and hysteresis filters
+	Published []float64
+	// Active hysteresis states of the last published values
+	Active []bool
+	// Unchanged consecutive unchanged polls of stale filters
+	Unchanged []int
+}
+
+// FilterValues convert numeric slice (i.e., []uint16, []int16, []uint32, []int32, []float32, 64-bit)
+// to float64 array for filter evaluation.
+func FilterValues(data interface{}) ([]float64, error) {
+	rVals := reflect.ValueOf(data)
+	if rVals.Kind() != reflect.Slice && rVals.Kind() != reflect.Array {
+		return nil, ErrFilterNotANumber
+	}
+
+	result := make([]float64, rVals.Len())
+	for idx := 0; idx < rVals.Len(); idx++ {
+		switch v := rVals.Index(idx); v.Kind() {
+		case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+			result[idx] = float64(v.Uint())
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+			result[idx] = float64(v.Int())
+		case reflect.Float32, reflect.Float64:
+			result[idx] = v.Float()
+		default:
+			return nil, ErrFilterNotANumber
+		}
+	}
+	return result, nil
+}
+
+// IsStatefulFilter check whether the filter type keeps state between evaluations.
+func IsStatefulFilter(t FilterType) bool {
+	switch t {
+	case Deadband, PercentDeadband, Hysteresis, Stale:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsHistoryFilter check whether the filter type compares against the latest history.
+func IsHistoryFilter(t FilterType) bool {
+	switch t {
+	case Change, RateOfChange, Stale:
+		return true
+	default:
+		return false
+	}
+}
+
+// latestValues unmarshal the latest marshalled history to float64 array.
+func latestValues(latest string) ([]float64, error) {
+	var prevs []float64
+	if latest == "" {
+		return nil, ErrFilterNoLatestData
+	}
+	if err := json.Unmarshal([]byte(latest), &prevs); err != nil {
+		return nil, ErrFilterNoLatestData
+	}
+	return prevs, nil
+}
+
+// unchangedCounts count the consecutive polls each element stays unchanged.
+func unchangedCounts(state FilterState, vals, prevs []float64) []int {
+	counts := make([]int, len(vals))
+	for idx, val := range vals {
+		if idx < len(prevs) && val == prevs[idx] {
+			counts[idx] = 1
+			if idx < len(state.Unchanged) {
+				counts[idx] += state.Unchanged[idx]
+			}
+		}
+	}
+	return counts
+}
+
+// matchValue check whether a single value passes the comparison filter.
+func matchValue(filter MbtcpFilterStatus, val float64) bool {
+	// args are stored in float32, compare in the same precision
+	v := float32(val)
+	switch filter.Type {
+	case GreaterEqual: // val >= desired value
+		return v >= filter.Arg[0]
+	case Greater: // val > desired value
+		return v > filter.Arg[0]
+	case Equal: // val == desired value
+		return v == filter.Arg[0]
+	case Less: //  val < desired value
+		return v < filter.Arg[0]
+	case LessEqual: // val <= desired value
+		return v <= filter.Arg[0]
+	case InsideRange: // desired 1 < val < desired 2; desired values are sorted.
+		return v > filter.Arg[0] && v < filter.Arg[1]
+	case InsideIncRange: // desired 1 <= val <= desired 2; desired values are sorted.
+		return v >= filter.Arg[0] && v <= filter.Arg[1]
+	case OutsideRange: // val < desired 1 || val > desired 2; desired values are sorted.
+		return v < filter.Arg[0] || v > filter.Arg[1]
+	case OutsideIncRange: // val <= desired 1 || val >= desired 2; desired values are sorted.
+		return v <= filter.Arg[0] || v >= filter.Arg[1]
+	default: // should not reach here
+		return true
+	}
+}
+
+// exceedDeadband check whether the value moves out of the deadband around the last published value.
+func exceedDeadband(filter MbtcpFilterStatus, val, published float64) bool {
+	band := float64(filter.Arg[0])
+	if filter.Type == PercentDeadband {
+		band = math.Abs(published) * band / 100
+	}
+	return math.Abs(val-published) > band
+}
+
+// hysteresisState get the next hysteresis state;
+// 	enter the active state when val >= desired 2, exit when val <= desired 1.
+func hysteresisState(filter MbtcpFilterStatus, val float64, active bool) bool {
+	if active {
+		return val > float64(filter.Arg[0])
+	}
+	return val >= float64(filter.Arg[1])
+}
+
+// UpdateFilterState update the filter state after evaluation, return the next state.
+// 	Deadband and hysteresis filters record the published data,
+// 	stale filters count the unchanged polls on every evaluation.
+func UpdateFilterState(filter MbtcpFilterStatus, state FilterState, data interface{}, latest string, published bool) FilterState {
+	if !IsStatefulFilter(filter.Type) {
+		return state
+	}
+	vals, err := FilterValues(data)
+	if err != nil {
+		return state
+	}
+
+	switch filter.Type {
+	case Stale:
+		prevs, _ := latestValues(latest) // treat no history as changed
+		state.Unchanged = unchangedCounts(state, vals, prevs)
+		return state
+	default:
+		if !published {
+			return state
+		}
+		state.Published = vals
+		if filter.Type == Hysteresis && len(filter.Arg) > 1 {
+			active := make([]bool, len(vals))
+			for idx, val := range vals {
+				prev := idx < len(state.Active) && state.Active[idx]
+				active[idx] = hysteresisState(filter, val, prev)
+			}
+			state.Active = active
+		}
+		return state
+	}
+}
+
+// ApplyFilter evaluate the filter against poll data, return true if the data should be published.
+// 	state is the runtime state of stateful filters (i.e., deadband, hysteresis and stale),
+// 	latest is the latest marshalled history and latestTs is its timestamp in nanoseconds,
+// 	ts is the timestamp of the data in nanoseconds; history is only used by the
+// 	`Change`, `RateOfChange` and `Stale` filters.
+// 	If the filter can not be applied, return true with the reason.
+func ApplyFilter(filter MbtcpFilterStatus, state FilterState, data interface{}, latest string, latestTs, ts int64) (bool, error) {
+	// check args
+	switch filter.Type {
+	case Change:
+		// no args needed
+	case InsideRange, InsideIncRange, OutsideRange, OutsideIncRange, Hysteresis:
+		if len(filter.Arg) < 2 {
+			return true, ErrFilterInvalidArgs
+		}
+	case Stale:
+		if len(filter.Arg) == 0 || filter.Arg[0] < 1 {
+			return true, ErrFilterInvalidArgs
+		}
+	default:
+		if len(filter.Arg) == 0 {
+			return true, ErrFilterInvalidArgs
+		}
+	}
+
+	vals, err := FilterValues(data)
+	if err != nil {
+		return true, err // string or should not reach here
+	}
+	if len(vals) == 0 {
+		return true, ErrFilterNoData
+	}
+
+	var prevs []float64
+	var counts []int
+	var elapsed float64 // seconds since the latest history
+	switch filter.Type {
+	case Change, RateOfChange:
+		if prevs, err = latestValues(latest); err != nil {
+			return true, err
+		}
+		if filter.Type == RateOfChange {
+			if elapsed = float64(ts-latestTs) / 1e9; elapsed <= 0 {
+				return true, ErrFilterInvalidTimestamp
+			}
+		}
+	case Stale:
+		prevs, _ = latestValues(latest) // treat no history as changed
+		counts = unchangedCounts(state, vals, prevs)
+	}
+
+	// match element at index
+	match := func(idx int) bool {
+		switch filter.Type {
+		case Change: // compare with the latest history
+			if idx >= len(prevs) {
+				return true // no history to compare, treat as changed
+			}
+			return vals[idx] != prevs[idx]
+		case Deadband, PercentDeadband: // compare with the last published value
+			if idx >= len(state.Published) {
+				return true // never published
+			}
+			return exceedDeadband(filter, vals[idx], state.Published[idx])
+		case Hysteresis: // compare with the last published state
+			if idx >= len(state.Active) {
+				return true // never published
+			}
+			return hysteresisState(filter, vals[idx], state.Active[idx]) != state.Active[idx]
+		case RateOfChange: // |val - latest| / elapsed > desired rate
+			if idx >= len(prevs) {
+				return true // no history to compare
+			}
+			return math.Abs(vals[idx]-prevs[idx])/elapsed > float64(filter.Arg[0])
+		case Stale: // unchanged for desired polls
+			return float64(counts[idx]) >= float64(filter.Arg[0])
+		default:
+			return matchValue(filter, vals[idx])
+		}
+	}
+
+	switch filter.Mode {
+	case FirstElement:
+		return match(0), nil
+	case AnyElement:
+		for idx := range vals {
+			if match(idx) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case AllElements:
+		for idx := range vals {
+			if !match(idx) {
+				return false, nil
+			}
+		}
+		return true, nil
+	case IndexElement:
+		if filter.Index < 0 || filter.Index >= len(vals) {
+			return true, ErrFilterInvalidIndex
+		}
+		return match(filter.Index), nil
+	default:
+		return true, ErrFilterInvalidMode
+	}
+}
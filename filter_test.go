@@ -0,0 +1,156 @@
+package psmb
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/takawang/sugar"
+)
+
+func TestFilter(t *testing.T) {
+
+	s := sugar.New(t)
+
+	// --------------------------------------------//
+	s.Title("Filter on slice data tests")
+
+	s.Assert("`GreaterEqual` filter on uint16 slice", func(logf sugar.Log) bool {
+		filter := MbtcpFilterStatus{Type: GreaterEqual, Arg: []float32{10}}
+		pass, err := ApplyFilter(filter, FilterState{}, []uint16{12, 3}, "", 0, 0)
+		logf("pass:%v, err:%v", pass, err)
+		if !pass || err != nil {
+			return false
+		}
+		pass, _ = ApplyFilter(filter, FilterState{}, []uint16{3, 12}, "", 0, 0)
+		return !pass
+	})
+
+	s.Assert("`Less` filter on signed slices", func(logf sugar.Log) bool {
+		filter := MbtcpFilterStatus{Type: Less, Arg: []float32{0}}
+		pass16, err16 := ApplyFilter(filter, FilterState{}, []int16{-5}, "", 0, 0)
+		pass32, err32 := ApplyFilter(filter, FilterState{}, []int32{-70000}, "", 0, 0)
+		logf("int16:%v %v, int32:%v %v", pass16, err16, pass32, err32)
+		return pass16 && pass32 && err16 == nil && err32 == nil
+	})
+
+	s.Assert("`InsideRange` filter on float32 slice", func(logf sugar.Log) bool {
+		filter := MbtcpFilterStatus{Type: InsideRange, Arg: []float32{1.5, 2.5}}
+		pass, err := ApplyFilter(filter, FilterState{}, []float32{2.0}, "", 0, 0)
+		logf("pass:%v, err:%v", pass, err)
+		return pass && err == nil
+	})
+
+	s.Assert("`Change` filter compares with the latest history", func(logf sugar.Log) bool {
+		filter := MbtcpFilterStatus{Type: Change}
+		same, _ := ApplyFilter(filter, FilterState{}, []uint16{1, 2}, "[1,2]", 0, 0)
+		diff, _ := ApplyFilter(filter, FilterState{}, []uint16{3, 2}, "[1,2]", 0, 0)
+		logf("same:%v, diff:%v", same, diff)
+		return !same && diff
+	})
+
+	s.Assert("Invalid args or data should pass through", func(logf sugar.Log) bool {
+		if pass, err := ApplyFilter(MbtcpFilterStatus{Type: InsideRange, Arg: []float32{1}}, FilterState{}, []uint16{1}, "", 0, 0); !pass || err != ErrFilterInvalidArgs {
+			return false
+		}
+		if pass, err := ApplyFilter(MbtcpFilterStatus{Type: Equal, Arg: []float32{1}}, FilterState{}, []uint16{}, "", 0, 0); !pass || err != ErrFilterNoData {
+			return false
+		}
+		pass, err := ApplyFilter(MbtcpFilterStatus{Type: Equal, Arg: []float32{1}}, FilterState{}, "112C", "", 0, 0)
+		logf("pass:%v, err:%v", pass, err)
+		return pass && err == ErrFilterNotANumber
+	})
+
+	// --------------------------------------------//
+	s.Title("Filter mode tests")
+
+	data := []uint16{1, 20, 300}
+
+	s.Assert("`AnyElement` mode", func(logf sugar.Log) bool {
+		filter := MbtcpFilterStatus{Type: Greater, Arg: []float32{100}, Mode: AnyElement}
+		pass, _ := ApplyFilter(filter, FilterState{}, data, "", 0, 0)
+		filter.Arg[0] = 1000
+		fail, _ := ApplyFilter(filter, FilterState{}, data, "", 0, 0)
+		logf("pass:%v, fail:%v", pass, fail)
+		return pass && !fail
+	})
+
+	s.Assert("`AllElements` mode", func(logf sugar.Log) bool {
+		filter := MbtcpFilterStatus{Type: GreaterEqual, Arg: []float32{1}, Mode: AllElements}
+		pass, _ := ApplyFilter(filter, FilterState{}, data, "", 0, 0)
+		filter.Arg[0] = 2
+		fail, _ := ApplyFilter(filter, FilterState{}, data, "", 0, 0)
+		logf("pass:%v, fail:%v", pass, fail)
+		return pass && !fail
+	})
+
+	s.Assert("`IndexElement` mode", func(logf sugar.Log) bool {
+		filter := MbtcpFilterStatus{Type: Equal, Arg: []float32{20}, Mode: IndexElement, Index: 1}
+		pass, _ := ApplyFilter(filter, FilterState{}, data, "", 0, 0)
+		filter.Index = 3
+		outOfRange, err := ApplyFilter(filter, FilterState{}, data, "", 0, 0)
+		logf("pass:%v, outOfRange:%v, err:%v", pass, outOfRange, err)
+		return pass && outOfRange && err == ErrFilterInvalidIndex
+	})
+
+	// --------------------------------------------//
+	s.Title("Stateful filter tests")
+
+	s.Assert("`Deadband` filter compares with the last published value", func(logf sugar.Log) bool {
+		filter := MbtcpFilterStatus{Type: Deadband, Arg: []float32{1}}
+		var state FilterState
+		first, _ := ApplyFilter(filter, state, []float32{10}, "", 0, 0)
+		state = UpdateFilterState(filter, state, []float32{10}, "", first)
+		inside, _ := ApplyFilter(filter, state, []float32{10.8}, "", 0, 0)
+		outside, _ := ApplyFilter(filter, state, []float32{11.2}, "", 0, 0)
+		logf("first:%v, inside:%v, outside:%v", first, inside, outside)
+		return first && !inside && outside
+	})
+
+	s.Assert("`PercentDeadband` filter compares with the last published value", func(logf sugar.Log) bool {
+		filter := MbtcpFilterStatus{Type: PercentDeadband, Arg: []float32{10}}
+		var state FilterState
+		state = UpdateFilterState(filter, state, []int16{-200}, "", true)
+		inside, _ := ApplyFilter(filter, state, []int16{-215}, "", 0, 0)
+		outside, _ := ApplyFilter(filter, state, []int16{-225}, "", 0, 0)
+		logf("inside:%v, outside:%v", inside, outside)
+		return !inside && outside
+	})
+
+	s.Assert("`Hysteresis` filter publishes on state transitions", func(logf sugar.Log) bool {
+		filter := MbtcpFilterStatus{Type: Hysteresis, Arg: []float32{20, 30}}
+		var state FilterState
+		state = UpdateFilterState(filter, state, []uint16{25}, "", true) // inactive
+		below, _ := ApplyFilter(filter, state, []uint16{29}, "", 0, 0)
+		enter, _ := ApplyFilter(filter, state, []uint16{30}, "", 0, 0)
+		state = UpdateFilterState(filter, state, []uint16{30}, "", enter) // active
+		stay, _ := ApplyFilter(filter, state, []uint16{21}, "", 0, 0)
+		exit, _ := ApplyFilter(filter, state, []uint16{20}, "", 0, 0)
+		logf("below:%v, enter:%v, stay:%v, exit:%v", below, enter, stay, exit)
+		return !below && enter && !stay && exit
+	})
+
+	s.Assert("`RateOfChange` filter compares with the latest history", func(logf sugar.Log) bool {
+		filter := MbtcpFilterStatus{Type: RateOfChange, Arg: []float32{5}}
+		slow, _ := ApplyFilter(filter, FilterState{}, []float32{14}, "[10]", 0, 1e9)
+		fast, _ := ApplyFilter(filter, FilterState{}, []float32{16}, "[10]", 0, 1e9)
+		invalid, err := ApplyFilter(filter, FilterState{}, []float32{16}, "[10]", 1e9, 1e9)
+		logf("slow:%v, fast:%v, invalid:%v, err:%v", slow, fast, invalid, err)
+		return !slow && fast && invalid && err == ErrFilterInvalidTimestamp
+	})
+
+	s.Assert("`Stale` filter publishes when value stays unchanged for N polls", func(logf sugar.Log) bool {
+		filter := MbtcpFilterStatus{Type: Stale, Arg: []float32{2}}
+		var state FilterState
+		latest := ""
+		results := []bool{}
+		for _, val := range []uint16{7, 7, 7, 8} {
+			data := []uint16{val}
+			pass, _ := ApplyFilter(filter, state, data, latest, 0, 0)
+			state = UpdateFilterState(filter, state, data, latest, pass)
+			results = append(results, pass)
+			latest = fmt.Sprintf("[%d]", val)
+		}
+		logf("results:%v", results)
+		return !results[0] && !results[1] && results[2] && !results[3]
+	})
+}
@@ -0,0 +1,13 @@
+package transport
+
+// [http]
+const (
+	keyListen         = "http.listen"
+	keyTimeout        = "http.timeout"
+	keyFrom           = "http.from"
+	keyMaxPending     = "http.max_pending"
+	defaultListen     = ":8080"
+	defaultTimeout    = 5 // second
+	defaultFrom       = "http"
+	defaultMaxPending = 1024
+)
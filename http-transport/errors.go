@@ -0,0 +1,23 @@
+package transport
+
+import "errors"
+
+var (
+	// ErrInvalidMessageLength is the error when the frames are not [command, json].
+	ErrInvalidMessageLength = errors.New("Invalid message length!")
+
+	// ErrNotFound is the error when no route matches the request.
+	ErrNotFound = errors.New("Resource not found!")
+
+	// ErrInvalidBody is the error when the request body is not a JSON object.
+	ErrInvalidBody = errors.New("Invalid request body!")
+
+	// ErrTimeout is the error when the service does not reply in time.
+	ErrTimeout = errors.New("Request timeout!")
+
+	// ErrNotStarted is the error when the transport is not started yet.
+	ErrNotStarted = errors.New("Transport not started!")
+
+	// ErrTooManyRequests is the error when too many requests are waiting for reply.
+	ErrTooManyRequests = errors.New("Too many pending requests!")
+)
@@ -0,0 +1,98 @@
+package transport
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/taka-wang/psmb"
+)
+
+// route map REST resource to command
+type route struct {
+	// method http method
+	method string
+	// pattern path segments, `{name}` is a path parameter
+	pattern []string
+	// cmd upstream command
+	cmd string
+}
+
+// routes REST resources of the mbtcp command set;
+// 	static segments should precede path parameters (e.g., `/polls/export` before `/polls/{name}`).
+var routes = []route{
+	// one-off requests
+	{http.MethodPost, []string{"once", "read"}, psmb.CmdMbtcpOnceRead},
+	{http.MethodPost, []string{"once", "write"}, psmb.CmdMbtcpOnceWrite},
+	{http.MethodGet, []string{"timeout"}, psmb.CmdMbtcpGetTimeout},
+	{http.MethodPut, []string{"timeout"}, psmb.CmdMbtcpSetTimeout},
+	// polling requests
+	{http.MethodPost, []string{"polls"}, psmb.CmdMbtcpCreatePoll},
+	{http.MethodGet, []string{"polls"}, psmb.CmdMbtcpGetPolls},
+	{http.MethodDelete, []string{"polls"}, psmb.CmdMbtcpDeletePolls},
+	{http.MethodPatch, []string{"polls", "enabled"}, psmb.CmdMbtcpTogglePolls},
+	{http.MethodPost, []string{"polls", "import"}, psmb.CmdMbtcpImportPolls},
+	{http.MethodGet, []string{"polls", "export"}, psmb.CmdMbtcpExportPolls},
+	{http.MethodGet, []string{"polls", "{name}"}, psmb.CmdMbtcpGetPoll},
+	{http.MethodDelete, []string{"polls", "{name}"}, psmb.CmdMbtcpDeletePoll},
+	{http.MethodPatch, []string{"polls", "{name}", "interval"}, psmb.CmdMbtcpUpdatePoll},
+	{http.MethodPatch, []string{"polls", "{name}", "enabled"}, psmb.CmdMbtcpTogglePoll},
+	{http.MethodGet, []string{"polls", "{name}", "history"}, psmb.CmdMbtcpGetPollHistory},
+	// filter requests
+	{http.MethodPost, []string{"filters"}, psmb.CmdMbtcpCreateFilter},
+	{http.MethodGet, []string{"filters"}, psmb.CmdMbtcpGetFilters},
+	{http.MethodDelete, []string{"filters"}, psmb.CmdMbtcpDeleteFilters},
+	{http.MethodPatch, []string{"filters", "enabled"}, psmb.CmdMbtcpToggleFilters},
+	{http.MethodPost, []string{"filters", "import"}, psmb.CmdMbtcpImportFilters},
+	{http.MethodGet, []string{"filters", "export"}, psmb.CmdMbtcpExportFilters},
+	{http.MethodGet, []string{"filters", "{name}"}, psmb.CmdMbtcpGetFilter},
+	{http.MethodPut, []string{"filters", "{name}"}, psmb.CmdMbtcpUpdateFilter},
+	{http.MethodDelete, []string{"filters", "{name}"}, psmb.CmdMbtcpDeleteFilter},
+	{http.MethodPatch, []string{"filters", "{name}", "enabled"}, psmb.CmdMbtcpToggleFilter},
+	// alarm requests
+	{http.MethodPost, []string{"alarms"}, psmb.CmdMbtcpCreateAlarm},
+	{http.MethodGet, []string{"alarms"}, psmb.CmdMbtcpGetAlarms},
+	{http.MethodDelete, []string{"alarms", "{name}"}, psmb.CmdMbtcpDeleteAlarm},
+	{http.MethodPost, []string{"alarms", "{name}", "ack"}, psmb.CmdMbtcpAckAlarm},
+	{http.MethodPost, []string{"alarms", "{name}", "shelve"}, psmb.CmdMbtcpShelveAlarm},
+	// device health requests
+	{http.MethodGet, []string{"devices"}, psmb.CmdMbtcpGetDevices},
+	{http.MethodGet, []string{"queues"}, psmb.CmdMbtcpGetQueues},
+	// tag database requests
+	{http.MethodPost, []string{"tag-devices"}, psmb.CmdMbtcpCreateTagDevice},
+	{http.MethodGet, []string{"tag-devices"}, psmb.CmdMbtcpGetTagDevices},
+	{http.MethodDelete, []string{"tag-devices", "{name}"}, psmb.CmdMbtcpDeleteTagDevice},
+	{http.MethodPost, []string{"tags"}, psmb.CmdMbtcpCreateTag},
+	{http.MethodGet, []string{"tags"}, psmb.CmdMbtcpGetTags},
+	{http.MethodDelete, []string{"tags", "{name}"}, psmb.CmdMbtcpDeleteTag},
+	{http.MethodGet, []string{"tags", "{name}", "value"}, psmb.CmdMbtcpReadTag},
+	{http.MethodPut, []string{"tags", "{name}", "value"}, psmb.CmdMbtcpWriteTag},
+	{http.MethodPost, []string{"tags", "{name}", "subscription"}, psmb.CmdMbtcpSubscribeTag},
+	{http.MethodDelete, []string{"tags", "{name}", "subscription"}, psmb.CmdMbtcpUnsubscribeTag},
+	// device profile requests
+	{http.MethodPost, []string{"profiles", "{profile}"}, psmb.CmdMbtcpApplyProfile},
+}
+
+// match find the route of the request, return the command and path parameters;
+// 	return ErrNotFound if no route matches.
+func match(method, path string) (string, map[string]string, error) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for _, r := range routes {
+		if r.method != method || len(r.pattern) != len(segments) {
+			continue
+		}
+		params := make(map[string]string)
+		matched := true
+		for idx, p := range r.pattern {
+			if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}") {
+				params[strings.Trim(p, "{}")] = segments[idx]
+			} else if p != segments[idx] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return r.cmd, params, nil
+		}
+	}
+	return "", nil, ErrNotFound
+}
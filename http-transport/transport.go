@@ -0,0 +1,197 @@
+// Package transport a REST/HTTP upstream transport (gateway) for proactive service.
+//
+// Requests are mapped to upstream commands (see routes.go), validated by the service
+// and replied synchronously by correlating the response Tid.
+//
+// By taka@cmwang.net
+//
+package transport
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/taka-wang/psmb"
+	"github.com/taka-wang/psmb/viper-conf"
+)
+
+func init() {
+	conf.SetDefault(keyListen, defaultListen)
+	conf.SetDefault(keyTimeout, defaultTimeout)
+	conf.SetDefault(keyFrom, defaultFrom)
+	conf.SetDefault(keyMaxPending, defaultMaxPending)
+}
+
+//@Implement IUpstreamTransport implicitly
+
+type (
+	// header common fields of responses for correlation
+	header struct {
+		Tid    json.Number `json:"tid"`
+		Status string      `json:"status"`
+	}
+
+	// transport http transport
+	transport struct {
+		// mutex for server, handler and pending
+		sync.Mutex
+		// server http server
+		server *http.Server
+		// handler request handler
+		handler func(msg []string)
+		// pending requests waiting for reply: (tid, reply channel)
+		pending map[string]chan string
+		// from request sender name
+		from string
+		// timeout reply timeout
+		timeout time.Duration
+	}
+)
+
+// NewTransport instantiate http transport
+func NewTransport(c map[string]string) (interface{}, error) {
+	return &transport{
+		pending: make(map[string]chan string),
+		from:    conf.GetString(keyFrom),
+		timeout: time.Duration(conf.GetInt(keyTimeout)) * time.Second,
+	}, nil
+}
+
+// Start listen on the configured address and serve REST requests
+func (t *transport) Start(handler func(msg []string)) error {
+	ln, err := net.Listen("tcp", conf.GetString(keyListen))
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: t}
+	t.Lock()
+	t.handler, t.server = handler, server
+	t.Unlock()
+	go func() {
+		if err := server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			conf.Log.WithError(err).Error("HTTP server")
+		}
+	}()
+	return nil
+}
+
+// Stop close http server
+func (t *transport) Stop() {
+	t.Lock()
+	server := t.server
+	t.Unlock()
+	if server != nil {
+		server.Close()
+	}
+}
+
+// Send deliver the response to the waiting request, others are ignored
+func (t *transport) Send(msg []string) error {
+	if len(msg) != 2 {
+		return ErrInvalidMessageLength
+	}
+
+	var h header
+	if err := json.Unmarshal([]byte(msg[1]), &h); err != nil || h.Tid == "" {
+		return nil // not a response
+	}
+
+	t.Lock()
+	ch, ok := t.pending[h.Tid.String()]
+	delete(t.pending, h.Tid.String())
+	t.Unlock()
+	if ok {
+		ch <- msg[1] // buffered
+	}
+	return nil
+}
+
+// reply write json response with status code
+func reply(w http.ResponseWriter, code int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write([]byte(body))
+}
+
+// replyError write error status with status code
+func replyError(w http.ResponseWriter, code int, err error) {
+	bytes, _ := json.Marshal(header{Status: err.Error()})
+	reply(w, code, string(bytes))
+}
+
+// ServeHTTP map REST request to command, pass to handler and wait for the response
+func (t *transport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	t.Lock()
+	handler := t.handler
+	t.Unlock()
+	if handler == nil {
+		replyError(w, http.StatusServiceUnavailable, ErrNotStarted)
+		return
+	}
+
+	cmd, params, err := match(r.Method, r.URL.Path)
+	if err != nil {
+		replyError(w, http.StatusNotFound, err)
+		return
+	}
+
+	// request body should be a JSON object, if any
+	req := make(map[string]interface{})
+	bytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		replyError(w, http.StatusBadRequest, ErrInvalidBody)
+		return
+	}
+	if len(bytes) > 0 {
+		if err := json.Unmarshal(bytes, &req); err != nil {
+			replyError(w, http.StatusBadRequest, ErrInvalidBody)
+			return
+		}
+	}
+	for k, v := range params {
+		req[k] = v
+	}
+	if _, ok := req["from"]; !ok {
+		req["from"] = t.from
+	}
+
+	// generate tid for correlation
+	tid := psmb.NewUpstreamTid()
+	tidStr := strconv.FormatInt(tid, 10)
+	req["tid"] = tid
+
+	payload, _ := json.Marshal(req)
+	ch := make(chan string, 1)
+
+	t.Lock()
+	if len(t.pending) >= conf.GetInt(keyMaxPending) {
+		t.Unlock()
+		replyError(w, http.StatusServiceUnavailable, ErrTooManyRequests)
+		return
+	}
+	t.pending[tidStr] = ch
+	t.Unlock()
+
+	handler([]string{cmd, string(payload)})
+
+	select {
+	case res := <-ch:
+		var h header
+		json.Unmarshal([]byte(res), &h)
+		if h.Status == "ok" {
+			reply(w, http.StatusOK, res)
+		} else {
+			reply(w, http.StatusBadRequest, res)
+		}
+	case <-time.After(t.timeout):
+		t.Lock()
+		delete(t.pending, tidStr)
+		t.Unlock()
+		replyError(w, http.StatusGatewayTimeout, ErrTimeout)
+	}
+}
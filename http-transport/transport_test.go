@@ -0,0 +1,142 @@
+package transport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/taka-wang/psmb"
+	"github.com/taka-wang/psmb/cron"
+	mfilter "github.com/taka-wang/psmb/mem-filter"
+	mreader "github.com/taka-wang/psmb/mem-reader"
+	mwriter "github.com/taka-wang/psmb/mem-writer"
+	history "github.com/taka-wang/psmb/redis-history"
+	psmbtcp "github.com/taka-wang/psmb/tcp"
+	"github.com/taka-wang/psmb/viper-conf"
+	"github.com/takawang/sugar"
+)
+
+// gateway transport instance created by service
+var gateway http.Handler
+
+func init() {
+	psmbtcp.Register("HTTP", func(c map[string]string) (interface{}, error) {
+		tp, err := NewTransport(c)
+		gateway = tp.(http.Handler)
+		return tp, err
+	})
+	psmbtcp.Register("Reader", mreader.NewDataStore)
+	psmbtcp.Register("Writer", mwriter.NewDataStore)
+	psmbtcp.Register("History", history.NewDataStore) // connect lazily
+	psmbtcp.Register("Filter", mfilter.NewDataStore)
+	psmbtcp.Register("Cron", cron.NewScheduler)
+}
+
+// do send REST request to gateway
+func do(method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	gateway.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return w
+}
+
+func TestRoutes(t *testing.T) {
+	s := sugar.New(t)
+
+	s.Assert("Resources should be mapped to commands", func(logf sugar.Log) bool {
+		cmd, params, err := match(http.MethodPatch, "/polls/p1/interval")
+		logf("cmd:%s, params:%v, err:%v", cmd, params, err)
+		if err != nil || cmd != psmb.CmdMbtcpUpdatePoll || params["name"] != "p1" {
+			return false
+		}
+		if cmd, _, _ = match(http.MethodGet, "/polls/export"); cmd != psmb.CmdMbtcpExportPolls {
+			return false
+		}
+		if cmd, _, _ = match(http.MethodGet, "/polls/p1/history/"); cmd != psmb.CmdMbtcpGetPollHistory {
+			return false
+		}
+		_, _, err = match(http.MethodPost, "/polls/p1")
+		return err == ErrNotFound
+	})
+}
+
+func TestGateway(t *testing.T) {
+	s := sugar.New(t)
+
+	conf.Set(keyListen, "127.0.0.1:0")
+	conf.Set(keyTimeout, 1)
+	conf.Set("psmbtcp.upstream_transport", "HTTP")
+	defer conf.Set("psmbtcp.upstream_transport", "")
+
+	srv, err := psmbtcp.NewService("Reader", "Writer", "History", "Filter", "Cron")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Start()
+	defer srv.Stop()
+
+	// wait for service to start
+	for idx := 0; idx < 100 && do(http.MethodGet, "/filters", "").Code == http.StatusServiceUnavailable; idx++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	s.Assert("`POST /filters` and `GET /filters/{name}`", func(logf sugar.Log) bool {
+		w := do(http.MethodPost, "/filters", `{"name":"p1","enabled":true,"type":3,"arg":[1]}`)
+		logf("code:%d, body:%s", w.Code, w.Body.String())
+		if w.Code != http.StatusOK {
+			return false
+		}
+		w = do(http.MethodGet, "/filters/p1", "")
+		logf("code:%d, body:%s", w.Code, w.Body.String())
+		var res psmb.MbtcpFilterStatus
+		json.Unmarshal(w.Body.Bytes(), &res)
+		return w.Code == http.StatusOK && res.Name == "p1" && res.Type == psmb.Equal
+	})
+
+	s.Assert("`POST /polls` and `PATCH /polls/{name}/interval`", func(logf sugar.Log) bool {
+		w := do(http.MethodPost, "/polls", `{"name":"p2","fc":3,"ip":"127.0.0.1","slave":1,"addr":10,"len":4,"interval":10,"enabled":false}`)
+		logf("code:%d, body:%s", w.Code, w.Body.String())
+		if w.Code != http.StatusOK {
+			return false
+		}
+		if w = do(http.MethodPatch, "/polls/p2/interval", `{"interval":20}`); w.Code != http.StatusOK {
+			return false
+		}
+		w = do(http.MethodGet, "/polls/p2", "")
+		logf("code:%d, body:%s", w.Code, w.Body.String())
+		var res psmb.MbtcpPollStatus
+		json.Unmarshal(w.Body.Bytes(), &res)
+		return w.Code == http.StatusOK && res.Interval == 20
+	})
+
+	s.Assert("Service errors should be bad request", func(logf sugar.Log) bool {
+		w := do(http.MethodGet, "/filters/none", "")
+		logf("code:%d, body:%s", w.Code, w.Body.String())
+		return w.Code == http.StatusBadRequest && strings.Contains(w.Body.String(), "status")
+	})
+
+	s.Assert("Invalid body and resource should fail", func(logf sugar.Log) bool {
+		if w := do(http.MethodPost, "/filters", `[1,2]`); w.Code != http.StatusBadRequest {
+			return false
+		}
+		w := do(http.MethodGet, "/unknown", "")
+		logf("code:%d, body:%s", w.Code, w.Body.String())
+		return w.Code == http.StatusNotFound
+	})
+
+	s.Assert("Requests failed to parse should be bad request without waiting", func(logf sugar.Log) bool {
+		start := time.Now()
+		w := do(http.MethodPost, "/once/read", `{"fc":"3","ip":"127.0.0.1","slave":1,"addr":10}`)
+		logf("code:%d, body:%s, elapsed:%v", w.Code, w.Body.String(), time.Since(start))
+		return w.Code == http.StatusBadRequest && strings.Contains(w.Body.String(), psmbtcp.ErrUnmarshal.Error()) &&
+			time.Since(start) < time.Second
+	})
+
+	s.Assert("One-off read without response should timeout", func(logf sugar.Log) bool {
+		w := do(http.MethodPost, "/once/read", `{"fc":3,"ip":"127.0.0.1","slave":1,"addr":10,"len":4}`)
+		logf("code:%d, body:%s", w.Code, w.Body.String())
+		return w.Code == http.StatusGatewayTimeout
+	})
+}
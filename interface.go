@@ -6,6 +6,8 @@
 //
 package psmb
 
+import "time"
+
 //
 // Interfaces
 //
@@ -29,21 +31,38 @@ type (
 		HandleResponse(cmd string, r interface{}) error
 	}
 
+	// WriterTask write task request
+	WriterTask struct {
+		// Cmd zmq frame 1
+		Cmd string `json:"cmd"`
+		// Tid transaction id from client
+		Tid int64 `json:"tid"`
+		// From client name
+		From string `json:"from,omitempty"`
+		// Deadline reply timeout deadline, zero if never expire
+		Deadline time.Time `json:"deadline"`
+	}
+
 	// IWriterTaskDataStore write task interface
-	//	(Tid, Command) map
+	//	(Downstream Tid, WriterTask) map
 	IWriterTaskDataStore interface {
 		// Add add request to write task map,
-		// params: TID, CMD strings.
-		Add(tid, cmd string)
+		// params: downstream TID string, WriterTask.
+		Add(tid string, task WriterTask)
 
 		// Get get request from write task map,
-		// params: TID string,
-		// return: cmd string, exist flag.
-		Get(tid string) (string, bool)
+		// params: downstream TID string,
+		// return: WriterTask, exist flag.
+		Get(tid string) (WriterTask, bool)
 
 		// Delete remove request from write task map
 		// params: TID string.
 		Delete(tid string)
+
+		// Expire remove expired requests from write task map,
+		// params: current time,
+		// return: (downstream TID, WriterTask) map.
+		Expire(now time.Time) map[string]WriterTask
 	}
 
 	// ReaderTask read/poll task request
@@ -54,6 +73,8 @@ type (
 		Cmd string
 		// Req request structure
 		Req interface{}
+		// Deadline one-off request deadline, zero if never expire
+		Deadline time.Time
 	}
 
 	// IReaderTaskDataStore read task interface
@@ -83,11 +104,21 @@ type (
 		// UpdateIntervalByName update poll request interval
 		UpdateIntervalByName(name string, interval uint64) error
 
+		// UpdateIntervalMsByName update poll request interval in milliseconds
+		UpdateIntervalMsByName(name string, intervalMs uint64) error
+
 		// UpdateToggleByName update poll request enabled flag
 		UpdateToggleByName(name string, toggle bool) error
 
 		// UpdateAllToggles update all poll requests enabled flag
 		UpdateAllToggles(toggle bool)
+
+		// UpdateDeadlineByID update one-off request deadline via TID
+		UpdateDeadlineByID(tid string, deadline time.Time) error
+
+		// ExpireTasks remove expired requests from read/poll task map
+		//	map: (TID, ReaderTask)
+		ExpireTasks(now time.Time) map[string]ReaderTask
 	}
 
 	// IHistoryDataStore history interface
@@ -100,6 +131,8 @@ type (
 		GetAll(name string) (map[string]string, error)
 		// GetLatest get latest history
 		GetLatest(name string) (string, error)
+		// GetLatestWithTimestamp get latest history and its timestamp in nanoseconds
+		GetLatestWithTimestamp(name string) (string, int64, error)
 	}
 
 	// IFilterDataStore filter interface
@@ -120,6 +153,64 @@ type (
 		UpdateAllToggles(toggle bool)
 	}
 
+	// IAlarmDataStore alarm interface
+	IAlarmDataStore interface {
+		// Add add or update alarm to alarm map
+		Add(name string, req interface{}) error
+		// Get get alarm from alarm map
+		Get(name string) (interface{}, bool)
+		// GetAll get all alarms from alarm map
+		GetAll() interface{}
+		// GetByPoll get all alarms bound to the poll
+		GetByPoll(poll string) interface{}
+		// Delete delete alarm from alarm map
+		Delete(name string)
+		// DeleteAll delete all alarms from alarm map
+		DeleteAll()
+	}
+
+	// IUpstreamTransport upstream transport interface (i.e., front-end to services),
+	// 	messages are frames in the format: [command, json].
+	IUpstreamTransport interface {
+		// Start start transport, received request frames are passed to handler
+		Start(handler func(msg []string)) error
+		// Stop stop transport
+		Stop()
+		// Send send response or data frames to upstream
+		Send(msg []string) error
+	}
+
+	// IDownstreamDriver in-process downstream driver interface (i.e., in place of modbusd)
+	IDownstreamDriver interface {
+		// Do execute downstream request (i.e., DMbtcpReadReq, DMbtcpWriteReq, DMbtcpTimeout),
+		// return response frames in modbusd format: [command code, response json].
+		Do(req interface{}) ([]string, error)
+		// Close close all connections
+		Close()
+		// Frame downstream frame served (i.e., tcp or rtu), same as frame 1 to modbusd
+		Frame() string
+	}
+
+	// ITagDataStore tag database interface: devices and tags mapped onto device registers
+	ITagDataStore interface {
+		// AddDevice add or update device definition
+		AddDevice(name string, req interface{}) error
+		// GetDevice get device definition
+		GetDevice(name string) (interface{}, bool)
+		// GetDevices get all device definitions
+		GetDevices() interface{}
+		// DeleteDevice delete device definition
+		DeleteDevice(name string)
+		// Add add or update tag
+		Add(name string, req interface{}) error
+		// Get get tag
+		Get(name string) (interface{}, bool)
+		// GetAll get all tags
+		GetAll() interface{}
+		// Delete delete tag
+		Delete(name string)
+	}
+
 	// IConfig config interface
 	IConfig interface {
 		// setLogger init logger function
@@ -0,0 +1,7 @@
+package alarm
+
+// [mem_alarm]
+const (
+	keyMaxCapacity     = "mem_alarm.max_capacity"
+	defaultMaxCapacity = 32
+)
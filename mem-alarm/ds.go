@@ -0,0 +1,113 @@
+// Package alarm an in-memory data store for alarm.
+//
+// Guideline: if error is one of the return, don't duplicately log to output.
+//
+// By taka@cmwang.net
+//
+package alarm
+
+import (
+	"sync"
+
+	"github.com/taka-wang/psmb"
+	"github.com/taka-wang/psmb/viper-conf"
+)
+
+var maxCapacity int
+
+func init() {
+	conf.SetDefault(keyMaxCapacity, defaultMaxCapacity)
+	maxCapacity = conf.GetInt(keyMaxCapacity)
+}
+
+//@Implement IAlarmDataStore implicitly
+
+// dataStore alarm map
+type dataStore struct {
+	// read writer mutex
+	sync.RWMutex
+	// m key-value map: (name, psmb.MbtcpAlarmStatus)
+	m map[string]psmb.MbtcpAlarmStatus
+}
+
+// NewDataStore instantiate alarm map
+func NewDataStore(conf map[string]string) (interface{}, error) {
+	return &dataStore{
+		m: make(map[string]psmb.MbtcpAlarmStatus),
+	}, nil
+}
+
+// Add add or update alarm to alarm map
+func (ds *dataStore) Add(name string, req interface{}) error {
+	if name == "" {
+		return ErrInvalidAlarmName
+	}
+	r, ok := req.(psmb.MbtcpAlarmStatus)
+	if !ok {
+		return ErrInvalidAlarmName
+	}
+
+	ds.Lock()
+	defer ds.Unlock()
+	if _, exist := ds.m[name]; !exist && len(ds.m)+1 > maxCapacity {
+		return ErrOutOfCapacity
+	}
+	ds.m[name] = r
+	return nil
+}
+
+// Get get alarm from alarm map
+func (ds *dataStore) Get(name string) (interface{}, bool) {
+	ds.RLock()
+	req, ok := ds.m[name]
+	ds.RUnlock()
+	return req, ok
+}
+
+// GetAll get all alarms from alarm map
+func (ds *dataStore) GetAll() interface{} {
+	arr := []psmb.MbtcpAlarmStatus{}
+	ds.RLock()
+	for _, v := range ds.m {
+		arr = append(arr, v)
+	}
+	ds.RUnlock()
+
+	if len(arr) == 0 {
+		err := ErrNoData
+		conf.Log.WithError(err).Warn("Fail to get all items from alarm data store")
+		return nil
+	}
+	return arr
+}
+
+// GetByPoll get all alarms bound to the poll from alarm map
+func (ds *dataStore) GetByPoll(poll string) interface{} {
+	arr := []psmb.MbtcpAlarmStatus{}
+	ds.RLock()
+	for _, v := range ds.m {
+		if v.Poll == poll {
+			arr = append(arr, v)
+		}
+	}
+	ds.RUnlock()
+
+	if len(arr) == 0 {
+		return nil // we intend to suppress this log; most polls have no alarm
+	}
+	return arr
+}
+
+// Delete remove alarm from alarm map
+func (ds *dataStore) Delete(name string) {
+	ds.Lock()
+	delete(ds.m, name)
+	ds.Unlock()
+}
+
+// DeleteAll delete all alarms from alarm map
+func (ds *dataStore) DeleteAll() {
+	ds.Lock()
+	ds.m = make(map[string]psmb.MbtcpAlarmStatus)
+	ds.Unlock()
+}
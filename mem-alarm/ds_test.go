@@ -0,0 +1,76 @@
+package alarm
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/taka-wang/psmb"
+	psmbtcp "github.com/taka-wang/psmb/tcp"
+	"github.com/takawang/sugar"
+)
+
+func init() {
+	psmbtcp.Register("Alarm", NewDataStore)
+}
+
+func TestAlarm(t *testing.T) {
+	s := sugar.New(t)
+
+	s.Assert("``add` alarm to map", func(logf sugar.Log) bool {
+		alarmMap, err := psmbtcp.AlarmDataStoreCreator("Alarm")
+		logf(err)
+		if err != nil {
+			return false
+		}
+
+		h := float32(80)
+		a := psmb.MbtcpAlarmStatus{Name: "A", Poll: "P1", High: &h, Enabled: true}
+		b := psmb.MbtcpAlarmStatus{Name: "B", Poll: "P2", High: &h, Enabled: true}
+
+		// ADD
+		if err := alarmMap.Add(a.Name, a); err != nil {
+			return false
+		}
+		alarmMap.Add(b.Name, b)
+		if err := alarmMap.Add("", b); err == nil {
+			return false
+		}
+
+		// GET
+		if r, ok := alarmMap.Get(a.Name); ok {
+			logf(r)
+		} else {
+			return false
+		}
+
+		// GET BY POLL
+		if r, ok := alarmMap.GetByPoll("P1").([]psmb.MbtcpAlarmStatus); !ok || len(r) != 1 || r[0].Name != "A" {
+			return false
+		}
+		if r := alarmMap.GetByPoll("P3"); r != nil {
+			return false
+		}
+
+		// DELETE
+		alarmMap.Delete(a.Name)
+		if r, ok := alarmMap.GetAll().([]psmb.MbtcpAlarmStatus); !ok || len(r) != 1 {
+			return false
+		}
+
+		// out of capacity test
+		for i := 0; i < 50; i++ {
+			if err := alarmMap.Add(strconv.Itoa(i), a); err != nil {
+				logf(err, i)
+			}
+		}
+		// update in place at capacity
+		b.Active = true
+		if err := alarmMap.Add(b.Name, b); err != nil {
+			return false
+		}
+
+		// DELETE ALL
+		alarmMap.DeleteAll()
+		return alarmMap.GetAll() == nil
+	})
+}
@@ -45,7 +45,8 @@ func (ds *dataStore) Add(name string, req interface{}) error {
 	}
 
 	ds.RLock()
-	boom := len(ds.m)+1 > maxCapacity
+	_, exist := ds.m[name]
+	boom := !exist && len(ds.m)+1 > maxCapacity // update in place is always allowed
 	ds.RUnlock()
 	if boom {
 		return ErrOutOfCapacity
@@ -8,6 +8,7 @@ package reader
 
 import (
 	"sync"
+	"time"
 
 	psmb "github.com/taka-wang/psmb"
 	conf "github.com/taka-wang/psmb/viper-conf"
@@ -88,24 +89,32 @@ func (ds *dataStore) GetTaskByName(name string) (interface{}, bool) {
 }
 
 // GetAll get all requests from read/poll task map
+//	mbtcp: []MbtcpPollStatus, mbrtu: []MbrtuPollStatus
 func (ds *dataStore) GetAll() interface{} {
 	arr := []psmb.MbtcpPollStatus{}
+	rtuArr := []psmb.MbrtuPollStatus{}
 
 	ds.RLock()
 	for _, v := range ds.nameMap {
 		// type casting check!
-		if item, ok := v.Req.(psmb.MbtcpPollStatus); ok {
+		switch item := v.Req.(type) {
+		case psmb.MbtcpPollStatus:
 			arr = append(arr, item)
+		case psmb.MbrtuPollStatus:
+			rtuArr = append(rtuArr, item)
 		}
 	}
 	ds.RUnlock()
 
-	if len(arr) == 0 {
-		err := ErrNoData
-		conf.Log.WithError(err).Warn("Fail to get all items from reader data store")
-		return nil
+	if len(arr) > 0 {
+		return arr
 	}
-	return arr
+	if len(rtuArr) > 0 {
+		return rtuArr
+	}
+	err := ErrNoData
+	conf.Log.WithError(err).Warn("Fail to get all items from reader data store")
+	return nil
 }
 
 // DeleteAll remove all requests from read/poll task map
@@ -161,13 +170,19 @@ func (ds *dataStore) UpdateIntervalByName(name string, interval uint64) error {
 		return ErrInvalidPollName
 	}
 
-	req, ok := task.Req.(psmb.MbtcpPollStatus)
-	if !ok {
+	var req interface{}
+	switch r := task.Req.(type) {
+	case psmb.MbtcpPollStatus:
+		r.Interval = interval // update interval
+		r.IntervalMs = 0      // in seconds
+		req = r
+	case psmb.MbrtuPollStatus:
+		r.Interval = interval // update interval
+		req = r
+	default:
 		return ErrInvalidPollName
 	}
 
-	req.Interval = interval // update interval
-
 	ds.Lock()
 	ds.nameMap[name] = psmb.ReaderTask{Name: name, Cmd: task.Cmd, Req: req} // update nameMap table
 	ds.idMap[tid] = ds.nameMap[name]                                        // update idMap table
@@ -175,6 +190,30 @@ func (ds *dataStore) UpdateIntervalByName(name string, interval uint64) error {
 	return nil
 }
 
+// UpdateIntervalMsByName update poll request interval in milliseconds
+func (ds *dataStore) UpdateIntervalMsByName(name string, intervalMs uint64) error {
+	ds.RLock()
+	tid, _ := ds.nameID[name]
+	task, ok := ds.nameMap[name]
+	ds.RUnlock()
+
+	if !ok {
+		return ErrInvalidPollName
+	}
+
+	r, ok := task.Req.(psmb.MbtcpPollStatus) // milliseconds for modbus tcp polls only
+	if !ok {
+		return ErrInvalidPollName
+	}
+	r.IntervalMs = intervalMs // update interval
+
+	ds.Lock()
+	ds.nameMap[name] = psmb.ReaderTask{Name: name, Cmd: task.Cmd, Req: r} // update nameMap table
+	ds.idMap[tid] = ds.nameMap[name]                                      // update idMap table
+	ds.Unlock()
+	return nil
+}
+
 // UpdateToggleByName update poll request enabled flag
 func (ds *dataStore) UpdateToggleByName(name string, toggle bool) error {
 	ds.RLock()
@@ -186,12 +225,18 @@ func (ds *dataStore) UpdateToggleByName(name string, toggle bool) error {
 		return ErrInvalidPollName
 	}
 
-	req, ok := task.Req.(psmb.MbtcpPollStatus)
-	if !ok {
+	var req interface{}
+	switch r := task.Req.(type) {
+	case psmb.MbtcpPollStatus:
+		r.Enabled = toggle // update flag
+		req = r
+	case psmb.MbrtuPollStatus:
+		r.Enabled = toggle // update flag
+		req = r
+	default:
 		return ErrInvalidPollName
 	}
 
-	req.Enabled = toggle // update flag
 	ds.Lock()
 	ds.nameMap[name] = psmb.ReaderTask{Name: name, Cmd: task.Cmd, Req: req} // update nameMap table
 	ds.idMap[tid] = ds.nameMap[name]                                        // update idMap table
@@ -203,12 +248,54 @@ func (ds *dataStore) UpdateToggleByName(name string, toggle bool) error {
 func (ds *dataStore) UpdateAllToggles(toggle bool) {
 	ds.Lock()
 	for name, task := range ds.nameMap {
-		if req, ok := task.Req.(psmb.MbtcpPollStatus); ok {
-			req.Enabled = toggle                                                    // update flag
-			ds.nameMap[name] = psmb.ReaderTask{Name: name, Cmd: task.Cmd, Req: req} // update nameMap table
-			tid, _ := ds.nameID[name]                                               // get Tid
-			ds.idMap[tid] = ds.nameMap[name]                                        // update idMap table
+		var req interface{}
+		switch r := task.Req.(type) {
+		case psmb.MbtcpPollStatus:
+			r.Enabled = toggle // update flag
+			req = r
+		case psmb.MbrtuPollStatus:
+			r.Enabled = toggle // update flag
+			req = r
+		default:
+			continue
+		}
+		ds.nameMap[name] = psmb.ReaderTask{Name: name, Cmd: task.Cmd, Req: req} // update nameMap table
+		tid, _ := ds.nameID[name]                                               // get Tid
+		ds.idMap[tid] = ds.nameMap[name]                                        // update idMap table
+	}
+	ds.Unlock()
+}
+
+// UpdateDeadlineByID update one-off request deadline via TID
+func (ds *dataStore) UpdateDeadlineByID(tid string, deadline time.Time) error {
+	ds.Lock()
+	defer ds.Unlock()
+
+	task, ok := ds.idMap[tid]
+	if !ok {
+		return ErrNoData
+	}
+	task.Deadline = deadline
+	ds.idMap[tid] = task
+	ds.nameMap[task.Name] = task
+	return nil
+}
+
+// ExpireTasks remove expired requests from read/poll task map
+func (ds *dataStore) ExpireTasks(now time.Time) map[string]psmb.ReaderTask {
+	expired := make(map[string]psmb.ReaderTask)
+
+	ds.Lock()
+	for tid, task := range ds.idMap {
+		if task.Deadline.IsZero() || task.Deadline.After(now) {
+			continue
 		}
+		expired[tid] = task
+		delete(ds.idName, tid)
+		delete(ds.idMap, tid)
+		delete(ds.nameID, task.Name)
+		delete(ds.nameMap, task.Name)
 	}
 	ds.Unlock()
+	return expired
 }
@@ -3,6 +3,7 @@ package reader
 import (
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/taka-wang/psmb"
 	psmbtcp "github.com/taka-wang/psmb/tcp"
@@ -81,4 +82,90 @@ func TestMbtcpReadTask(t *testing.T) {
 
 		return true
 	})
+
+	s.Assert("`rtu` poll requests should be updated", func(logf sugar.Log) bool {
+		reader, err := psmbtcp.ReaderDataStoreCreator("Reader")
+		if err != nil {
+			logf(err)
+			return false
+		}
+
+		req := psmb.MbrtuPollStatus{Name: "rtu", Interval: 1, Device: "/dev/ttyUSB0"}
+		if err := reader.Add(req.Name, "1", psmb.CmdMbrtuCreatePoll, req); err != nil {
+			logf(err)
+			return false
+		}
+		if err := reader.UpdateIntervalByName(req.Name, 3); err != nil {
+			logf(err)
+			return false
+		}
+		if err := reader.UpdateToggleByName(req.Name, true); err != nil {
+			logf(err)
+			return false
+		}
+
+		all, ok := reader.GetAll().([]psmb.MbrtuPollStatus)
+		if !ok || len(all) != 1 {
+			logf(all)
+			return false
+		}
+		logf(all)
+		return all[0].Interval == 3 && all[0].Enabled
+	})
+
+	s.Assert("`tcp` poll interval should be updated in milliseconds", func(logf sugar.Log) bool {
+		reader, err := psmbtcp.ReaderDataStoreCreator("Reader")
+		if err != nil {
+			logf(err)
+			return false
+		}
+
+		req := psmb.MbtcpPollStatus{Name: "tcp", Interval: 1, IP: "127.0.0.1"}
+		if err := reader.Add(req.Name, "1", psmb.CmdMbtcpCreatePoll, req); err != nil {
+			logf(err)
+			return false
+		}
+		if err := reader.UpdateIntervalMsByName(req.Name, 100); err != nil {
+			logf(err)
+			return false
+		}
+		if all, ok := reader.GetAll().([]psmb.MbtcpPollStatus); !ok || len(all) != 1 || all[0].IntervalMs != 100 {
+			logf(all)
+			return false
+		}
+		if err := reader.UpdateIntervalByName(req.Name, 2); err != nil {
+			logf(err)
+			return false
+		}
+		all, _ := reader.GetAll().([]psmb.MbtcpPollStatus)
+		logf(all)
+		return len(all) == 1 && all[0].Interval == 2 && all[0].IntervalMs == 0 && reader.UpdateIntervalMsByName("none", 1) != nil
+	})
+
+	s.Assert("Expired one-off requests should be evicted", func(logf sugar.Log) bool {
+		reader, err := psmbtcp.ReaderDataStoreCreator("Reader")
+		if err != nil {
+			logf(err)
+			return false
+		}
+
+		now := time.Now()
+		reader.Add("", "1", psmb.CmdMbtcpOnceRead, psmb.MbtcpReadReq{Tid: 1})
+		reader.Add("", "2", psmb.CmdMbtcpOnceRead, psmb.MbtcpReadReq{Tid: 2})
+		reader.Add("poll", "3", psmb.CmdMbtcpCreatePoll, psmb.MbtcpPollStatus{Name: "poll"})
+		reader.UpdateDeadlineByID("1", now.Add(-time.Millisecond))
+		reader.UpdateDeadlineByID("2", now.Add(time.Hour))
+		if err := reader.UpdateDeadlineByID("4", now); err == nil {
+			return false
+		}
+
+		expired := reader.ExpireTasks(now)
+		logf(expired)
+		if _, ok := reader.GetTaskByID("1"); ok || len(expired) != 1 || expired["1"].Cmd != psmb.CmdMbtcpOnceRead {
+			return false
+		}
+		_, ok2 := reader.GetTaskByID("2")
+		_, ok3 := reader.GetTaskByName("poll")
+		return ok2 && ok3
+	})
 }
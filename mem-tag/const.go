@@ -0,0 +1,7 @@
+package tag
+
+// [mem_tag]
+const (
+	keyMaxCapacity     = "mem_tag.max_capacity"
+	defaultMaxCapacity = 256
+)
@@ -0,0 +1,160 @@
+// Package tag an in-memory data store for tag database.
+//
+// Guideline: if error is one of the return, don't duplicately log to output.
+//
+// By taka@cmwang.net
+//
+package tag
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/taka-wang/psmb"
+	"github.com/taka-wang/psmb/viper-conf"
+)
+
+var maxCapacity int
+
+func init() {
+	conf.SetDefault(keyMaxCapacity, defaultMaxCapacity)
+	maxCapacity = conf.GetInt(keyMaxCapacity)
+}
+
+//@Implement ITagDataStore implicitly
+
+// dataStore tag database
+type dataStore struct {
+	// read writer mutex
+	sync.RWMutex
+	// devices key-value map: (name, psmb.MbtcpTagDevice)
+	devices map[string]psmb.MbtcpTagDevice
+	// tags key-value map: (name, psmb.MbtcpTag)
+	tags map[string]psmb.MbtcpTag
+}
+
+// NewDataStore instantiate tag database
+func NewDataStore(conf map[string]string) (interface{}, error) {
+	return &dataStore{
+		devices: make(map[string]psmb.MbtcpTagDevice),
+		tags:    make(map[string]psmb.MbtcpTag),
+	}, nil
+}
+
+// AddDevice add or update device definition
+func (ds *dataStore) AddDevice(name string, req interface{}) error {
+	if name == "" {
+		return ErrInvalidDeviceName
+	}
+	r, ok := req.(psmb.MbtcpTagDevice)
+	if !ok {
+		return ErrInvalidDeviceName
+	}
+
+	ds.Lock()
+	defer ds.Unlock()
+	if _, exist := ds.devices[name]; !exist && len(ds.devices)+1 > maxCapacity {
+		return ErrOutOfCapacity
+	}
+	ds.devices[name] = r
+	return nil
+}
+
+// GetDevice get device definition
+func (ds *dataStore) GetDevice(name string) (interface{}, bool) {
+	ds.RLock()
+	req, ok := ds.devices[name]
+	ds.RUnlock()
+	return req, ok
+}
+
+// GetDevices get all device definitions sorted by name
+func (ds *dataStore) GetDevices() interface{} {
+	arr := []psmb.MbtcpTagDevice{}
+	ds.RLock()
+	for _, v := range ds.devices {
+		arr = append(arr, v)
+	}
+	ds.RUnlock()
+
+	if len(arr) == 0 {
+		err := ErrNoData
+		conf.Log.WithError(err).Warn("Fail to get all devices from tag data store")
+		return nil
+	}
+	sort.Sort(byDeviceName(arr))
+	return arr
+}
+
+// DeleteDevice delete device definition
+func (ds *dataStore) DeleteDevice(name string) {
+	ds.Lock()
+	delete(ds.devices, name)
+	ds.Unlock()
+}
+
+// Add add or update tag
+func (ds *dataStore) Add(name string, req interface{}) error {
+	if name == "" {
+		return ErrInvalidTagName
+	}
+	r, ok := req.(psmb.MbtcpTag)
+	if !ok {
+		return ErrInvalidTagName
+	}
+
+	ds.Lock()
+	defer ds.Unlock()
+	if _, exist := ds.tags[name]; !exist && len(ds.tags)+1 > maxCapacity {
+		return ErrOutOfCapacity
+	}
+	ds.tags[name] = r
+	return nil
+}
+
+// Get get tag
+func (ds *dataStore) Get(name string) (interface{}, bool) {
+	ds.RLock()
+	req, ok := ds.tags[name]
+	ds.RUnlock()
+	return req, ok
+}
+
+// GetAll get all tags sorted by name
+func (ds *dataStore) GetAll() interface{} {
+	arr := []psmb.MbtcpTag{}
+	ds.RLock()
+	for _, v := range ds.tags {
+		arr = append(arr, v)
+	}
+	ds.RUnlock()
+
+	if len(arr) == 0 {
+		err := ErrNoData
+		conf.Log.WithError(err).Warn("Fail to get all tags from tag data store")
+		return nil
+	}
+	sort.Sort(byTagName(arr))
+	return arr
+}
+
+// Delete delete tag
+func (ds *dataStore) Delete(name string) {
+	ds.Lock()
+	delete(ds.tags, name)
+	ds.Unlock()
+}
+
+// byDeviceName sort devices by name
+type byDeviceName []psmb.MbtcpTagDevice
+
+func (a byDeviceName) Len() int           { return len(a) }
+func (a byDeviceName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byDeviceName) Less(i, j int) bool { return a[i].Name < a[j].Name }
+
+// byTagName sort tags by name
+type byTagName []psmb.MbtcpTag
+
+func (a byTagName) Len() int           { return len(a) }
+func (a byTagName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byTagName) Less(i, j int) bool { return a[i].Name < a[j].Name }
@@ -0,0 +1,81 @@
+package tag
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/taka-wang/psmb"
+	psmbtcp "github.com/taka-wang/psmb/tcp"
+	"github.com/takawang/sugar"
+)
+
+func init() {
+	psmbtcp.Register("Tag", NewDataStore)
+}
+
+func TestTag(t *testing.T) {
+	s := sugar.New(t)
+
+	s.Assert("`add` devices and tags to map", func(logf sugar.Log) bool {
+		tagMap, err := psmbtcp.TagDataStoreCreator("Tag")
+		logf(err)
+		if err != nil {
+			return false
+		}
+
+		d := psmb.MbtcpTagDevice{Name: "plc", IP: "192.168.0.1", Slave: 1, Order: psmb.BigEndian}
+		a := psmb.MbtcpTag{Name: "temp", Device: "plc", FC: 3, Addr: 10, Type: psmb.Float32, Units: "C"}
+		b := psmb.MbtcpTag{Name: "pump", Device: "plc", FC: 1, Addr: 2, Access: "rw"}
+
+		// ADD
+		if err := tagMap.AddDevice(d.Name, d); err != nil {
+			return false
+		}
+		if err := tagMap.AddDevice("", d); err == nil {
+			return false
+		}
+		if err := tagMap.Add(a.Name, a); err != nil {
+			return false
+		}
+		tagMap.Add(b.Name, b)
+		if err := tagMap.Add("", b); err == nil {
+			return false
+		}
+
+		// GET
+		if r, ok := tagMap.GetDevice(d.Name); !ok || r.(psmb.MbtcpTagDevice).IP != d.IP {
+			return false
+		}
+		if r, ok := tagMap.Get(a.Name); !ok || r.(psmb.MbtcpTag).Units != "C" {
+			return false
+		}
+		if _, ok := tagMap.Get("none"); ok {
+			return false
+		}
+
+		// GET ALL sorted by name
+		if r, ok := tagMap.GetAll().([]psmb.MbtcpTag); !ok || len(r) != 2 || r[0].Name != "pump" {
+			return false
+		}
+
+		// DELETE
+		tagMap.Delete(a.Name)
+		if r, ok := tagMap.GetAll().([]psmb.MbtcpTag); !ok || len(r) != 1 {
+			return false
+		}
+		tagMap.DeleteDevice(d.Name)
+		if tagMap.GetDevices() != nil {
+			return false
+		}
+
+		// out of capacity test
+		for i := 0; i < 300; i++ {
+			if err := tagMap.Add(strconv.Itoa(i), a); err != nil {
+				logf(err, i)
+			}
+		}
+		// update in place at capacity
+		b.Access = "r"
+		return tagMap.Add(b.Name, b) == nil
+	})
+}
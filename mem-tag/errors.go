@@ -0,0 +1,17 @@
+package tag
+
+import "errors"
+
+var (
+	// ErrInvalidTagName is the error when the name is invalid
+	ErrInvalidTagName = errors.New("Invalid tag name")
+
+	// ErrInvalidDeviceName is the error when the device name is invalid
+	ErrInvalidDeviceName = errors.New("Invalid device name")
+
+	// ErrNoData is the error when the return is empty
+	ErrNoData = errors.New("Data does not exist.")
+
+	// ErrOutOfCapacity is the error when the store capacity is full
+	ErrOutOfCapacity = errors.New("Tag data store run out of capacity!")
+)
@@ -0,0 +1,7 @@
+package transport
+
+// [mem_transport]
+const (
+	keyBufferSize     = "mem_transport.buffer_size"
+	defaultBufferSize = 256
+)
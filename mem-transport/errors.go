@@ -0,0 +1,16 @@
+package transport
+
+import (
+	"errors"
+)
+
+var (
+	// ErrInvalidMessageLength is the error when the frames are not [command, json].
+	ErrInvalidMessageLength = errors.New("Invalid message length!")
+
+	// ErrBufferFull is the error when the transport buffer is full
+	ErrBufferFull = errors.New("Transport buffer is full!")
+
+	// ErrTimeout is the error when no message is received in time
+	ErrTimeout = errors.New("Receive timeout!")
+)
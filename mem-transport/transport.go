@@ -0,0 +1,98 @@
+// Package transport an in-memory upstream transport for tests.
+//
+// Guideline: if error is one of the return, don't duplicately log to output.
+//
+// By taka@cmwang.net
+//
+package transport
+
+import (
+	"time"
+
+	"github.com/taka-wang/psmb/viper-conf"
+)
+
+var bufferSize int
+
+func init() {
+	conf.SetDefault(keyBufferSize, defaultBufferSize)
+	bufferSize = conf.GetInt(keyBufferSize)
+}
+
+//@Implement IUpstreamTransport implicitly
+
+// Transport in-memory upstream transport
+type Transport struct {
+	// in request frames from services
+	in chan []string
+	// out response or data frames to services
+	out chan []string
+	// done stop signal
+	done chan struct{}
+}
+
+// NewTransport instantiate in-memory transport
+func NewTransport(conf map[string]string) (interface{}, error) {
+	return &Transport{
+		in:  make(chan []string, bufferSize),
+		out: make(chan []string, bufferSize),
+	}, nil
+}
+
+// Start pass request frames to handler until stopped;
+// 	requests injected before start are queued.
+func (t *Transport) Start(handler func(msg []string)) error {
+	t.done = make(chan struct{})
+	go func(done chan struct{}) {
+		for {
+			select {
+			case msg := <-t.in:
+				handler(msg)
+			case <-done:
+				return
+			}
+		}
+	}(t.done)
+	return nil
+}
+
+// Stop stop passing request frames
+func (t *Transport) Stop() {
+	if t.done != nil {
+		close(t.done)
+		t.done = nil
+	}
+}
+
+// Send send response or data frames to services
+func (t *Transport) Send(msg []string) error {
+	if len(msg) != 2 {
+		return ErrInvalidMessageLength
+	}
+	select {
+	case t.out <- msg:
+		return nil
+	default:
+		return ErrBufferFull
+	}
+}
+
+// Request inject request frames as if sent by services
+func (t *Transport) Request(cmd, req string) error {
+	select {
+	case t.in <- []string{cmd, req}:
+		return nil
+	default:
+		return ErrBufferFull
+	}
+}
+
+// Recv receive response or data frames sent to services within timeout
+func (t *Transport) Recv(timeout time.Duration) ([]string, error) {
+	select {
+	case msg := <-t.out:
+		return msg, nil
+	case <-time.After(timeout):
+		return nil, ErrTimeout
+	}
+}
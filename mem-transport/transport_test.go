@@ -0,0 +1,104 @@
+package transport
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/taka-wang/psmb"
+	"github.com/taka-wang/psmb/cron"
+	mfilter "github.com/taka-wang/psmb/mem-filter"
+	mreader "github.com/taka-wang/psmb/mem-reader"
+	mwriter "github.com/taka-wang/psmb/mem-writer"
+	history "github.com/taka-wang/psmb/redis-history"
+	psmbtcp "github.com/taka-wang/psmb/tcp"
+	"github.com/taka-wang/psmb/viper-conf"
+	"github.com/takawang/sugar"
+)
+
+// memTransport transport instance created by service
+var memTransport *Transport
+
+func init() {
+	psmbtcp.Register("Transport", NewTransport)
+	psmbtcp.Register("SharedTransport", func(c map[string]string) (interface{}, error) {
+		tp, err := NewTransport(c)
+		memTransport = tp.(*Transport)
+		return tp, err
+	})
+	psmbtcp.Register("Reader", mreader.NewDataStore)
+	psmbtcp.Register("Writer", mwriter.NewDataStore)
+	psmbtcp.Register("History", history.NewDataStore) // connect lazily
+	psmbtcp.Register("Filter", mfilter.NewDataStore)
+	psmbtcp.Register("Cron", cron.NewScheduler)
+}
+
+func TestTransport(t *testing.T) {
+	s := sugar.New(t)
+
+	s.Assert("Queued requests should be handled after start", func(logf sugar.Log) bool {
+		tp, err := psmbtcp.UpstreamTransportCreator("Transport")
+		if err != nil {
+			return false
+		}
+		mem := tp.(*Transport)
+		mem.Request("cmd", "{}")
+
+		got := make(chan []string, 1)
+		tp.Start(func(msg []string) { got <- msg })
+		defer tp.Stop()
+
+		select {
+		case msg := <-got:
+			logf("msg:%v", msg)
+			return msg[0] == "cmd" && msg[1] == "{}"
+		case <-time.After(time.Second):
+			return false
+		}
+	})
+
+	s.Assert("Sent frames should be received", func(logf sugar.Log) bool {
+		tp, _ := psmbtcp.UpstreamTransportCreator("Transport")
+		mem := tp.(*Transport)
+		if err := tp.Send([]string{"cmd"}); err != ErrInvalidMessageLength {
+			return false
+		}
+		if _, err := mem.Recv(10 * time.Millisecond); err != ErrTimeout {
+			return false
+		}
+		tp.Send([]string{"cmd", "{}"})
+		msg, err := mem.Recv(time.Second)
+		logf("msg:%v, err:%v", msg, err)
+		return err == nil && msg[1] == "{}"
+	})
+
+	s.Assert("Service should reply through the transport", func(logf sugar.Log) bool {
+		conf.Set("psmbtcp.upstream_transport", "SharedTransport")
+		defer conf.Set("psmbtcp.upstream_transport", "")
+
+		srv, err := psmbtcp.NewService("Reader", "Writer", "History", "Filter", "Cron")
+		if err != nil {
+			return false
+		}
+		go srv.Start()
+		defer srv.Stop()
+
+		mem := memTransport
+		mem.Request(psmb.CmdMbtcpCreateFilter, `{"tid":1,"name":"p1","enabled":true,"type":3,"arg":[1]}`)
+		msg, err := mem.Recv(time.Second)
+		if err != nil || msg[0] != psmb.CmdMbtcpCreateFilter {
+			return false
+		}
+
+		mem.Request(psmb.CmdMbtcpGetFilter, `{"tid":2,"name":"p1"}`)
+		if msg, err = mem.Recv(time.Second); err != nil {
+			return false
+		}
+		logf("msg:%v", msg)
+		var res psmb.MbtcpFilterStatus
+		if err := json.Unmarshal([]byte(msg[1]), &res); err != nil {
+			return false
+		}
+		return msg[0] == psmb.CmdMbtcpGetFilter && res.Tid == 2 && res.Status == "ok" && res.Type == psmb.Equal
+	})
+}
@@ -4,37 +4,42 @@
 //
 package writer
 
-import "sync"
+import (
+	"sync"
+	"time"
+
+	psmb "github.com/taka-wang/psmb"
+)
 
 // @Implement IWriterTaskDataStore contract implicitly
 
 // dataStore write task map type
 type dataStore struct {
 	sync.RWMutex
-	// m key-value map: (tid, command)
-	m map[string]string
+	// m key-value map: (tid, WriterTask)
+	m map[string]psmb.WriterTask
 }
 
 // NewDataStore instantiate mbtcp write task map
 func NewDataStore(conf map[string]string) (interface{}, error) {
 	return &dataStore{
-		m: make(map[string]string),
+		m: make(map[string]psmb.WriterTask),
 	}, nil
 }
 
 // Add add request to write task map
-func (ds *dataStore) Add(tid, cmd string) {
+func (ds *dataStore) Add(tid string, task psmb.WriterTask) {
 	ds.Lock()
-	ds.m[tid] = cmd
+	ds.m[tid] = task
 	ds.Unlock()
 }
 
 // Get get request from write task map
-func (ds *dataStore) Get(tid string) (string, bool) {
+func (ds *dataStore) Get(tid string) (psmb.WriterTask, bool) {
 	ds.RLock()
-	cmd, ok := ds.m[tid]
+	task, ok := ds.m[tid]
 	ds.RUnlock()
-	return cmd, ok
+	return task, ok
 }
 
 // Delete remove request from write task map
@@ -43,3 +48,19 @@ func (ds *dataStore) Delete(tid string) {
 	delete(ds.m, tid)
 	ds.Unlock()
 }
+
+// Expire remove expired requests from write task map
+func (ds *dataStore) Expire(now time.Time) map[string]psmb.WriterTask {
+	expired := make(map[string]psmb.WriterTask)
+
+	ds.Lock()
+	for tid, task := range ds.m {
+		if task.Deadline.IsZero() || task.Deadline.After(now) {
+			continue
+		}
+		expired[tid] = task
+		delete(ds.m, tid)
+	}
+	ds.Unlock()
+	return expired
+}
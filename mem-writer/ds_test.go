@@ -2,7 +2,9 @@ package writer
 
 import (
 	"testing"
+	"time"
 
+	psmb "github.com/taka-wang/psmb"
 	psmbtcp "github.com/taka-wang/psmb/tcp"
 	"github.com/takawang/sugar"
 )
@@ -22,9 +24,9 @@ func TestWriterMap(t *testing.T) {
 			return false
 		}
 
-		writerMap.Add("123456", "12")
+		writerMap.Add("123456", psmb.WriterTask{Cmd: "12", Tid: 1, From: "web"})
 		logf("add `123456` to table")
-		writerMap.Add("234561", "34")
+		writerMap.Add("234561", psmb.WriterTask{Cmd: "34", Tid: 1, From: "app"})
 		logf("add `234561` to table")
 
 		r1, b1 := writerMap.Get("123456")
@@ -32,7 +34,7 @@ func TestWriterMap(t *testing.T) {
 		if !b1 {
 			return false
 		}
-		if r1 != "12" {
+		if r1.Cmd != "12" || r1.From != "web" {
 			return false
 		}
 
@@ -55,4 +57,26 @@ func TestWriterMap(t *testing.T) {
 		}
 		return true
 	})
+
+	s.Assert("Expired tasks should be evicted", func(logf sugar.Log) bool {
+		writerMap, err := psmbtcp.WriterDataStoreCreator("Writer")
+		if err != nil {
+			logf(err)
+			return false
+		}
+
+		now := time.Now()
+		writerMap.Add("1", psmb.WriterTask{Cmd: "1", Tid: 1, Deadline: now.Add(-time.Millisecond)})
+		writerMap.Add("2", psmb.WriterTask{Cmd: "2", Tid: 2, Deadline: now.Add(time.Hour)})
+		writerMap.Add("3", psmb.WriterTask{Cmd: "3", Tid: 3}) // never expire
+
+		expired := writerMap.Expire(now)
+		logf(expired)
+		if _, ok := writerMap.Get("1"); ok || len(expired) != 1 || expired["1"].Tid != 1 {
+			return false
+		}
+		_, ok2 := writerMap.Get("2")
+		_, ok3 := writerMap.Get("3")
+		return ok2 && ok3
+	})
 }
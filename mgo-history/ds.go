@@ -245,3 +245,27 @@ func (ds *dataStore) GetLatest(name string) (string, error) {
 	}
 	return ret, nil
 }
+
+func (ds *dataStore) GetLatestWithTimestamp(name string) (string, int64, error) {
+	session, err := ds.openSession()
+	defer ds.closeSession(session)
+	if err != nil {
+		return "", 0, err
+	}
+
+	// Collection latest
+	c := session.DB(databaseName).C(collectionName)
+	result := blob{}
+
+	// Query latest
+	if err := c.Find(bson.M{"name": name}).Sort("-timestamp").One(&result); err != nil {
+		return "", 0, err
+	}
+
+	// marshal to string
+	ret, err := marshal(result.Data)
+	if err != nil {
+		return "", 0, err
+	}
+	return ret, result.Timestamp, nil
+}
@@ -150,6 +150,11 @@ func GetFloat64(key string) float64 {
 	return 0
 }
 
+// GetStringMapString returns the value associated with the key as a map of strings
+func GetStringMapString(key string) map[string]string {
+	return nil
+}
+
 // GetDuration returns the value associated with the key as a duration
 func GetDuration(key string) time.Duration {
 	switch key {
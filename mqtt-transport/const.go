@@ -0,0 +1,41 @@
+package transport
+
+// [mqtt]
+const (
+	defaultMqttDocker   = "mosquitto" // mqtt broker service name for link
+	keyMqttServer       = "mqtt.server"
+	keyMqttPort         = "mqtt.port"
+	keyClientID         = "mqtt.client_id"
+	keyUsername         = "mqtt.username"
+	keyPassword         = "mqtt.password"
+	keyTopicPrefix      = "mqtt.topic_prefix"
+	keyQoS              = "mqtt.qos"
+	keyRetained         = "mqtt.retained"
+	keyStatusTopic      = "mqtt.status_topic"
+	keyConnectTimeout   = "mqtt.connect_timeout"
+	keyMaxPending       = "mqtt.max_pending"
+	keyReplyTimeout     = "mqtt.reply_timeout"
+	defaultMqttServer   = "127.0.0.1"
+	defaultMqttPort     = "1883"
+	defaultClientID     = "psmb"
+	defaultUsername     = ""
+	defaultPassword     = ""
+	defaultPrefix       = "psmb"
+	defaultQoS          = 1
+	defaultRetained     = true // retain last poll data
+	defaultStatus       = "status"
+	defaultTimeout      = 5 // second
+	defaultMaxPending   = 1024
+	defaultReplyTimeout = 60000 // ms
+)
+
+// topic levels
+const (
+	topicCmd      = "cmd"
+	topicReply    = "reply"
+	topicData     = "data"
+	topicEvent    = "event"
+	statusOnline  = "online"
+	statusOffline = "offline"
+	anonymous     = "anonymous"
+)
@@ -0,0 +1,17 @@
+package transport
+
+import "errors"
+
+var (
+	// ErrInvalidMessageLength is the error when the frames are not [command, json].
+	ErrInvalidMessageLength = errors.New("Invalid message length!")
+
+	// ErrNotConnected is the error when the client is not connected to broker.
+	ErrNotConnected = errors.New("Not connected to MQTT broker!")
+
+	// ErrConnectTimeout is the error when connecting to broker timeout.
+	ErrConnectTimeout = errors.New("MQTT connect timeout!")
+
+	// ErrTooManyRequests is the error when too many requests are waiting for reply.
+	ErrTooManyRequests = errors.New("Too many pending requests!")
+)
@@ -0,0 +1,239 @@
+// Package transport an MQTT upstream transport (bridge) for proactive service.
+//
+// Topics (with the default prefix):
+// 	psmb/cmd/<command>        requests from services
+// 	psmb/reply/<from>/<tid>   responses to the request sender
+// 	psmb/<poll name>/data     poll data
+// 	psmb/event/<command>      other messages (e.g., alarm events)
+// 	psmb/status               online/offline status (last will)
+//
+// By taka@cmwang.net
+//
+package transport
+
+import (
+	"encoding/json"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/taka-wang/psmb"
+	"github.com/taka-wang/psmb/viper-conf"
+)
+
+func setDefaults() {
+	// set default mqtt values
+	conf.SetDefault(keyMqttServer, defaultMqttServer)
+	conf.SetDefault(keyMqttPort, defaultMqttPort)
+	conf.SetDefault(keyClientID, defaultClientID)
+	conf.SetDefault(keyUsername, defaultUsername)
+	conf.SetDefault(keyPassword, defaultPassword)
+	conf.SetDefault(keyTopicPrefix, defaultPrefix)
+	conf.SetDefault(keyQoS, defaultQoS)
+	conf.SetDefault(keyRetained, defaultRetained)
+	conf.SetDefault(keyStatusTopic, defaultStatus)
+	conf.SetDefault(keyConnectTimeout, defaultTimeout)
+	conf.SetDefault(keyMaxPending, defaultMaxPending)
+	conf.SetDefault(keyReplyTimeout, defaultReplyTimeout)
+
+	// Note: for docker environment
+	// lookup mqtt broker
+	host, err := net.LookupHost(defaultMqttDocker)
+	if err != nil {
+		conf.Log.WithError(err).Debug("Local run")
+	} else {
+		conf.Log.WithField("hostname", host[0]).Info("Docker run")
+		conf.Set(keyMqttServer, host[0]) // override default
+	}
+}
+
+func init() {
+	setDefaults() // set defaults
+}
+
+//@Implement IUpstreamTransport implicitly
+
+type (
+	// header common fields of requests and responses for routing
+	header struct {
+		Tid  json.Number `json:"tid"`
+		From string      `json:"from"`
+		Name string      `json:"name"`
+	}
+
+	// pendingRequest request waiting for reply
+	pendingRequest struct {
+		// from request sender
+		from string
+		// tid client tid in raw json
+		tid json.RawMessage
+		// deadline evicted if not answered before
+		deadline time.Time
+	}
+
+	// transport mqtt transport
+	transport struct {
+		// mutex for pending
+		sync.Mutex
+		// client mqtt client
+		client mqtt.Client
+		// pending requests waiting for reply: (transport tid, request)
+		pending map[string]pendingRequest
+		// prefix topic prefix
+		prefix string
+		// qos quality of service
+		qos byte
+		// retained retain last poll data flag
+		retained bool
+		// handler request handler
+		handler func(msg []string)
+	}
+)
+
+// NewTransport instantiate mqtt transport
+func NewTransport(c map[string]string) (interface{}, error) {
+	return &transport{
+		pending:  make(map[string]pendingRequest),
+		prefix:   conf.GetString(keyTopicPrefix),
+		qos:      byte(conf.GetInt(keyQoS)),
+		retained: conf.GetBool(keyRetained),
+	}, nil
+}
+
+// topic join topic levels with prefix
+func (t *transport) topic(levels ...string) string {
+	return strings.Join(append([]string{t.prefix}, levels...), "/")
+}
+
+// Start connect to broker and subscribe to command topics
+func (t *transport) Start(handler func(msg []string)) error {
+	t.handler = handler
+	statusTopic := t.topic(conf.GetString(keyStatusTopic))
+
+	opts := mqtt.NewClientOptions().
+		AddBroker("tcp://" + net.JoinHostPort(conf.GetString(keyMqttServer), conf.GetString(keyMqttPort))).
+		SetClientID(conf.GetString(keyClientID)).
+		SetUsername(conf.GetString(keyUsername)).
+		SetPassword(conf.GetString(keyPassword)).
+		SetAutoReconnect(true).
+		SetWill(statusTopic, statusOffline, t.qos, true).
+		SetOnConnectHandler(func(c mqtt.Client) {
+			// (re)subscribe on every connection
+			if token := c.Subscribe(t.topic(topicCmd, "+"), t.qos, t.onRequest); token.Wait() && token.Error() != nil {
+				conf.Log.WithError(token.Error()).Error("Fail to subscribe command topic")
+			}
+			c.Publish(statusTopic, t.qos, true, statusOnline)
+		})
+
+	t.client = mqtt.NewClient(opts)
+	token := t.client.Connect()
+	if !token.WaitTimeout(time.Duration(conf.GetInt(keyConnectTimeout)) * time.Second) {
+		return ErrConnectTimeout
+	}
+	return token.Error()
+}
+
+// onRequest pass request from command topic to handler
+func (t *transport) onRequest(c mqtt.Client, m mqtt.Message) {
+	levels := strings.Split(m.Topic(), "/")
+	cmd := levels[len(levels)-1]
+	t.handler([]string{cmd, string(t.track(m.Payload(), time.Now()))})
+}
+
+// track remember the sender of the request for reply, return the payload to pass to handler;
+// 	the client tid is replaced by a unique tid, so that senders with the same tid never collide.
+func (t *transport) track(payload []byte, now time.Time) []byte {
+	var h header
+	var body map[string]json.RawMessage
+	if json.Unmarshal(payload, &h) != nil || h.Tid == "" || json.Unmarshal(payload, &body) != nil {
+		return payload // reply to event topic
+	}
+	from := h.From
+	if from == "" {
+		from = anonymous
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	t.expire(now)
+	if len(t.pending) >= conf.GetInt(keyMaxPending) {
+		conf.Log.WithError(ErrTooManyRequests).Warn("Reply request to event topic")
+		return payload
+	}
+
+	TidStr := strconv.FormatInt(psmb.NewUpstreamTid(), 10)
+	req := pendingRequest{
+		from:     from,
+		tid:      body["tid"],
+		deadline: now.Add(time.Duration(conf.GetInt64(keyReplyTimeout)) * time.Millisecond),
+	}
+	body["tid"] = json.RawMessage(TidStr)
+	bytes, err := json.Marshal(body)
+	if err != nil {
+		return payload
+	}
+	t.pending[TidStr] = req
+	return bytes
+}
+
+// expire evict the requests not answered before deadline; the caller should hold the lock.
+func (t *transport) expire(now time.Time) {
+	for TidStr, req := range t.pending {
+		if now.After(req.deadline) {
+			conf.Log.WithField("from", req.from).Debug("Evict unanswered request")
+			delete(t.pending, TidStr)
+		}
+	}
+}
+
+// answer get the reply topic and the payload with client tid of the request, false if not pending
+func (t *transport) answer(TidStr, payload string) (string, string, bool) {
+	t.Lock()
+	req, ok := t.pending[TidStr]
+	delete(t.pending, TidStr)
+	t.Unlock()
+	if !ok {
+		return "", "", false
+	}
+	tid := string(req.tid)
+	return t.topic(topicReply, req.from, tid), strings.Replace(payload, `"tid":`+TidStr, `"tid":`+tid, 1), true
+}
+
+// Stop publish offline status and disconnect from broker
+func (t *transport) Stop() {
+	if t.client == nil || !t.client.IsConnected() {
+		return
+	}
+	t.client.Publish(t.topic(conf.GetString(keyStatusTopic)), t.qos, true, statusOffline).Wait()
+	t.client.Disconnect(250)
+}
+
+// Send publish poll data to data topic, responses to reply topic and the others to event topic
+func (t *transport) Send(msg []string) error {
+	if len(msg) != 2 {
+		return ErrInvalidMessageLength
+	}
+	if t.client == nil || !t.client.IsConnected() {
+		return ErrNotConnected
+	}
+
+	var h header
+	json.Unmarshal([]byte(msg[1]), &h) // best effort
+
+	topic, payload, retained := t.topic(topicEvent, msg[0]), msg[1], false
+	switch {
+	case msg[0] == psmb.CmdMbtcpData && h.Name != "":
+		topic, retained = t.topic(h.Name, topicData), t.retained
+	case h.Tid != "":
+		if replyTopic, reply, ok := t.answer(h.Tid.String(), msg[1]); ok {
+			topic, payload = replyTopic, reply
+		}
+	}
+
+	token := t.client.Publish(topic, t.qos, retained, payload)
+	token.Wait()
+	return token.Error()
+}
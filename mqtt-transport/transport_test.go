@@ -0,0 +1,165 @@
+package transport
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/taka-wang/psmb"
+	psmbtcp "github.com/taka-wang/psmb/tcp"
+	"github.com/taka-wang/psmb/viper-conf"
+	"github.com/takawang/sugar"
+)
+
+func init() {
+	psmbtcp.Register("Transport", NewTransport)
+}
+
+// newClient connect to broker as a service
+func newClient(id string) (mqtt.Client, error) {
+	opts := mqtt.NewClientOptions().
+		AddBroker("tcp://" + net.JoinHostPort(conf.GetString(keyMqttServer), conf.GetString(keyMqttPort))).
+		SetClientID(id)
+	c := mqtt.NewClient(opts)
+	token := c.Connect()
+	token.Wait()
+	return c, token.Error()
+}
+
+// subscribe subscribe topic and forward payloads to channel
+func subscribe(c mqtt.Client, topic string) chan string {
+	ch := make(chan string, 10)
+	c.Subscribe(topic, 1, func(c mqtt.Client, m mqtt.Message) {
+		ch <- string(m.Payload())
+	}).Wait()
+	return ch
+}
+
+// recv receive from channel within timeout
+func recv(ch chan string) string {
+	select {
+	case s := <-ch:
+		return s
+	case <-time.After(2 * time.Second):
+		return ""
+	}
+}
+
+// trackedTid get the transport tid of the tracked request
+func trackedTid(payload []byte) string {
+	var h header
+	json.Unmarshal(payload, &h)
+	return h.Tid.String()
+}
+
+func TestTrack(t *testing.T) {
+	s := sugar.New(t)
+
+	tp, _ := NewTransport(nil)
+	bridge := tp.(*transport)
+	now := time.Now()
+
+	s.Assert("Requests with the same tid from two clients should not cross-talk", func(logf sugar.Log) bool {
+		a := trackedTid(bridge.track([]byte(`{"tid":7,"from":"a"}`), now))
+		b := trackedTid(bridge.track([]byte(`{"tid":7,"from":"b"}`), now))
+		logf("a:%s, b:%s", a, b)
+		if a == b || a == "7" {
+			return false
+		}
+		topicB, replyB, okB := bridge.answer(b, `{"tid":`+b+`,"status":"b"}`)
+		topicA, replyA, okA := bridge.answer(a, `{"tid":`+a+`,"status":"a"}`)
+		logf("b:%s %s, a:%s %s", topicB, replyB, topicA, replyA)
+		return okA && okB && topicA == "psmb/reply/a/7" && replyA == `{"tid":7,"status":"a"}` &&
+			topicB == "psmb/reply/b/7" && replyB == `{"tid":7,"status":"b"}`
+	})
+
+	s.Assert("Unanswered requests should be evicted after reply timeout", func(logf sugar.Log) bool {
+		a := trackedTid(bridge.track([]byte(`{"tid":8}`), now))
+		bridge.track([]byte(`{"tid":9}`), now.Add(time.Hour))
+		_, _, ok := bridge.answer(a, `{"tid":`+a+`,"status":"ok"}`)
+		bridge.Lock()
+		defer bridge.Unlock()
+		logf("answered:%v, pending:%d", ok, len(bridge.pending))
+		return !ok && len(bridge.pending) == 1
+	})
+}
+
+func TestTransport(t *testing.T) {
+	s := sugar.New(t)
+
+	tp, err := psmbtcp.UpstreamTransportCreator("Transport")
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := make(chan []string, 10)
+	if err := tp.Start(func(msg []string) { requests <- msg }); err != nil {
+		t.Fatal(err)
+	}
+	defer tp.Stop()
+
+	client, err := newClient("tester")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(250)
+
+	s.Assert("Status should be online", func(logf sugar.Log) bool {
+		status := recv(subscribe(client, "psmb/status"))
+		logf("status:%s", status)
+		return status == statusOnline
+	})
+
+	s.Assert("Command and reply should be routed by from and tid", func(logf sugar.Log) bool {
+		replies := subscribe(client, "psmb/reply/tester/+")
+		client.Publish("psmb/cmd/"+psmb.CmdMbtcpOnceRead, 1, false, `{"tid":123,"from":"tester"}`).Wait()
+
+		var msg []string
+		select {
+		case msg = <-requests:
+		case <-time.After(2 * time.Second):
+			return false
+		}
+		logf("request:%v", msg)
+		if msg[0] != psmb.CmdMbtcpOnceRead {
+			return false
+		}
+
+		var h header
+		json.Unmarshal([]byte(msg[1]), &h)
+		if err := tp.Send([]string{msg[0], `{"tid":` + h.Tid.String() + `,"status":"ok"}`}); err != nil {
+			return false
+		}
+		reply := recv(replies)
+		logf("reply:%s", reply)
+		return reply == `{"tid":123,"status":"ok"}`
+	})
+
+	s.Assert("Poll data should be published to data topic and retained", func(logf sugar.Log) bool {
+		data := `{"ts":1,"name":"p1","status":"ok","data":[1,2]}`
+		if err := tp.Send([]string{psmb.CmdMbtcpData, data}); err != nil {
+			return false
+		}
+		late, err := newClient("late")
+		if err != nil {
+			return false
+		}
+		defer late.Disconnect(250)
+		got := recv(subscribe(late, "psmb/p1/data"))
+		logf("data:%s", got)
+		return got == data
+	})
+
+	s.Assert("Other messages should be published to event topic", func(logf sugar.Log) bool {
+		events := subscribe(client, "psmb/event/#")
+		tp.Send([]string{psmb.CmdMbtcpAlarm, `{"name":"a1","state":"active"}`})
+		got := recv(events)
+		logf("event:%s", got)
+		return got != ""
+	})
+
+	s.Assert("Invalid frames should fail", func(logf sugar.Log) bool {
+		return tp.Send([]string{"cmd"}) == ErrInvalidMessageLength
+	})
+}
@@ -0,0 +1,226 @@
+// Package alarm an redis-based data store for alarm.
+//
+// Guideline: if error is one of the return, don't duplicately log to output.
+//
+//
+// By taka@cmwang.net
+//
+package alarm
+
+import (
+	"encoding/json"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/taka-wang/psmb"
+	"github.com/taka-wang/psmb/viper-conf"
+)
+
+var (
+	hashName    string
+	maxCapacity int
+)
+
+func setDefaults() {
+	// set default redis values
+	conf.SetDefault(keyRedisServer, defaultRedisServer)
+	conf.SetDefault(keyRedisPort, defaultRedisPort)
+	conf.SetDefault(keyRedisMaxIdel, defaultRedisMaxIdel)
+	conf.SetDefault(keyRedisMaxActive, defaultRedisMaxActive)
+	conf.SetDefault(keyRedisIdelTimeout, defaultRedisIdelTimeout)
+	conf.SetDefault(keyMaxCapacity, defaultMaxCapacity)
+
+	// set default redis-alarm values
+	conf.SetDefault(keyHashName, defaultHashName)
+
+	// Note: for docker environment
+	// lookup redis server
+	host, err := net.LookupHost(defaultRedisDocker)
+	if err != nil {
+		conf.Log.WithError(err).Debug("Local run")
+	} else {
+		conf.Log.WithField("hostname", host[0]).Info("Docker run")
+		conf.Set(keyRedisServer, host[0]) // override default
+	}
+}
+
+func init() {
+	setDefaults() // set defaults
+	hashName = conf.GetString(keyHashName)
+	maxCapacity = conf.GetInt(keyMaxCapacity)
+}
+
+//@Implement IAlarmDataStore implicitly
+
+// dataStore alarm map
+type dataStore struct {
+	mutex sync.Mutex
+	count int
+	pool  *redis.Pool
+}
+
+// NewDataStore instantiate alarm map
+func NewDataStore(c map[string]string) (interface{}, error) {
+	return &dataStore{
+		pool: &redis.Pool{
+			MaxIdle: conf.GetInt(keyRedisMaxIdel),
+			// When zero, there is no limit on the number of connections in the pool.
+			MaxActive:   conf.GetInt(keyRedisMaxActive),
+			IdleTimeout: conf.GetDuration(keyRedisIdelTimeout) * time.Second,
+			Dial: func() (redis.Conn, error) {
+				conn, err := redis.Dial("tcp", conf.GetString(keyRedisServer)+":"+conf.GetString(keyRedisPort))
+				if err != nil {
+					conf.Log.WithError(err).Error("Redis pool dial error")
+				}
+				return conn, err
+			},
+		},
+	}, nil
+}
+
+// Add add or update alarm to alarm map
+func (ds *dataStore) Add(name string, req interface{}) error {
+	if name == "" {
+		return ErrInvalidAlarmName
+	}
+
+	ds.mutex.Lock() // lock
+	conn := ds.pool.Get()
+	defer conn.Close()
+	defer ds.mutex.Unlock() // unlock
+
+	// update in place is always allowed
+	exist, err := redis.Bool(conn.Do("HEXISTS", hashName, name))
+	if err != nil {
+		return err
+	}
+	if !exist && ds.count+1 > maxCapacity {
+		return ErrOutOfCapacity
+	}
+
+	// marshal
+	bytes, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	if _, err := conn.Do("HSET", hashName, name, string(bytes)); err != nil {
+		return err
+	}
+
+	ret, err := redis.Int(conn.Do("HLEN", hashName))
+	if err != nil {
+		return err
+	}
+
+	ds.count = ret // update count
+	return nil
+}
+
+// Get get alarm from alarm map
+func (ds *dataStore) Get(name string) (interface{}, bool) {
+	if name == "" {
+		return nil, false
+	}
+
+	ds.mutex.Lock() // lock
+	conn := ds.pool.Get()
+	defer conn.Close()
+
+	ret, err := redis.String(conn.Do("HGET", hashName, name))
+	ds.mutex.Unlock() // unlock
+	if err != nil {
+		return nil, false
+	}
+	// unmarshal
+	var d psmb.MbtcpAlarmStatus
+	if err := json.Unmarshal([]byte(ret), &d); err != nil {
+		conf.Log.WithError(ErrUnmarshal).Error("Fail to unmarshal items from alarm map")
+		return nil, false
+	}
+	return d, true
+}
+
+// all get all alarms matching the predicate from alarm map
+func (ds *dataStore) all(match func(psmb.MbtcpAlarmStatus) bool) []psmb.MbtcpAlarmStatus {
+	ds.mutex.Lock() // lock
+	conn := ds.pool.Get()
+	defer conn.Close()
+
+	ret, err := redis.StringMap(conn.Do("HGETALL", hashName))
+	ds.mutex.Unlock() // unlock
+	if err != nil {
+		conf.Log.WithError(err).Warn("Fail to get all items from alarm map")
+		return nil
+	}
+
+	arr := []psmb.MbtcpAlarmStatus{}
+	for _, v := range ret {
+		var d psmb.MbtcpAlarmStatus
+		if err := json.Unmarshal([]byte(v), &d); err == nil && match(d) {
+			arr = append(arr, d)
+		}
+	}
+	return arr
+}
+
+// GetAll get all alarms from alarm map
+func (ds *dataStore) GetAll() interface{} {
+	arr := ds.all(func(psmb.MbtcpAlarmStatus) bool { return true })
+	if len(arr) == 0 {
+		conf.Log.WithError(ErrNoData).Warn("Alarm map is empty")
+		return nil
+	}
+	return arr
+}
+
+// GetByPoll get all alarms bound to the poll from alarm map
+func (ds *dataStore) GetByPoll(poll string) interface{} {
+	arr := ds.all(func(d psmb.MbtcpAlarmStatus) bool { return d.Poll == poll })
+	if len(arr) == 0 {
+		return nil // we intend to suppress this log; most polls have no alarm
+	}
+	return arr
+}
+
+// Delete remove alarm from alarm map
+func (ds *dataStore) Delete(name string) {
+	if name == "" {
+		conf.Log.WithError(ErrInvalidAlarmName).Warn("Fail to delete item from alarm map")
+		return
+	}
+
+	ds.mutex.Lock() // lock
+	conn := ds.pool.Get()
+	defer conn.Close()
+	defer ds.mutex.Unlock() // unlock
+
+	// delete item
+	if _, err := conn.Do("HDEL", hashName, name); err != nil {
+		conf.Log.WithError(err).Warn("Fail to delete item from alarm map")
+		return
+	}
+	// get length
+	ret, err := redis.Int(conn.Do("HLEN", hashName))
+	if err != nil {
+		conf.Log.WithError(err).Warn("Fail to get length from alarm map")
+		return
+	}
+	ds.count = ret // update count
+}
+
+// DeleteAll delete all alarms from alarm map
+func (ds *dataStore) DeleteAll() {
+	ds.mutex.Lock() // lock
+	conn := ds.pool.Get()
+	defer conn.Close()
+	defer ds.mutex.Unlock() // unlock
+
+	if _, err := conn.Do("DEL", hashName); err != nil {
+		conf.Log.WithError(err).Warn("Fail to delete all items from alarm map")
+		return
+	}
+	ds.count = 0 // reset
+}
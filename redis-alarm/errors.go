@@ -0,0 +1,17 @@
+package alarm
+
+import "errors"
+
+var (
+	// ErrInvalidAlarmName is the error when the name is invalid
+	ErrInvalidAlarmName = errors.New("Invalid Alarm name")
+
+	// ErrNoData is the error when the return is empty
+	ErrNoData = errors.New("Data does not exist.")
+
+	// ErrUnmarshal is the error when unmarshalling JSON string to structure failed.
+	ErrUnmarshal = errors.New("Fail to unmarshal!")
+
+	// ErrOutOfCapacity is the error when the store capacity is full
+	ErrOutOfCapacity = errors.New("Alarm data store run out of capacity!")
+)
@@ -83,15 +83,20 @@ func NewDataStore(c map[string]string) (interface{}, error) {
 
 // Add add request to filter map
 func (ds *dataStore) Add(name string, req interface{}) error {
-	if ds.count+1 > maxCapacity {
-		return ErrOutOfCapacity
-	}
-
 	ds.mutex.Lock() // lock
 	conn := ds.pool.Get()
 	defer conn.Close()
 	defer ds.mutex.Unlock() // unlock
 
+	// update in place is always allowed
+	exist, err := redis.Bool(conn.Do("HEXISTS", hashName, name))
+	if err != nil {
+		return err
+	}
+	if !exist && ds.count+1 > maxCapacity {
+		return ErrOutOfCapacity
+	}
+
 	// marshal
 	bytes, err := json.Marshal(req)
 	if err != nil {
@@ -167,4 +167,38 @@ func TestFilter(t *testing.T) {
 		return true
 	})
 
+	s.Assert("stateful filter should be persisted", func(logf sugar.Log) bool {
+		filterMap, _ := psmbtcp.FilterDataStoreCreator("Filter")
+		filterMap.DeleteAll()
+
+		a := psmb.MbtcpFilterStatus{
+			Name:    "deadband",
+			Enabled: true,
+			Type:    psmb.Deadband,
+			Arg:     []float32{0.5},
+		}
+		filterMap.Add(a.Name, a)
+
+		// fill to capacity
+		for i := 0; i < 50; i++ {
+			filterMap.Add(strconv.Itoa(i), a)
+		}
+
+		// update in place should be allowed at capacity
+		a.Arg = []float32{12.5}
+		if err := filterMap.Add(a.Name, a); err != nil {
+			logf(err)
+			return false
+		}
+
+		r, ok := filterMap.Get(a.Name)
+		filterMap.DeleteAll()
+		if !ok {
+			return false
+		}
+		logf(r)
+		f := r.(psmb.MbtcpFilterStatus)
+		return f.Type == psmb.Deadband && len(f.Arg) == 1 && f.Arg[0] == 12.5
+	})
+
 }
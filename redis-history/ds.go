@@ -7,6 +7,7 @@ package history
 import (
 	"encoding/json"
 	"net"
+	"strconv"
 	"sync"
 	"time"
 
@@ -166,3 +167,30 @@ func (ds *dataStore) GetLatest(name string) (string, error) {
 	}
 	return ret, nil
 }
+
+func (ds *dataStore) GetLatestWithTimestamp(name string) (string, int64, error) {
+	if name == "" {
+		return "", 0, ErrInvalidName
+	}
+
+	ds.mutex.Lock() // lock
+	conn := ds.pool.Get()
+	defer conn.Close()
+
+	// zrevrange: the latest one with score (i.e., timestamp)
+	ret, err := redis.Strings(conn.Do("ZREVRANGE", zsetPrefix+name, 0, 0, "WITHSCORES"))
+	ds.mutex.Unlock() // unlock
+	if err != nil {
+		return "", 0, err
+	}
+	if len(ret) < 2 {
+		return "", 0, ErrNoData
+	}
+
+	// score is stored in float
+	ts, err := strconv.ParseFloat(ret[1], 64)
+	if err != nil {
+		return "", 0, err
+	}
+	return ret[0], int64(ts), nil
+}
@@ -64,6 +64,13 @@ func TestHistoryMap(t *testing.T) {
 			logf(ret)
 		}
 
+		if ret, ts, err := historyMap.GetLatestWithTimestamp("hello"); err != nil {
+			logf(err)
+			//return false
+		} else {
+			logf(ret, ts)
+		}
+
 		if ret, err := historyMap.GetAll("hello"); err != nil {
 			logf(err)
 			//return false
@@ -1,5 +1,7 @@
 package writer
 
+import "time"
+
 // [redis]
 const (
 	defaultRedisDocker      = "redis" // redis service name for link
@@ -18,5 +20,7 @@ const (
 // [redis_writer]
 const (
 	keyHashName     = "redis_writer.hash_name"
-	defaultHashName = "mbtcp:writer"
+	defaultHashName = "mbtcp:writer" // key prefix of write tasks
+	deadlineSuffix  = ":deadline"    // sorted set of task deadlines
+	expireGrace     = 60 * time.Second
 )
@@ -5,11 +5,13 @@
 package writer
 
 import (
+	"encoding/json"
 	"net"
 	"sync"
 	"time"
 
 	"github.com/garyburd/redigo/redis"
+	psmb "github.com/taka-wang/psmb"
 	//"github.com/taka-wang/psmb/mini-conf"
 	"github.com/taka-wang/psmb/viper-conf"
 )
@@ -70,31 +72,63 @@ func NewDataStore(c map[string]string) (interface{}, error) {
 	}, nil
 }
 
-// Add add request to write task map
-func (ds *dataStore) Add(tid, cmd string) {
+// key task key in redis
+func key(tid string) string {
+	return hashName + ":" + tid
+}
+
+// Add add request to write task map,
+// 	task with deadline is evicted by redis TTL even if nobody sweeps it.
+func (ds *dataStore) Add(tid string, task psmb.WriterTask) {
+	bytes, err := json.Marshal(task)
+	if err != nil {
+		conf.Log.WithError(err).Warn("Fail to marshal writer task")
+		return
+	}
+
+	args := redis.Args{}.Add(key(tid), string(bytes))
+	if !task.Deadline.IsZero() {
+		ttl := task.Deadline.Sub(time.Now()) + expireGrace // leave time for sweeper
+		if ttl < time.Millisecond {
+			ttl = time.Millisecond
+		}
+		args = args.Add("PX", int64(ttl/time.Millisecond))
+	}
+
 	ds.mutex.Lock() // lock
 	conn := ds.pool.Get()
 	defer conn.Close()
 	defer ds.mutex.Unlock() // unlock
 
-	if _, err := conn.Do("HSET", hashName, tid, cmd); err != nil {
+	conn.Send("MULTI")
+	conn.Send("SET", args...)
+	if !task.Deadline.IsZero() {
+		conn.Send("ZADD", hashName+deadlineSuffix, task.Deadline.UnixNano()/int64(time.Millisecond), tid)
+	}
+	if _, err := conn.Do("EXEC"); err != nil {
 		conf.Log.WithError(err).Warn("Fail to add item to writer data store")
 	}
 }
 
 // Get get request from write task map
-func (ds *dataStore) Get(tid string) (string, bool) {
+func (ds *dataStore) Get(tid string) (psmb.WriterTask, bool) {
+	var task psmb.WriterTask
+
 	ds.mutex.Lock() // lock
 	conn := ds.pool.Get()
 	defer conn.Close()
 
-	ret, err := redis.String(conn.Do("HGET", hashName, tid))
+	ret, err := redis.String(conn.Do("GET", key(tid)))
 	ds.mutex.Unlock() // unlock
 	if err != nil {
 		conf.Log.WithError(err).Warn("Fail to get item from writer data store")
-		return "", false
+		return task, false
 	}
-	return ret, true
+	if err := json.Unmarshal([]byte(ret), &task); err != nil {
+		conf.Log.WithError(err).Warn("Fail to unmarshal writer task")
+		return task, false
+	}
+	return task, true
 }
 
 // Delete remove request from write task map
@@ -104,7 +138,42 @@ func (ds *dataStore) Delete(tid string) {
 	defer conn.Close()
 	defer ds.mutex.Unlock() // unlock
 
-	if _, err := conn.Do("HDEL", hashName, tid); err != nil {
+	conn.Send("MULTI")
+	conn.Send("DEL", key(tid))
+	conn.Send("ZREM", hashName+deadlineSuffix, tid)
+	if _, err := conn.Do("EXEC"); err != nil {
 		conf.Log.WithError(err).Error("Fail to delete item from writer data store")
 	}
 }
+
+// Expire remove expired requests from write task map
+func (ds *dataStore) Expire(now time.Time) map[string]psmb.WriterTask {
+	expired := make(map[string]psmb.WriterTask)
+
+	ds.mutex.Lock() // lock
+	conn := ds.pool.Get()
+	defer conn.Close()
+	defer ds.mutex.Unlock() // unlock
+
+	tids, err := redis.Strings(conn.Do("ZRANGEBYSCORE", hashName+deadlineSuffix, "-inf", now.UnixNano()/int64(time.Millisecond)))
+	if err != nil {
+		conf.Log.WithError(err).Warn("Fail to get expired items from writer data store")
+		return expired
+	}
+
+	for _, tid := range tids {
+		ret, err := redis.String(conn.Do("GET", key(tid)))
+		conn.Do("DEL", key(tid))
+		conn.Do("ZREM", hashName+deadlineSuffix, tid)
+		if err != nil {
+			continue // already evicted by TTL
+		}
+		var task psmb.WriterTask
+		if err := json.Unmarshal([]byte(ret), &task); err != nil {
+			conf.Log.WithError(err).Warn("Fail to unmarshal writer task")
+			continue
+		}
+		expired[tid] = task
+	}
+	return expired
+}
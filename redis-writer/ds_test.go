@@ -2,7 +2,9 @@ package writer
 
 import (
 	"testing"
+	"time"
 
+	psmb "github.com/taka-wang/psmb"
 	psmbtcp "github.com/taka-wang/psmb/tcp"
 	"github.com/taka-wang/psmb/viper-conf"
 	"github.com/takawang/sugar"
@@ -28,9 +30,9 @@ func TestWriterMap(t *testing.T) {
 		}
 
 		for index := 0; index < 200; index++ {
-			writerMap.Add("123456", "12")
+			writerMap.Add("123456", psmb.WriterTask{Cmd: "12", Tid: 1, From: "web"})
 			log("add `123456` to table")
-			writerMap.Add("234561", "34")
+			writerMap.Add("234561", psmb.WriterTask{Cmd: "34", Tid: 1, From: "app"})
 			log("add `234561` to table")
 		}
 
@@ -39,7 +41,7 @@ func TestWriterMap(t *testing.T) {
 		if !b1 {
 			return false
 		}
-		if r1 != "12" {
+		if r1.Cmd != "12" || r1.From != "web" {
 			return false
 		}
 
@@ -72,7 +74,7 @@ func TestWriterMap(t *testing.T) {
 		writerMap, err := psmbtcp.WriterDataStoreCreator("Writer")
 		logf(err)
 
-		writerMap.Add("123", "123")
+		writerMap.Add("123", psmb.WriterTask{Cmd: "123"})
 		writerMap.Get("123")
 		writerMap.Delete("123")
 		return true
@@ -82,11 +84,34 @@ func TestWriterMap(t *testing.T) {
 		conf.Set(keyRedisServer, "1.1.1.1")
 		writerMap, err := psmbtcp.WriterDataStoreCreator("Writer")
 		logf(err)
-		writerMap.Add("10", "10")
+		writerMap.Add("10", psmb.WriterTask{Cmd: "10"})
 		if _, b := writerMap.Get("10"); b == false {
 			logf(b)
 		}
 		writerMap.Delete("10")
 		return true
 	})
+
+	s.Assert("Expired tasks should be evicted", func(logf sugar.Log) bool {
+		conf.Set(keyRedisServer, defaultRedisServer)
+		setDefaults()
+		writerMap, err := psmbtcp.WriterDataStoreCreator("Writer")
+		if err != nil {
+			logf(err)
+			return false
+		}
+
+		now := time.Now()
+		writerMap.Add("1", psmb.WriterTask{Cmd: "1", Tid: 1, Deadline: now.Add(-time.Millisecond)})
+		writerMap.Add("2", psmb.WriterTask{Cmd: "2", Tid: 2, Deadline: now.Add(time.Hour)})
+
+		expired := writerMap.Expire(now)
+		logf(expired)
+		if _, ok := writerMap.Get("1"); ok || expired["1"].Tid != 1 {
+			return false
+		}
+		_, ok := writerMap.Get("2")
+		writerMap.Delete("2")
+		return ok
+	})
 }
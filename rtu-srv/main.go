@@ -0,0 +1,33 @@
+package main
+
+import (
+	cron "github.com/taka-wang/psmb/cron"
+	mfilter "github.com/taka-wang/psmb/mem-filter"
+	mreader "github.com/taka-wang/psmb/mem-reader"
+	mwriter "github.com/taka-wang/psmb/mem-writer"
+	history "github.com/taka-wang/psmb/redis-history"
+	mbrtu "github.com/taka-wang/psmb/rtu"
+	mbtcp "github.com/taka-wang/psmb/tcp"
+)
+
+func init() {
+	// register plugins explicitly
+	mbtcp.Register("MemReader", mreader.NewDataStore)
+	mbtcp.Register("MemWriter", mwriter.NewDataStore)
+	mbtcp.Register("History", history.NewDataStore)
+	mbtcp.Register("MemFilter", mfilter.NewDataStore)
+	mbtcp.Register("Cron", cron.NewScheduler)
+}
+
+func main() {
+	// dependency injection & factory pattern
+	if srv, _ := mbrtu.NewService(
+		"MemReader", // Reader Data Store
+		"MemWriter", // Writer Data Store
+		"History",   // History Data Store
+		"MemFilter", // Filter Data Store
+		"Cron",      // Scheduler
+	); srv != nil {
+		srv.Start()
+	}
+}
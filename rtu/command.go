@@ -0,0 +1,40 @@
+package rtu
+
+import . "github.com/taka-wang/psmb"
+
+// commands mbrtu commands and the mbtcp commands serving them
+var commands = map[string]string{
+	CmdMbrtuOnceRead:       CmdMbtcpOnceRead,
+	CmdMbrtuOnceWrite:      CmdMbtcpOnceWrite,
+	CmdMbrtuCreatePoll:     CmdMbtcpCreatePoll,
+	CmdMbrtuUpdatePoll:     CmdMbtcpUpdatePoll,
+	CmdMbrtuGetPoll:        CmdMbtcpGetPoll,
+	CmdMbrtuDeletePoll:     CmdMbtcpDeletePoll,
+	CmdMbrtuTogglePoll:     CmdMbtcpTogglePoll,
+	CmdMbrtuGetPolls:       CmdMbtcpGetPolls,
+	CmdMbrtuDeletePolls:    CmdMbtcpDeletePolls,
+	CmdMbrtuTogglePolls:    CmdMbtcpTogglePolls,
+	CmdMbrtuImportPolls:    CmdMbtcpImportPolls,
+	CmdMbrtuExportPolls:    CmdMbtcpExportPolls,
+	CmdMbrtuGetPollHistory: CmdMbtcpGetPollHistory,
+	CmdMbrtuCreateFilter:   CmdMbtcpCreateFilter,
+	CmdMbrtuUpdateFilter:   CmdMbtcpUpdateFilter,
+	CmdMbrtuGetFilter:      CmdMbtcpGetFilter,
+	CmdMbrtuDeleteFilter:   CmdMbtcpDeleteFilter,
+	CmdMbrtuToggleFilter:   CmdMbtcpToggleFilter,
+	CmdMbrtuGetFilters:     CmdMbtcpGetFilters,
+	CmdMbrtuDeleteFilters:  CmdMbtcpDeleteFilters,
+	CmdMbrtuToggleFilters:  CmdMbtcpToggleFilters,
+	CmdMbrtuImportFilters:  CmdMbtcpImportFilters,
+	CmdMbrtuExportFilters:  CmdMbtcpExportFilters,
+	CmdMbrtuData:           CmdMbtcpData,
+}
+
+// replies mbtcp commands and the mbrtu commands replying them
+var replies = make(map[string]string)
+
+func init() {
+	for rtu, tcp := range commands {
+		replies[tcp] = rtu
+	}
+}
@@ -0,0 +1,51 @@
+package rtu
+
+// [psmbrtu]
+const (
+	keyRTUDefaultDevice      = "psmbrtu.default_device"
+	keyRTUDefaultBaud        = "psmbrtu.default_baud"
+	keyRTUDefaultParity      = "psmbrtu.default_parity"
+	keyRTUDefaultDataBits    = "psmbrtu.default_data_bits"
+	keyRTUDefaultStopBits    = "psmbrtu.default_stop_bits"
+	keyDownstreamDriver      = "psmbrtu.downstream_driver"
+	keyUpstreamTransport     = "psmbrtu.upstream_transport"
+	keyDeviceMaxInFlight     = "psmbrtu.device_max_in_flight"
+	keyDeviceMaxRate         = "psmbrtu.device_max_rate"
+	keyDeviceMaxQueue        = "psmbrtu.device_max_queue"
+	keyPollCoalesce          = "psmbrtu.poll_coalesce"
+	keyPollCoalesceGap       = "psmbrtu.poll_coalesce_gap"
+	defaultDevice            = "/dev/ttyUSB0"
+	defaultBaud              = 9600
+	defaultParity            = "N"
+	defaultDataBits          = 8
+	defaultStopBits          = 1
+	defaultDownstreamDriver  = "" // empty: modbusd over zmq
+	defaultUpstreamTransport = "" // empty: zmq pub/sub
+	defaultDeviceMaxInFlight = 0  // unlimited
+	defaultDeviceMaxRate     = 0  // unlimited
+	defaultDeviceMaxQueue    = 100
+	defaultPollCoalesce      = false
+	defaultPollCoalesceGap   = 0 // adjacent or overlapping polls only
+)
+
+// [zmq_rtu]
+const (
+	keyZmqPubUpstream       = "zmq_rtu.pub.upstream"
+	keyZmqPubDownstream     = "zmq_rtu.pub.downstream"
+	keyZmqSubUpstream       = "zmq_rtu.sub.upstream"
+	keyZmqSubDownstream     = "zmq_rtu.sub.downstream"
+	defaultZmqPubUpstream   = "ipc:///tmp/from.psmbrtu"
+	defaultZmqPubDownstream = "ipc:///tmp/to.modbusrtu"
+	defaultZmqSubUpstream   = "ipc:///tmp/to.psmbrtu"
+	defaultZmqSubDownstream = "ipc:///tmp/from.modbusrtu"
+)
+
+// [zmq_rtu.router]
+const (
+	keyZmqRouterUpstream       = "zmq_rtu.router.upstream"
+	keyZmqRouterMaxPending     = "zmq_rtu.router.max_pending"
+	keyZmqRouterTimeout        = "zmq_rtu.router.timeout"
+	defaultZmqRouterUpstream   = "ipc:///tmp/rpc.psmbrtu" // empty: disable router
+	defaultZmqRouterMaxPending = 1024
+	defaultZmqRouterTimeout    = 60000 // ms
+)
@@ -0,0 +1,16 @@
+package rtu
+
+import "errors"
+
+// Service
+
+var (
+	// ErrMarshal is the error when marshalling to JSON string failed.
+	ErrMarshal = errors.New("Fail to marshal!")
+
+	// ErrUnmarshal is the error when unmarshalling JSON string to structure failed.
+	ErrUnmarshal = errors.New("Fail to unmarshal!")
+
+	// ErrInvalidParity is the error when the serial parity is not N, E or O.
+	ErrInvalidParity = errors.New("Invalid parity!")
+)
@@ -0,0 +1,308 @@
+// Package rtu provide proactive service library for modbus `RTU` .
+//
+// The rtu service is the tcp proactive service serving the mbrtu command family,
+// 	serial lines are carried in the ip (device) and port (settings, e.g., 9600-8N1) fields.
+//
+// By taka@cmwang.net
+//
+package rtu
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	. "github.com/taka-wang/psmb"
+	mbtcp "github.com/taka-wang/psmb/tcp"
+	"github.com/taka-wang/psmb/viper-conf"
+)
+
+var (
+	// defaultMbDevice default serial device path
+	defaultMbDevice string
+	// defaultMbBaud default serial baud rate
+	defaultMbBaud int
+	// defaultMbParity default serial parity
+	defaultMbParity string
+	// defaultMbDataBits default serial data bits
+	defaultMbDataBits int
+	// defaultMbStopBits default serial stop bits
+	defaultMbStopBits int
+)
+
+func setDefaults() {
+	// set default psmbrtu values
+	conf.SetDefault(keyRTUDefaultDevice, defaultDevice)
+	conf.SetDefault(keyRTUDefaultBaud, defaultBaud)
+	conf.SetDefault(keyRTUDefaultParity, defaultParity)
+	conf.SetDefault(keyRTUDefaultDataBits, defaultDataBits)
+	conf.SetDefault(keyRTUDefaultStopBits, defaultStopBits)
+	conf.SetDefault(keyDownstreamDriver, defaultDownstreamDriver)
+	conf.SetDefault(keyUpstreamTransport, defaultUpstreamTransport)
+	conf.SetDefault(keyDeviceMaxInFlight, defaultDeviceMaxInFlight)
+	conf.SetDefault(keyDeviceMaxRate, defaultDeviceMaxRate)
+	conf.SetDefault(keyDeviceMaxQueue, defaultDeviceMaxQueue)
+	conf.SetDefault(keyPollCoalesce, defaultPollCoalesce)
+	conf.SetDefault(keyPollCoalesceGap, defaultPollCoalesceGap)
+	// set default zmq values
+	conf.SetDefault(keyZmqPubUpstream, defaultZmqPubUpstream)
+	conf.SetDefault(keyZmqPubDownstream, defaultZmqPubDownstream)
+	conf.SetDefault(keyZmqSubUpstream, defaultZmqSubUpstream)
+	conf.SetDefault(keyZmqSubDownstream, defaultZmqSubDownstream)
+	conf.SetDefault(keyZmqRouterUpstream, defaultZmqRouterUpstream)
+	conf.SetDefault(keyZmqRouterMaxPending, defaultZmqRouterMaxPending)
+	conf.SetDefault(keyZmqRouterTimeout, defaultZmqRouterTimeout)
+}
+
+func init() {
+	setDefaults() // set defaults
+
+	defaultMbDevice = conf.GetString(keyRTUDefaultDevice)
+	defaultMbBaud = conf.GetInt(keyRTUDefaultBaud)
+	defaultMbParity = conf.GetString(keyRTUDefaultParity)
+	defaultMbDataBits = conf.GetInt(keyRTUDefaultDataBits)
+	defaultMbStopBits = conf.GetInt(keyRTUDefaultStopBits)
+}
+
+// @Implement Family contract of the tcp package implicitly
+
+type (
+	// serial serial line parameters of requests
+	serial struct {
+		Device   string `json:"device"`
+		Baud     int    `json:"baud"`
+		Parity   string `json:"parity"`
+		DataBits int    `json:"data_bits"`
+		StopBits int    `json:"stop_bits"`
+	}
+
+	// mbrtuFamily mbrtu command family
+	mbrtuFamily struct{}
+)
+
+// NewService modbus rtu proactive serivce constructor,
+// plugins are created by the factory methods of the tcp package.
+func NewService(reader, writer, history, filter, sch string) (IProactiveService, error) {
+	return mbtcp.NewFamilyService(mbrtuFamily{}, reader, writer, history, filter, sch)
+}
+
+// marshal helper function to marshal structure
+func marshal(r interface{}) (string, error) {
+	bytes, err := json.Marshal(r) // marshal to json string
+	if err != nil {
+		return "", ErrMarshal
+	}
+	return string(bytes), nil
+}
+
+// serialLine helper function to fill serial parameters with defaults
+func serialLine(s serial) (serial, error) {
+	s.Parity = strings.ToUpper(s.Parity)
+	if s.Device == "" {
+		s.Device = defaultMbDevice
+	}
+	if s.Baud <= 0 {
+		s.Baud = defaultMbBaud
+	}
+	if s.Parity == "" {
+		s.Parity = defaultMbParity
+	}
+	if s.DataBits <= 0 {
+		s.DataBits = defaultMbDataBits
+	}
+	if s.StopBits <= 0 {
+		s.StopBits = defaultMbStopBits
+	}
+	switch s.Parity {
+	case "N", "E", "O":
+		return s, nil
+	default:
+		return s, ErrInvalidParity
+	}
+}
+
+// settings serial line settings in the port field, e.g., 9600-8N1
+func (s serial) settings() string {
+	return fmt.Sprintf("%d-%d%s%d", s.Baud, s.DataBits, s.Parity, s.StopBits)
+}
+
+// parseSerial helper function to parse serial line from the ip (device) and port (settings) fields
+func parseSerial(device, settings string) serial {
+	s := serial{Device: device}
+	fmt.Sscanf(settings, "%d-%1d%1s%d", &s.Baud, &s.DataBits, &s.Parity, &s.StopBits)
+	return s
+}
+
+// tcpRequest helper function to replace the serial line fields of rtu request by ip and port fields
+func tcpRequest(req []byte) (map[string]json.RawMessage, error) {
+	var body map[string]json.RawMessage
+	var line serial
+	if err := json.Unmarshal(req, &body); err != nil {
+		return nil, ErrUnmarshal
+	}
+	if err := json.Unmarshal(req, &line); err != nil {
+		return nil, ErrUnmarshal
+	}
+	line, err := serialLine(line)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range []string{"device", "baud", "parity", "data_bits", "stop_bits"} {
+		delete(body, key)
+	}
+	body["ip"], _ = json.Marshal(line.Device)
+	body["port"], _ = json.Marshal(line.settings())
+	return body, nil
+}
+
+// rtuPoll helper function to convert tcp poll to rtu poll
+func rtuPoll(poll MbtcpPollStatus) MbrtuPollStatus {
+	line := parseSerial(poll.IP, poll.Port)
+	return MbrtuPollStatus{
+		Tid:         poll.Tid,
+		From:        poll.From,
+		Name:        poll.Name,
+		Interval:    poll.Interval,
+		IntervalMs:  poll.IntervalMs,
+		Cron:        poll.Cron,
+		TimeZone:    poll.TimeZone,
+		Enabled:     poll.Enabled,
+		FC:          poll.FC,
+		Device:      line.Device,
+		Baud:        line.Baud,
+		Parity:      line.Parity,
+		DataBits:    line.DataBits,
+		StopBits:    line.StopBits,
+		Slave:       poll.Slave,
+		Addr:        poll.Addr,
+		Status:      poll.Status,
+		Len:         poll.Len,
+		Type:        poll.Type,
+		Order:       poll.Order,
+		Range:       poll.Range,
+		Int64String: poll.Int64String,
+	}
+}
+
+// Frame downstream frame 1 to modbusd
+func (mbrtuFamily) Frame() string {
+	return "rtu"
+}
+
+// Key get the config key of the family by the psmbtcp or zmq config key
+func (mbrtuFamily) Key(key string) string {
+	switch {
+	case strings.HasPrefix(key, "psmbtcp."):
+		return "psmbrtu." + strings.TrimPrefix(key, "psmbtcp.")
+	case strings.HasPrefix(key, "zmq."):
+		return "zmq_rtu." + strings.TrimPrefix(key, "zmq.")
+	default:
+		return key
+	}
+}
+
+// Command get the mbtcp command of the family command, false if not supported
+func (mbrtuFamily) Command(cmd string) (string, bool) {
+	tcp, ok := commands[cmd]
+	return tcp, ok
+}
+
+// Reply get the family command of the mbtcp command, false if not supported
+func (mbrtuFamily) Reply(cmd string) (string, bool) {
+	if rtu, ok := replies[cmd]; ok {
+		return rtu, true
+	}
+	switch cmd {
+	case CmdMbtcpAlarm, CmdMbtcpDeviceStatus: // events of mbtcp only
+		return "", false
+	default: // reply unsupported requests as is
+		return cmd, true
+	}
+}
+
+// Request convert the family request of the mbtcp command into mbtcp request json string
+func (mbrtuFamily) Request(cmd, req string) (string, error) {
+	switch cmd {
+	case CmdMbtcpOnceRead, CmdMbtcpOnceWrite, CmdMbtcpCreatePoll:
+		body, err := tcpRequest([]byte(req))
+		if err != nil {
+			return "", err
+		}
+		return marshal(body)
+	case CmdMbtcpImportPolls:
+		var body map[string]json.RawMessage
+		var polls []json.RawMessage
+		if err := json.Unmarshal([]byte(req), &body); err != nil {
+			return "", ErrUnmarshal
+		}
+		if err := json.Unmarshal(body["polls"], &polls); err != nil {
+			return "", ErrUnmarshal
+		}
+		var tcpPolls []map[string]json.RawMessage
+		for _, poll := range polls {
+			tcpPoll, err := tcpRequest(poll)
+			if err != nil {
+				conf.Log.WithError(err).Warn("Skip poll of invalid serial line")
+				continue
+			}
+			tcpPolls = append(tcpPolls, tcpPoll)
+		}
+		body["polls"], _ = json.Marshal(tcpPolls)
+		return marshal(body)
+	default:
+		return req, nil
+	}
+}
+
+// Response convert the mbtcp response into family response
+func (mbrtuFamily) Response(resp interface{}) interface{} {
+	switch r := resp.(type) {
+	case MbtcpPollStatus:
+		return rtuPoll(r)
+	case MbtcpPollsStatus:
+		polls := []MbrtuPollStatus{}
+		for _, poll := range r.Polls {
+			polls = append(polls, rtuPoll(poll))
+		}
+		return MbrtuPollsStatus{Tid: r.Tid, From: r.From, Status: r.Status, Polls: polls}
+	default:
+		return resp
+	}
+}
+
+// Downstream convert the mbtcp downstream command into family downstream command
+func (mbrtuFamily) Downstream(command interface{}) interface{} {
+	switch c := command.(type) {
+	case DMbtcpReadReq:
+		line := parseSerial(c.IP, c.Port)
+		return DMbrtuReadReq{
+			Tid:      c.Tid,
+			Cmd:      c.Cmd,
+			Device:   line.Device,
+			Baud:     line.Baud,
+			Parity:   line.Parity,
+			DataBits: line.DataBits,
+			StopBits: line.StopBits,
+			Slave:    c.Slave,
+			Addr:     c.Addr,
+			Len:      c.Len,
+		}
+	case DMbtcpWriteReq:
+		line := parseSerial(c.IP, c.Port)
+		return DMbrtuWriteReq{
+			Tid:      c.Tid,
+			Cmd:      c.Cmd,
+			Device:   line.Device,
+			Baud:     line.Baud,
+			Parity:   line.Parity,
+			DataBits: line.DataBits,
+			StopBits: line.StopBits,
+			Slave:    c.Slave,
+			Addr:     c.Addr,
+			Len:      c.Len,
+			Data:     c.Data,
+		}
+	default:
+		return command
+	}
+}
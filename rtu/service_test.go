@@ -0,0 +1,385 @@
+package rtu
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/taka-wang/psmb"
+	"github.com/taka-wang/psmb/cron"
+	mfilter "github.com/taka-wang/psmb/mem-filter"
+	mreader "github.com/taka-wang/psmb/mem-reader"
+	mtransport "github.com/taka-wang/psmb/mem-transport"
+	mwriter "github.com/taka-wang/psmb/mem-writer"
+	history "github.com/taka-wang/psmb/redis-history"
+	mbtcp "github.com/taka-wang/psmb/tcp"
+	driver "github.com/taka-wang/psmb/tcp-driver"
+	"github.com/taka-wang/psmb/viper-conf"
+	"github.com/takawang/sugar"
+)
+
+// memTransport transport instance created by service
+var memTransport *mtransport.Transport
+
+// echo driver instance created by service
+var echo *echoDriver
+
+func init() {
+	mbtcp.Register("SharedTransport", func(c map[string]string) (interface{}, error) {
+		tp, err := mtransport.NewTransport(c)
+		memTransport = tp.(*mtransport.Transport)
+		return tp, err
+	})
+	mbtcp.Register("Reader", mreader.NewDataStore)
+	mbtcp.Register("Writer", mwriter.NewDataStore)
+	mbtcp.Register("History", history.NewDataStore) // connect lazily
+	mbtcp.Register("Filter", mfilter.NewDataStore)
+	mbtcp.Register("Cron", cron.NewScheduler)
+	mbtcp.Register("ModbusTCP", driver.NewDriver)
+	mbtcp.Register("EchoDriver", func(c map[string]string) (interface{}, error) {
+		echo = &echoDriver{}
+		return echo, nil
+	})
+}
+
+// echoDriver fake downstream rtu driver, echo the read addresses as data
+type echoDriver struct {
+	sync.Mutex
+	// reads downstream read requests
+	reads []DMbrtuReadReq
+	// writes downstream write requests
+	writes []DMbrtuWriteReq
+}
+
+func (d *echoDriver) Do(req interface{}) ([]string, error) {
+	var cmd int
+	res := DMbtcpRes{Status: "ok"}
+	d.Lock()
+	switch r := req.(type) {
+	case DMbrtuReadReq:
+		cmd, res.Tid = r.Cmd, r.Tid
+		for idx := uint16(0); idx < r.Len; idx++ {
+			res.Data = append(res.Data, r.Addr+idx)
+		}
+		d.reads = append(d.reads, r)
+	case DMbrtuWriteReq:
+		cmd, res.Tid = r.Cmd, r.Tid
+		d.writes = append(d.writes, r)
+	default:
+		res.Status = "not rtu"
+	}
+	d.Unlock()
+	bytes, _ := json.Marshal(res)
+	return []string{strconv.Itoa(cmd), string(bytes)}, nil
+}
+
+func (d *echoDriver) Frame() string { return "rtu" }
+
+func (d *echoDriver) Close() {}
+
+// lastRead get the last downstream read request
+func (d *echoDriver) lastRead() DMbrtuReadReq {
+	d.Lock()
+	defer d.Unlock()
+	if len(d.reads) == 0 {
+		return DMbrtuReadReq{}
+	}
+	return d.reads[len(d.reads)-1]
+}
+
+// lastWrite get the last downstream write request
+func (d *echoDriver) lastWrite() DMbrtuWriteReq {
+	d.Lock()
+	defer d.Unlock()
+	if len(d.writes) == 0 {
+		return DMbrtuWriteReq{}
+	}
+	return d.writes[len(d.writes)-1]
+}
+
+// startService start the service over the shared transport and echo driver,
+// 	return the transport and the stop function to stop the service and restore the config.
+func startService(t *testing.T) (*mtransport.Transport, func()) {
+	settings := map[string]string{
+		keyUpstreamTransport: "SharedTransport",
+		keyDownstreamDriver:  "EchoDriver",
+	}
+	previous := make(map[string]string)
+	for key, value := range settings {
+		previous[key] = conf.GetString(key)
+		conf.Set(key, value)
+	}
+	reset := func() {
+		for key, value := range previous {
+			conf.Set(key, value)
+		}
+	}
+
+	srv, err := NewService("Reader", "Writer", "History", "Filter", "Cron")
+	if err != nil {
+		reset()
+		t.Fatal(err)
+	}
+	go srv.Start()
+	return memTransport, func() {
+		srv.Stop()
+		reset()
+	}
+}
+
+// recvCmd receive the next reply of the command, skip others
+func recvCmd(mem *mtransport.Transport, cmd string, timeout time.Duration) ([]string, error) {
+	for {
+		msg, err := mem.Recv(timeout)
+		if err != nil || msg[0] == cmd {
+			return msg, err
+		}
+	}
+}
+
+func TestSerialLine(t *testing.T) {
+	s := sugar.New(t)
+
+	s.Assert("Serial line settings should round trip", func(logf sugar.Log) bool {
+		line := serial{"/dev/ttyS1", 19200, "E", 7, 2}
+		got := parseSerial(line.Device, line.settings())
+		logf("settings:%s, got:%v", line.settings(), got)
+		return line.settings() == "19200-7E2" && got == line
+	})
+
+	s.Assert("Empty serial line should be filled with defaults", func(logf sugar.Log) bool {
+		line, err := serialLine(serial{Parity: "e"})
+		logf("line:%v, err:%v", line, err)
+		return err == nil && line == serial{defaultMbDevice, defaultMbBaud, "E", defaultMbDataBits, defaultMbStopBits}
+	})
+
+	s.Assert("Unknown parity should be rejected", func(logf sugar.Log) bool {
+		_, err := serialLine(serial{Parity: "X"})
+		return err == ErrInvalidParity
+	})
+
+	s.Assert("Poll schedule and encoding should be mapped both ways", func(logf sugar.Log) bool {
+		req, err := mbrtuFamily{}.Request(CmdMbtcpCreatePoll, `{"tid":1,"name":"p","interval_ms":200,"cron":"0 * * * * *","timezone":"UTC","int64_string":true,"fc":3,"slave":1,"addr":1}`)
+		var poll MbtcpPollStatus
+		if err != nil || json.Unmarshal([]byte(req), &poll) != nil {
+			logf("req:%s, err:%v", req, err)
+			return false
+		}
+		got := rtuPoll(poll)
+		logf("poll:%v, got:%v", poll, got)
+		return got.IntervalMs == 200 && got.Cron == "0 * * * * *" && got.TimeZone == "UTC" && got.Int64String
+	})
+
+	s.Assert("Rtu service should not share endpoints with tcp service", func(logf sugar.Log) bool {
+		family := mbrtuFamily{}
+		for _, key := range []string{"zmq.pub.upstream", "zmq.pub.downstream", "zmq.sub.upstream", "zmq.sub.downstream", "zmq.router.upstream"} {
+			logf("%s:%s, %s:%s", key, conf.GetString(key), family.Key(key), conf.GetString(family.Key(key)))
+			if conf.GetString(family.Key(key)) == "" || conf.GetString(family.Key(key)) == conf.GetString(key) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func TestDriver(t *testing.T) {
+	s := sugar.New(t)
+
+	s.Assert("Tcp only driver should be rejected", func(logf sugar.Log) bool {
+		previous := conf.GetString(keyDownstreamDriver)
+		conf.Set(keyDownstreamDriver, "ModbusTCP")
+		defer conf.Set(keyDownstreamDriver, previous)
+		_, err := NewService("Reader", "Writer", "History", "Filter", "Cron")
+		logf("err:%v", err)
+		return err == mbtcp.ErrDriverNotSupport
+	})
+}
+
+func TestService(t *testing.T) {
+	s := sugar.New(t)
+
+	mem, stop := startService(t)
+	defer stop()
+
+	s.Assert("Read request should be sent over the default serial line", func(logf sugar.Log) bool {
+		mem.Request(CmdMbrtuOnceRead, `{"tid":1,"fc":3,"slave":1,"addr":10,"len":2}`)
+		msg, err := recvCmd(mem, CmdMbrtuOnceRead, 2*time.Second)
+		if err != nil {
+			logf("err:%v", err)
+			return false
+		}
+		logf("msg:%v", msg)
+		var res MbtcpReadRes
+		json.Unmarshal([]byte(msg[1]), &res)
+		read := echo.lastRead()
+		logf("read:%+v", read)
+		return res.Tid == 1 && res.Status == "ok" &&
+			read.Device == defaultMbDevice && read.Baud == defaultMbBaud && read.Parity == defaultMbParity &&
+			read.DataBits == defaultMbDataBits && read.StopBits == defaultMbStopBits && read.Addr == 10 && read.Len == 2
+	})
+
+	s.Assert("Write request should be sent over the requested serial line", func(logf sugar.Log) bool {
+		mem.Request(CmdMbrtuOnceWrite, `{"tid":2,"fc":6,"device":"/dev/ttyS1","baud":19200,"parity":"e","data_bits":7,"stop_bits":2,"slave":1,"addr":20,"data":"22"}`)
+		msg, err := recvCmd(mem, CmdMbrtuOnceWrite, 2*time.Second)
+		if err != nil {
+			logf("err:%v", err)
+			return false
+		}
+		logf("msg:%v", msg)
+		var res MbtcpSimpleRes
+		json.Unmarshal([]byte(msg[1]), &res)
+		write := echo.lastWrite()
+		logf("write:%+v", write)
+		return res.Tid == 2 && res.Status == "ok" &&
+			write.Device == "/dev/ttyS1" && write.Baud == 19200 && write.Parity == "E" &&
+			write.DataBits == 7 && write.StopBits == 2 && write.Addr == 20 && write.Data == uint16(22)
+	})
+
+	s.Assert("Read requests with the same tid from two clients should not cross-talk", func(logf sugar.Log) bool {
+		mem.Request(CmdMbrtuOnceRead, `{"tid":7,"from":"a","fc":3,"slave":1,"addr":1,"len":1}`)
+		mem.Request(CmdMbrtuOnceRead, `{"tid":7,"from":"b","fc":3,"slave":1,"addr":2,"len":1}`)
+
+		got := make(map[uint16]bool)
+		for idx := 0; idx < 2; idx++ {
+			msg, err := mem.Recv(2 * time.Second)
+			if err != nil {
+				logf("err:%v", err)
+				return false
+			}
+			logf("msg:%v", msg)
+			var res struct {
+				Tid  int64    `json:"tid"`
+				Data []uint16 `json:"data"`
+			}
+			json.Unmarshal([]byte(msg[1]), &res)
+			if msg[0] != CmdMbrtuOnceRead || res.Tid != 7 || len(res.Data) != 1 {
+				return false
+			}
+			got[res.Data[0]] = true
+		}
+		read := echo.lastRead()
+		logf("read:%+v", read)
+		return got[1] && got[2] && read.Tid != "7"
+	})
+
+	s.Assert("Write requests with the same tid from two clients should not cross-talk", func(logf sugar.Log) bool {
+		mem.Request(CmdMbrtuOnceWrite, `{"tid":8,"from":"a","fc":6,"slave":1,"addr":1,"data":"1"}`)
+		mem.Request(CmdMbrtuOnceRead, `{"tid":8,"from":"b","fc":3,"slave":1,"addr":3,"len":1}`)
+
+		got := make(map[string]bool)
+		for idx := 0; idx < 2; idx++ {
+			msg, err := mem.Recv(2 * time.Second)
+			if err != nil {
+				logf("err:%v", err)
+				return false
+			}
+			logf("msg:%v", msg)
+			var res MbtcpSimpleRes
+			json.Unmarshal([]byte(msg[1]), &res)
+			if res.Tid != 8 || res.Status != "ok" {
+				return false
+			}
+			got[msg[0]] = true
+		}
+		return got[CmdMbrtuOnceWrite] && got[CmdMbrtuOnceRead]
+	})
+
+	s.Assert("Request of invalid parity should be rejected with its tid", func(logf sugar.Log) bool {
+		mem.Request(CmdMbrtuOnceRead, `{"tid":3,"fc":3,"parity":"X","slave":1,"addr":10}`)
+		msg, err := recvCmd(mem, CmdMbrtuOnceRead, 2*time.Second)
+		if err != nil {
+			logf("err:%v", err)
+			return false
+		}
+		logf("msg:%v", msg)
+		var res MbtcpSimpleRes
+		json.Unmarshal([]byte(msg[1]), &res)
+		return res.Tid == 3 && res.Status == ErrInvalidParity.Error()
+	})
+
+	s.Assert("Mbtcp only request should not be supported", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpGetQueues, `{"tid":4}`)
+		msg, err := recvCmd(mem, CmdMbtcpGetQueues, 2*time.Second)
+		if err != nil {
+			logf("err:%v", err)
+			return false
+		}
+		logf("msg:%v", msg)
+		var res MbtcpSimpleRes
+		json.Unmarshal([]byte(msg[1]), &res)
+		return res.Status == mbtcp.ErrRequestNotSupport.Error()
+	})
+
+	s.Assert("Poll should publish rtu data and report its serial line", func(logf sugar.Log) bool {
+		mem.Request(CmdMbrtuCreatePoll, `{"tid":5,"name":"rtu","interval":1,"enabled":true,"fc":3,"device":"/dev/ttyS2","baud":4800,"slave":1,"addr":30,"len":2}`)
+		if msg, err := recvCmd(mem, CmdMbrtuCreatePoll, 2*time.Second); err != nil || msg[1] != `{"tid":5,"status":"ok"}` {
+			logf("msg:%v, err:%v", msg, err)
+			return false
+		}
+		msg, err := recvCmd(mem, CmdMbrtuData, 3*time.Second)
+		if err != nil {
+			logf("err:%v", err)
+			return false
+		}
+		logf("data:%v", msg)
+		var data MbtcpPollData
+		json.Unmarshal([]byte(msg[1]), &data)
+		if data.Name != "rtu" || data.Status != "ok" {
+			return false
+		}
+
+		mem.Request(CmdMbrtuGetPoll, `{"tid":6,"name":"rtu"}`)
+		msg, err = recvCmd(mem, CmdMbrtuGetPoll, 2*time.Second)
+		if err != nil {
+			logf("err:%v", err)
+			return false
+		}
+		logf("poll:%v", msg)
+		var poll MbrtuPollStatus
+		json.Unmarshal([]byte(msg[1]), &poll)
+
+		mem.Request(CmdMbrtuDeletePoll, `{"tid":7,"name":"rtu"}`)
+		recvCmd(mem, CmdMbrtuDeletePoll, 2*time.Second)
+		return poll.Tid == 6 && poll.Device == "/dev/ttyS2" && poll.Baud == 4800 && poll.Parity == defaultMbParity &&
+			poll.DataBits == defaultMbDataBits && poll.StopBits == defaultMbStopBits && poll.Addr == 30
+	})
+
+	s.Assert("Imported polls of invalid serial line should be skipped", func(logf sugar.Log) bool {
+		mem.Request(CmdMbrtuImportPolls, `{"tid":8,"polls":[`+
+			`{"name":"p1","interval":1,"fc":3,"slave":1,"addr":1},`+
+			`{"name":"p2","interval":1,"fc":3,"parity":"X","slave":1,"addr":2}]}`)
+		if msg, err := recvCmd(mem, CmdMbrtuImportPolls, 2*time.Second); err != nil {
+			logf("msg:%v, err:%v", msg, err)
+			return false
+		}
+
+		mem.Request(CmdMbrtuExportPolls, `{"tid":9}`)
+		msg, err := recvCmd(mem, CmdMbrtuExportPolls, 2*time.Second)
+		if err != nil {
+			logf("err:%v", err)
+			return false
+		}
+		logf("polls:%v", msg)
+		var res MbrtuPollsStatus
+		json.Unmarshal([]byte(msg[1]), &res)
+
+		mem.Request(CmdMbrtuDeletePolls, `{"tid":10}`)
+		recvCmd(mem, CmdMbrtuDeletePolls, 2*time.Second)
+		return res.Tid == 9 && len(res.Polls) == 1 && res.Polls[0].Name == "p1" && res.Polls[0].Device == defaultMbDevice
+	})
+
+	s.Assert("Filter requests should be served", func(logf sugar.Log) bool {
+		mem.Request(CmdMbrtuCreateFilter, `{"tid":11,"name":"rtu","enabled":true,"type":1,"arg":[1]}`)
+		msg, err := recvCmd(mem, CmdMbrtuCreateFilter, 2*time.Second)
+		if err != nil {
+			logf("err:%v", err)
+			return false
+		}
+		logf("msg:%v", msg)
+		mem.Request(CmdMbrtuDeleteFilter, `{"tid":12,"name":"rtu"}`)
+		recvCmd(mem, CmdMbrtuDeleteFilter, 2*time.Second)
+		return msg[1] == `{"tid":11,"status":"ok"}`
+	})
+}
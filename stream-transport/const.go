@@ -0,0 +1,25 @@
+package transport
+
+// [stream]
+const (
+	keyListen         = "stream.listen"
+	keyBufferSize     = "stream.buffer_size"
+	keyDropPolicy     = "stream.drop_policy"
+	defaultListen     = ":8081"
+	defaultBufferSize = 64
+	defaultDropPolicy = dropOldest
+)
+
+// drop policy when the client buffer is full
+const (
+	// dropOldest drop the oldest buffered message
+	dropOldest = "oldest"
+	// dropNewest drop the incoming message
+	dropNewest = "newest"
+)
+
+// server-sent events
+const (
+	eventDropped = "dropped"
+	paramPoll    = "poll"
+)
@@ -0,0 +1,14 @@
+package transport
+
+import "errors"
+
+var (
+	// ErrInvalidMessageLength is the error when the frames are not [command, json].
+	ErrInvalidMessageLength = errors.New("Invalid message length!")
+
+	// ErrInvalidPattern is the error when the poll name pattern is malformed.
+	ErrInvalidPattern = errors.New("Invalid poll name pattern!")
+
+	// ErrStreamNotSupport is the error when the response writer can not flush.
+	ErrStreamNotSupport = errors.New("Streaming not support!")
+)
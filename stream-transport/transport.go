@@ -0,0 +1,214 @@
+// Package transport a server-sent events (SSE) upstream transport streaming live poll data.
+//
+// Clients subscribe by `GET /stream?poll=<name or glob>` (repeatable, default all polls),
+// each client has a bounded buffer with a drop policy, so a slow client never blocks the service.
+//
+// By taka@cmwang.net
+//
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"sync"
+
+	"github.com/taka-wang/psmb"
+	"github.com/taka-wang/psmb/viper-conf"
+)
+
+func init() {
+	conf.SetDefault(keyListen, defaultListen)
+	conf.SetDefault(keyBufferSize, defaultBufferSize)
+	conf.SetDefault(keyDropPolicy, defaultDropPolicy)
+}
+
+//@Implement IUpstreamTransport implicitly
+
+type (
+	// client stream subscriber
+	client struct {
+		// mutex for dropped counter and buffer eviction
+		sync.Mutex
+		// patterns poll name patterns
+		patterns []string
+		// ch bounded message buffer
+		ch chan string
+		// dropped the number of dropped messages since the last delivery
+		dropped int
+		// policy drop policy
+		policy string
+	}
+
+	// transport sse transport
+	transport struct {
+		// read writer mutex for server and clients
+		sync.RWMutex
+		// server http server
+		server *http.Server
+		// clients subscribers
+		clients map[*client]bool
+	}
+)
+
+// NewTransport instantiate sse transport
+func NewTransport(c map[string]string) (interface{}, error) {
+	return &transport{
+		clients: make(map[*client]bool),
+	}, nil
+}
+
+// newClient create subscriber with poll name patterns
+func newClient(patterns []string) (*client, error) {
+	if len(patterns) == 0 {
+		patterns = []string{"*"}
+	}
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, ErrInvalidPattern
+		}
+	}
+	return &client{
+		patterns: patterns,
+		ch:       make(chan string, conf.GetInt(keyBufferSize)),
+		policy:   conf.GetString(keyDropPolicy),
+	}, nil
+}
+
+// match check whether the poll name matches any pattern
+func (c *client) match(name string) bool {
+	for _, p := range c.patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// push buffer message without blocking, apply drop policy if the buffer is full
+func (c *client) push(msg string) {
+	c.Lock()
+	defer c.Unlock()
+	for {
+		select {
+		case c.ch <- msg:
+			return
+		default:
+		}
+		c.dropped++
+		if c.policy == dropNewest {
+			return
+		}
+		select { // drop oldest and retry
+		case <-c.ch:
+		default:
+		}
+	}
+}
+
+// takeDropped get and reset dropped counter
+func (c *client) takeDropped() int {
+	c.Lock()
+	defer c.Unlock()
+	dropped := c.dropped
+	c.dropped = 0
+	return dropped
+}
+
+// Start listen on the configured address and serve streams;
+// 	the stream is output only, handler is not used.
+func (t *transport) Start(handler func(msg []string)) error {
+	ln, err := net.Listen("tcp", conf.GetString(keyListen))
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: t}
+	t.Lock()
+	t.server = server
+	t.Unlock()
+	go func() {
+		if err := server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			conf.Log.WithError(err).Error("Stream server")
+		}
+	}()
+	return nil
+}
+
+// Stop close stream server and all streams
+func (t *transport) Stop() {
+	t.RLock()
+	server := t.server
+	t.RUnlock()
+	if server != nil {
+		server.Close()
+	}
+}
+
+// Send push poll data to matched subscribers, others are ignored
+func (t *transport) Send(msg []string) error {
+	if len(msg) != 2 {
+		return ErrInvalidMessageLength
+	}
+	if msg[0] != psmb.CmdMbtcpData {
+		return nil
+	}
+
+	var data psmb.MbtcpPollData
+	if err := json.Unmarshal([]byte(msg[1]), &data); err != nil {
+		return nil // not poll data
+	}
+
+	t.RLock()
+	defer t.RUnlock()
+	for c := range t.clients {
+		if c.match(data.Name) {
+			c.push(msg[1])
+		}
+	}
+	return nil
+}
+
+// ServeHTTP stream poll data to the subscriber until disconnected
+func (t *transport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, ErrStreamNotSupport.Error(), http.StatusInternalServerError)
+		return
+	}
+	c, err := newClient(r.URL.Query()[paramPoll])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	t.Lock()
+	t.clients[c] = true
+	t.Unlock()
+	defer func() {
+		t.Lock()
+		delete(t.clients, c)
+		t.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	done := r.Context().Done()
+	for {
+		select {
+		case msg := <-c.ch:
+			if dropped := c.takeDropped(); dropped > 0 {
+				fmt.Fprintf(w, "event: %s\ndata: %d\n\n", eventDropped, dropped)
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", psmb.CmdMbtcpData, msg)
+			flusher.Flush()
+		case <-done:
+			return
+		}
+	}
+}
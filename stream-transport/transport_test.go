@@ -0,0 +1,114 @@
+package transport
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/taka-wang/psmb"
+	psmbtcp "github.com/taka-wang/psmb/tcp"
+	"github.com/takawang/sugar"
+)
+
+func init() {
+	psmbtcp.Register("Stream", NewTransport)
+}
+
+// subscribe connect to stream and wait until the client is registered
+func subscribe(t *transport, url string) (*bufio.Reader, func(), error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, nil, err
+	}
+	for idx := 0; idx < 100; idx++ {
+		t.RLock()
+		n := len(t.clients)
+		t.RUnlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return bufio.NewReader(resp.Body), func() { resp.Body.Close() }, nil
+}
+
+// next read the next event and data
+func next(r *bufio.Reader) (string, string) {
+	var event, data string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return event, data
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			return event, data
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	s := sugar.New(t)
+
+	tp, err := psmbtcp.UpstreamTransportCreator("Stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := tp.(*transport)
+
+	s.Assert("Poll data should be streamed to matched subscribers", func(logf sugar.Log) bool {
+		srv := httptest.NewServer(stream)
+		defer srv.Close()
+
+		r, closer, err := subscribe(stream, srv.URL+"/stream?poll=LED_*")
+		if err != nil {
+			return false
+		}
+		defer closer()
+
+		tp.Send([]string{psmb.CmdMbtcpData, `{"name":"TEMP_1","status":"ok"}`}) // not matched
+		tp.Send([]string{psmb.CmdMbtcpAlarm, `{"name":"LED_1"}`})               // not poll data
+		tp.Send([]string{psmb.CmdMbtcpData, `{"name":"LED_1","status":"ok"}`})
+		event, data := next(r)
+		logf("event:%s, data:%s", event, data)
+		return event == psmb.CmdMbtcpData && strings.Contains(data, "LED_1")
+	})
+
+	s.Assert("Invalid pattern should be bad request", func(logf sugar.Log) bool {
+		w := httptest.NewRecorder()
+		stream.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stream?poll=[", nil))
+		return w.Code == http.StatusBadRequest
+	})
+
+	s.Assert("Full buffer should drop the oldest message", func(logf sugar.Log) bool {
+		c := &client{ch: make(chan string, 2), policy: dropOldest}
+		for _, msg := range []string{"1", "2", "3"} {
+			c.push(msg)
+		}
+		first, second := <-c.ch, <-c.ch
+		logf("buffer:%s %s, dropped:%d", first, second, c.dropped)
+		return first == "2" && second == "3" && c.takeDropped() == 1 && c.dropped == 0
+	})
+
+	s.Assert("Full buffer should drop the newest message", func(logf sugar.Log) bool {
+		c := &client{ch: make(chan string, 2), policy: dropNewest}
+		for _, msg := range []string{"1", "2", "3"} {
+			c.push(msg)
+		}
+		first, second := <-c.ch, <-c.ch
+		logf("buffer:%s %s, dropped:%d", first, second, c.dropped)
+		return first == "1" && second == "2" && c.dropped == 1
+	})
+
+	s.Assert("Invalid frames should fail", func(logf sugar.Log) bool {
+		return tp.Send([]string{"cmd"}) == ErrInvalidMessageLength
+	})
+}
@@ -0,0 +1,31 @@
+package driver
+
+// [tcp_driver]
+const (
+	keyTimeout     = "tcp_driver.timeout"
+	defaultTimeout = 200000 // usec, the same unit as modbusd
+)
+
+// modbusd command code
+const (
+	fc1          = 1
+	fc2          = 2
+	fc3          = 3
+	fc4          = 4
+	fc5          = 5
+	fc6          = 6
+	fc15         = 15
+	fc16         = 16
+	setMbTimeout = 50
+	getMbTimeout = 51
+)
+
+// modbus tcp frame
+const (
+	// mbapHeaderLength transaction id, protocol id, length and unit id
+	mbapHeaderLength = 7
+	// maxPDULength maximum modbus pdu length
+	maxPDULength = 253
+	// exceptionMask exception function code mask
+	exceptionMask = 0x80
+)
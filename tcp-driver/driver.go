@@ -0,0 +1,213 @@
+// Package driver an in-process modbus tcp downstream driver.
+//
+// Guideline: if error is one of the return, don't duplicately log to output.
+//
+// By taka@cmwang.net
+//
+package driver
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/taka-wang/psmb"
+	"github.com/taka-wang/psmb/viper-conf"
+)
+
+var defaultTimeoutUsec int64
+
+func init() {
+	conf.SetDefault(keyTimeout, defaultTimeout)
+	defaultTimeoutUsec = conf.GetInt64(keyTimeout)
+}
+
+//@Implement IDownstreamDriver implicitly
+
+type (
+	// conn pooled modbus tcp connection
+	conn struct {
+		sync.Mutex
+		// c underlying tcp connection, nil if disconnected
+		c net.Conn
+		// tid modbus transaction id
+		tid uint16
+	}
+
+	// driver modbus tcp driver
+	driver struct {
+		// read writer mutex for pool
+		sync.RWMutex
+		// pool connection pool: (ip:port/slave, conn)
+		pool map[string]*conn
+		// timeout dial/read/write timeout in usec
+		timeout int64
+	}
+)
+
+// NewDriver instantiate modbus tcp driver
+func NewDriver(conf map[string]string) (interface{}, error) {
+	return &driver{
+		pool:    make(map[string]*conn),
+		timeout: defaultTimeoutUsec,
+	}, nil
+}
+
+// getConn get or create pooled connection by key
+func (d *driver) getConn(key string) *conn {
+	d.RLock()
+	c, ok := d.pool[key]
+	d.RUnlock()
+	if ok {
+		return c
+	}
+
+	d.Lock()
+	defer d.Unlock()
+	if c, ok = d.pool[key]; !ok {
+		c = &conn{}
+		d.pool[key] = c
+	}
+	return c
+}
+
+// transact send request pdu to the slave and return response pdu;
+// 	the connection is dropped on I/O errors and re-dialed on the next call.
+func (d *driver) transact(ip, port string, slave uint8, pdu []byte) ([]byte, error) {
+	addr := net.JoinHostPort(ip, port)
+	c := d.getConn(addr + "/" + strconv.Itoa(int(slave)))
+	timeout := time.Duration(atomic.LoadInt64(&d.timeout)) * time.Microsecond
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.c == nil {
+		nc, err := net.DialTimeout("tcp", addr, timeout)
+		if err != nil {
+			return nil, err
+		}
+		c.c = nc
+	}
+
+	res, err := c.roundTrip(slave, pdu, timeout)
+	if err != nil {
+		c.c.Close()
+		c.c = nil
+	}
+	return res, err
+}
+
+// roundTrip write adu and read the matched response pdu
+func (c *conn) roundTrip(slave uint8, pdu []byte, timeout time.Duration) ([]byte, error) {
+	c.tid++
+	adu := make([]byte, mbapHeaderLength, mbapHeaderLength+len(pdu))
+	binary.BigEndian.PutUint16(adu[0:], c.tid)
+	binary.BigEndian.PutUint16(adu[2:], 0) // protocol id
+	binary.BigEndian.PutUint16(adu[4:], uint16(len(pdu)+1))
+	adu[6] = slave
+	adu = append(adu, pdu...)
+
+	if err := c.c.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	if _, err := c.c.Write(adu); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, mbapHeaderLength)
+	if _, err := io.ReadFull(c.c, header); err != nil {
+		return nil, err
+	}
+	length := int(binary.BigEndian.Uint16(header[4:]))
+	if length < 2 || length > maxPDULength+1 {
+		return nil, ErrInvalidResponse
+	}
+	res := make([]byte, length-1)
+	if _, err := io.ReadFull(c.c, res); err != nil {
+		return nil, err
+	}
+	if binary.BigEndian.Uint16(header[0:]) != c.tid || header[6] != slave {
+		return nil, ErrTransactionMismatch
+	}
+	return res, nil
+}
+
+// response marshal response to modbusd frames
+func response(cmd int, resp interface{}) ([]string, error) {
+	bytes, err := json.Marshal(resp)
+	if err != nil {
+		return nil, ErrMarshal
+	}
+	return []string{strconv.Itoa(cmd), string(bytes)}, nil
+}
+
+// Do execute downstream request
+func (d *driver) Do(req interface{}) ([]string, error) {
+	switch r := req.(type) {
+	case psmb.DMbtcpReadReq:
+		resp := psmb.DMbtcpRes{Tid: r.Tid, Status: "ok"}
+		switch r.Cmd {
+		case fc1, fc2, fc3, fc4:
+			pdu, err := d.transact(r.IP, r.Port, r.Slave, readPDU(r.Cmd, r.Addr, r.Len))
+			if err == nil {
+				resp.Data, err = parseReadPDU(r.Cmd, r.Len, pdu)
+			}
+			if err != nil {
+				resp.Status = err.Error()
+			}
+		default:
+			resp.Status = ErrInvalidFunctionCode.Error()
+		}
+		return response(r.Cmd, resp)
+	case psmb.DMbtcpWriteReq:
+		resp := psmb.DMbtcpRes{Tid: r.Tid, Status: "ok"}
+		pdu, err := writePDU(r.Cmd, r.Addr, r.Len, r.Data)
+		if err == nil {
+			if pdu, err = d.transact(r.IP, r.Port, r.Slave, pdu); err == nil {
+				err = checkPDU(r.Cmd, pdu)
+			}
+		}
+		if err != nil {
+			resp.Status = err.Error()
+		}
+		return response(r.Cmd, resp)
+	case psmb.DMbtcpTimeout:
+		resp := psmb.DMbtcpTimeout{Tid: r.Tid, Cmd: r.Cmd, Status: "ok"}
+		switch r.Cmd {
+		case setMbTimeout:
+			atomic.StoreInt64(&d.timeout, r.Timeout)
+		case getMbTimeout:
+			resp.Timeout = atomic.LoadInt64(&d.timeout)
+		default:
+			resp.Status = ErrRequestNotSupport.Error()
+		}
+		return response(r.Cmd, resp)
+	default:
+		return nil, ErrRequestNotSupport
+	}
+}
+
+// Frame serve modbus tcp requests only
+func (d *driver) Frame() string {
+	return "tcp"
+}
+
+// Close close all pooled connections
+func (d *driver) Close() {
+	d.Lock()
+	defer d.Unlock()
+	for key, c := range d.pool {
+		c.Lock()
+		if c.c != nil {
+			c.c.Close()
+			c.c = nil
+		}
+		c.Unlock()
+		delete(d.pool, key)
+	}
+}
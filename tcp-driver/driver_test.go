@@ -0,0 +1,220 @@
+package driver
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/taka-wang/psmb"
+	psmbtcp "github.com/taka-wang/psmb/tcp"
+	"github.com/takawang/sugar"
+)
+
+func init() {
+	psmbtcp.Register("Driver", NewDriver)
+}
+
+// fakeSlave loopback modbus tcp slave with coils and holding registers
+type fakeSlave struct {
+	sync.Mutex
+	ln    net.Listener
+	coils []uint16
+	regs  []uint16
+	conns int
+}
+
+func newFakeSlave() (*fakeSlave, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &fakeSlave{ln: ln, coils: make([]uint16, 32), regs: make([]uint16, 32)}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.Lock()
+			s.conns++
+			s.Unlock()
+			go s.serve(c)
+		}
+	}()
+	return s, nil
+}
+
+func (s *fakeSlave) port() string {
+	_, port, _ := net.SplitHostPort(s.ln.Addr().String())
+	return port
+}
+
+func (s *fakeSlave) serve(c net.Conn) {
+	defer c.Close()
+	for {
+		header := make([]byte, mbapHeaderLength)
+		if _, err := io.ReadFull(c, header); err != nil {
+			return
+		}
+		req := make([]byte, binary.BigEndian.Uint16(header[4:])-1)
+		if _, err := io.ReadFull(c, req); err != nil {
+			return
+		}
+		res := s.handle(req)
+		binary.BigEndian.PutUint16(header[4:], uint16(len(res)+1))
+		c.Write(append(header, res...))
+	}
+}
+
+func (s *fakeSlave) handle(req []byte) []byte {
+	s.Lock()
+	defer s.Unlock()
+
+	fc := int(req[0])
+	addr := int(binary.BigEndian.Uint16(req[1:]))
+	val := binary.BigEndian.Uint16(req[3:])
+	if addr >= len(s.regs) {
+		return []byte{req[0] | exceptionMask, 2} // illegal data address
+	}
+
+	switch fc {
+	case fc1:
+		return append([]byte{req[0], byte((val + 7) / 8)}, packBits(s.coils[addr:addr+int(val)])...)
+	case fc3:
+		res := []byte{req[0], byte(2 * val)}
+		for _, reg := range s.regs[addr : addr+int(val)] {
+			res = append(res, byte(reg>>8), byte(reg))
+		}
+		return res
+	case fc5:
+		if val == 0xFF00 {
+			s.coils[addr] = 1
+		} else {
+			s.coils[addr] = 0
+		}
+		return req
+	case fc6:
+		s.regs[addr] = val
+		return req
+	case fc15:
+		copy(s.coils[addr:], unpackBits(req[6:], val))
+		return req[:5]
+	case fc16:
+		for idx := 0; idx < int(val); idx++ {
+			s.regs[addr+idx] = binary.BigEndian.Uint16(req[6+2*idx:])
+		}
+		return req[:5]
+	default:
+		return []byte{req[0] | exceptionMask, 1} // illegal function
+	}
+}
+
+func TestDriver(t *testing.T) {
+	s := sugar.New(t)
+
+	slave, err := newFakeSlave()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer slave.ln.Close()
+
+	drv, err := psmbtcp.DownstreamDriverCreator("Driver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer drv.Close()
+
+	do := func(req interface{}) (psmb.DMbtcpRes, error) {
+		var res psmb.DMbtcpRes
+		msg, err := drv.Do(req)
+		if err != nil {
+			return res, err
+		}
+		err = json.Unmarshal([]byte(msg[1]), &res)
+		return res, err
+	}
+
+	s.Assert("`FC16` write and `FC3` read registers", func(logf sugar.Log) bool {
+		w := psmb.DMbtcpWriteReq{Tid: "1", Cmd: fc16, IP: "127.0.0.1", Port: slave.port(), Slave: 1, Addr: 2, Len: 3, Data: []uint16{1, 2, 0xABCD}}
+		if res, err := do(w); err != nil || res.Status != "ok" || res.Tid != "1" {
+			logf("res:%v, err:%v", res, err)
+			return false
+		}
+		r := psmb.DMbtcpReadReq{Tid: "2", Cmd: fc3, IP: "127.0.0.1", Port: slave.port(), Slave: 1, Addr: 2, Len: 3}
+		res, err := do(r)
+		logf("res:%v, err:%v", res, err)
+		return err == nil && res.Status == "ok" && len(res.Data) == 3 && res.Data[2] == 0xABCD
+	})
+
+	s.Assert("`FC5`, `FC15` write and `FC1` read bits", func(logf sugar.Log) bool {
+		w := psmb.DMbtcpWriteReq{Tid: "3", Cmd: fc15, IP: "127.0.0.1", Port: slave.port(), Slave: 1, Addr: 0, Len: 10, Data: []uint16{1, 0, 1, 0, 0, 0, 0, 0, 0, 1}}
+		if res, err := do(w); err != nil || res.Status != "ok" {
+			return false
+		}
+		w = psmb.DMbtcpWriteReq{Tid: "4", Cmd: fc5, IP: "127.0.0.1", Port: slave.port(), Slave: 1, Addr: 1, Data: uint16(1)}
+		if res, err := do(w); err != nil || res.Status != "ok" {
+			return false
+		}
+		r := psmb.DMbtcpReadReq{Tid: "5", Cmd: fc1, IP: "127.0.0.1", Port: slave.port(), Slave: 1, Addr: 0, Len: 10}
+		res, err := do(r)
+		logf("res:%v, err:%v", res, err)
+		return err == nil && res.Status == "ok" && len(res.Data) == 10 &&
+			res.Data[0] == 1 && res.Data[1] == 1 && res.Data[2] == 1 && res.Data[3] == 0 && res.Data[9] == 1
+	})
+
+	s.Assert("Modbus exception should be reported in status", func(logf sugar.Log) bool {
+		r := psmb.DMbtcpReadReq{Tid: "6", Cmd: fc3, IP: "127.0.0.1", Port: slave.port(), Slave: 1, Addr: 100, Len: 1}
+		res, err := do(r)
+		logf("res:%v, err:%v", res, err)
+		return err == nil && res.Status != "ok" && res.Tid == "6"
+	})
+
+	s.Assert("Connections should be pooled per slave", func(logf sugar.Log) bool {
+		for _, id := range []uint8{1, 2, 2} {
+			r := psmb.DMbtcpReadReq{Tid: "7", Cmd: fc3, IP: "127.0.0.1", Port: slave.port(), Slave: id, Addr: 0, Len: 1}
+			if _, err := do(r); err != nil {
+				return false
+			}
+		}
+		slave.Lock()
+		defer slave.Unlock()
+		logf("connections:%d", slave.conns)
+		return slave.conns == 2
+	})
+
+	s.Assert("Invalid write data should fail without connecting", func(logf sugar.Log) bool {
+		w := psmb.DMbtcpWriteReq{Tid: "8", Cmd: fc16, IP: "127.0.0.1", Port: slave.port(), Slave: 1, Addr: 0, Len: 3, Data: []uint16{1}}
+		res, err := do(w)
+		logf("res:%v, err:%v", res, err)
+		return err == nil && res.Status == ErrInvalidData.Error()
+	})
+
+	s.Assert("Unreachable slave should be reported in status", func(logf sugar.Log) bool {
+		r := psmb.DMbtcpReadReq{Tid: "9", Cmd: fc3, IP: "127.0.0.1", Port: "1", Slave: 1, Addr: 0, Len: 1}
+		res, err := do(r)
+		logf("res:%v, err:%v", res, err)
+		return err == nil && res.Status != "ok"
+	})
+
+	s.Assert("`set` and `get` timeout", func(logf sugar.Log) bool {
+		if _, err := drv.Do(psmb.DMbtcpTimeout{Tid: "10", Cmd: setMbTimeout, Timeout: 300000}); err != nil {
+			return false
+		}
+		msg, err := drv.Do(psmb.DMbtcpTimeout{Tid: "11", Cmd: getMbTimeout})
+		if err != nil {
+			return false
+		}
+		var res psmb.DMbtcpTimeout
+		json.Unmarshal([]byte(msg[1]), &res)
+		logf("frames:%v", msg)
+		return msg[0] == "51" && res.Status == "ok" && res.Timeout == 300000
+	})
+
+	s.Assert("Unknown request should fail", func(logf sugar.Log) bool {
+		_, err := drv.Do("fake")
+		return err == ErrRequestNotSupport
+	})
+}
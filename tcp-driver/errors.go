@@ -0,0 +1,23 @@
+package driver
+
+import "errors"
+
+var (
+	// ErrRequestNotSupport is the error when the downstream request is not supported.
+	ErrRequestNotSupport = errors.New("Request not support!")
+
+	// ErrInvalidFunctionCode is the error when the function code is not allowed.
+	ErrInvalidFunctionCode = errors.New("Invalid function code!")
+
+	// ErrInvalidData is the error when the write data type or length is invalid.
+	ErrInvalidData = errors.New("Invalid write data!")
+
+	// ErrInvalidResponse is the error when the response frame is malformed.
+	ErrInvalidResponse = errors.New("Invalid modbus response!")
+
+	// ErrTransactionMismatch is the error when the response transaction id does not match.
+	ErrTransactionMismatch = errors.New("Modbus transaction id mismatch!")
+
+	// ErrMarshal is the error when marshalling to JSON string failed.
+	ErrMarshal = errors.New("Fail to marshal!")
+)
@@ -0,0 +1,130 @@
+package driver
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// readPDU build read coils/discrete inputs/registers request pdu
+func readPDU(fc int, addr, length uint16) []byte {
+	pdu := make([]byte, 5)
+	pdu[0] = byte(fc)
+	binary.BigEndian.PutUint16(pdu[1:], addr)
+	binary.BigEndian.PutUint16(pdu[3:], length)
+	return pdu
+}
+
+// packBits pack bits (i.e., 0 or 1 in uint16 array) to bytes, LSB first
+func packBits(bits []uint16) []byte {
+	bytes := make([]byte, (len(bits)+7)/8)
+	for idx, bit := range bits {
+		if bit != 0 {
+			bytes[idx/8] |= 1 << uint(idx%8)
+		}
+	}
+	return bytes
+}
+
+// unpackBits unpack bytes to bits in uint16 array, LSB first
+func unpackBits(bytes []byte, length uint16) []uint16 {
+	bits := make([]uint16, length)
+	for idx := range bits {
+		bits[idx] = uint16(bytes[idx/8]>>uint(idx%8)) & 1
+	}
+	return bits
+}
+
+// writePDU build write single/multiple coils/registers request pdu,
+// 	data should be uint16 (FC5, FC6) or []uint16 (FC15, FC16)
+func writePDU(fc int, addr, length uint16, data interface{}) ([]byte, error) {
+	switch fc {
+	case fc5, fc6:
+		val, ok := data.(uint16)
+		if !ok {
+			return nil, ErrInvalidData
+		}
+		if fc == fc5 && val != 0 {
+			val = 0xFF00 // ON
+		}
+		pdu := make([]byte, 5)
+		pdu[0] = byte(fc)
+		binary.BigEndian.PutUint16(pdu[1:], addr)
+		binary.BigEndian.PutUint16(pdu[3:], val)
+		return pdu, nil
+	case fc15, fc16:
+		vals, ok := data.([]uint16)
+		if !ok || len(vals) == 0 || int(length) > len(vals) {
+			return nil, ErrInvalidData
+		}
+		if length == 0 {
+			length = uint16(len(vals))
+		}
+		vals = vals[:length]
+
+		var payload []byte
+		if fc == fc15 {
+			payload = packBits(vals)
+		} else {
+			payload = make([]byte, 2*len(vals))
+			for idx, val := range vals {
+				binary.BigEndian.PutUint16(payload[2*idx:], val)
+			}
+		}
+		if 6+len(payload) > maxPDULength {
+			return nil, ErrInvalidData
+		}
+
+		pdu := make([]byte, 6, 6+len(payload))
+		pdu[0] = byte(fc)
+		binary.BigEndian.PutUint16(pdu[1:], addr)
+		binary.BigEndian.PutUint16(pdu[3:], length)
+		pdu[5] = byte(len(payload))
+		return append(pdu, payload...), nil
+	default:
+		return nil, ErrInvalidFunctionCode
+	}
+}
+
+// checkPDU check response pdu function code and exception
+func checkPDU(fc int, pdu []byte) error {
+	if len(pdu) < 2 {
+		return ErrInvalidResponse
+	}
+	if pdu[0] == byte(fc)|exceptionMask {
+		return fmt.Errorf("Modbus exception %d", pdu[1])
+	}
+	if pdu[0] != byte(fc) {
+		return ErrInvalidResponse
+	}
+	return nil
+}
+
+// parseReadPDU parse read response pdu to uint16 array;
+// 	bits (FC1, FC2) are unpacked one bit per element.
+func parseReadPDU(fc int, length uint16, pdu []byte) ([]uint16, error) {
+	if err := checkPDU(fc, pdu); err != nil {
+		return nil, err
+	}
+	count := int(pdu[1])
+	if len(pdu) != count+2 {
+		return nil, ErrInvalidResponse
+	}
+	payload := pdu[2:]
+
+	switch fc {
+	case fc1, fc2:
+		if count < (int(length)+7)/8 {
+			return nil, ErrInvalidResponse
+		}
+		return unpackBits(payload, length), nil
+	default: // fc3, fc4
+		if count != 2*int(length) {
+			return nil, ErrInvalidResponse
+		}
+		regs := make([]uint16, length)
+		for idx := range regs {
+			regs[idx] = binary.BigEndian.Uint16(payload[2*idx:])
+		}
+		return regs, nil
+	}
+}
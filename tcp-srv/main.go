@@ -2,14 +2,21 @@ package main
 
 import (
 	cron "github.com/taka-wang/psmb/cron"
+	gateway "github.com/taka-wang/psmb/http-transport"
+	malarm "github.com/taka-wang/psmb/mem-alarm"
 	mfilter "github.com/taka-wang/psmb/mem-filter"
 	mreader "github.com/taka-wang/psmb/mem-reader"
+	mtag "github.com/taka-wang/psmb/mem-tag"
 	mwriter "github.com/taka-wang/psmb/mem-writer"
 	mgohistory "github.com/taka-wang/psmb/mgo-history"
+	mqtt "github.com/taka-wang/psmb/mqtt-transport"
+	ralarm "github.com/taka-wang/psmb/redis-alarm"
 	rfilter "github.com/taka-wang/psmb/redis-filter"
 	history "github.com/taka-wang/psmb/redis-history"
 	rwriter "github.com/taka-wang/psmb/redis-writer"
+	stream "github.com/taka-wang/psmb/stream-transport"
 	mbtcp "github.com/taka-wang/psmb/tcp"
+	driver "github.com/taka-wang/psmb/tcp-driver"
 )
 
 func init() {
@@ -21,7 +28,14 @@ func init() {
 	mbtcp.Register("MgoHistory", mgohistory.NewDataStore)
 	mbtcp.Register("MemFilter", mfilter.NewDataStore)
 	mbtcp.Register("RedisFilter", rfilter.NewDataStore)
+	mbtcp.Register("MemAlarm", malarm.NewDataStore)
+	mbtcp.Register("RedisAlarm", ralarm.NewDataStore)
+	mbtcp.Register("MemTag", mtag.NewDataStore)
 	mbtcp.Register("Cron", cron.NewScheduler)
+	mbtcp.Register("ModbusTCP", driver.NewDriver)
+	mbtcp.Register("MQTT", mqtt.NewTransport)
+	mbtcp.Register("HTTP", gateway.NewTransport)
+	mbtcp.Register("Stream", stream.NewTransport)
 }
 
 func main() {
@@ -32,6 +46,10 @@ func main() {
 		"History",     // History Data Store
 		"RedisFilter", // Filter Data Store
 		"Cron",        // Scheduler
+		mbtcp.Options{
+			Alarm: "RedisAlarm", // Alarm Data Store
+			Tag:   "MemTag",     // Tag Data Store
+		},
 	); srv != nil {
 		srv.Start()
 	}
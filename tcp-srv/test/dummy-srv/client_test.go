@@ -54,6 +54,20 @@ func subscriber() (string, string) {
 	}
 }
 
+// generic dealer request and reply
+func dealer(cmd, json string) (string, string) {
+	requester, _ := zmq.NewSocket(zmq.DEALER)
+	defer requester.Close()
+	requester.Connect("ipc:///tmp/rpc.psmb")
+	requester.Send(cmd, zmq.SNDMORE) // frame 1
+	requester.Send(json, 0)          // frame 2
+	msg, _ := requester.RecvMessage(0)
+	if len(msg) != 2 {
+		return "", ""
+	}
+	return msg[0], msg[1]
+}
+
 // long subscribe for data
 func longSubscriber() {
 	longRun = true
@@ -4877,6 +4891,38 @@ func TestPollsRequest(t *testing.T) {
 	*/
 }
 
+func TestRouterRequest(t *testing.T) {
+	s := sugar.New(t)
+
+	s.Assert("`mbtcp.once.read` test over router: port 502 - reply to sender only", func(logf sugar.Log) bool {
+		readReq := psmb.MbtcpReadReq{
+			From:  "router",
+			Tid:   time.Now().UTC().UnixNano(),
+			IP:    hostName,
+			Port:  portNum1,
+			FC:    1,
+			Slave: 1,
+			Addr:  3,
+			Len:   1,
+		}
+
+		readReqStr, _ := json.Marshal(readReq)
+		cmd := "mbtcp.once.read"
+		s1, s2 := dealer(cmd, string(readReqStr))
+
+		logf("req: %s, %s", cmd, string(readReqStr))
+		logf("res: %s, %s", s1, s2)
+
+		// parse resonse
+		var r2 psmb.MbtcpReadRes
+		if err := json.Unmarshal([]byte(s2), &r2); err != nil {
+			logf("json err: %v", err)
+		}
+		// check response
+		return s1 == cmd && r2.Tid == readReq.Tid && r2.Status == "ok"
+	})
+}
+
 /*
 func TestPSMB(t *testing.T) {
 	s := sugar.New(t)
@@ -0,0 +1,272 @@
+package tcp
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+
+	. "github.com/taka-wang/psmb"
+	"github.com/taka-wang/psmb/viper-conf"
+)
+
+// coalescedPoll poll served by a block read
+type coalescedPoll struct {
+	// tid downstream tid of the poll
+	tid  string
+	addr uint16
+	len  uint16
+}
+
+// pollBlock block read of coalesced polls
+type pollBlock struct {
+	// req schedule of the block read
+	req MbtcpPollStatus
+	// command downstream block read request
+	command DMbtcpReadReq
+	members []coalescedPoll
+}
+
+// coalescer poll optimizer, group enabled polls of the same device, function code
+// 	and schedule into block reads within the modbus limits and gap tolerance.
+type coalescer struct {
+	sync.RWMutex
+	// regrouping serialize regrouping with scheduler updates
+	regrouping sync.Mutex
+	enabled    bool
+	// gap max unpolled registers (or bits) between polls of a block
+	gap int
+	// tids (poll name, downstream tid)
+	tids map[string]string
+	// blocks (block tid, block read)
+	blocks map[string]*pollBlock
+	// covered (poll tid, block tid)
+	covered map[string]string
+}
+
+// newCoalescer create poll optimizer
+func newCoalescer(enabled bool, gap int) *coalescer {
+	if gap < 0 {
+		gap = 0
+	}
+	return &coalescer{
+		enabled: enabled,
+		gap:     gap,
+		tids:    make(map[string]string),
+		blocks:  make(map[string]*pollBlock),
+		covered: make(map[string]string),
+	}
+}
+
+// add register the downstream tid of the poll
+func (c *coalescer) add(name, tid string) {
+	c.Lock()
+	c.tids[name] = tid
+	c.Unlock()
+}
+
+// remove unregister the poll
+func (c *coalescer) remove(name string) {
+	c.Lock()
+	delete(c.tids, name)
+	c.Unlock()
+}
+
+// removeAll unregister all polls
+func (c *coalescer) removeAll() {
+	c.Lock()
+	c.tids = make(map[string]string)
+	c.Unlock()
+}
+
+// isCovered check whether the poll is served by a block read
+func (c *coalescer) isCovered(tid string) bool {
+	c.RLock()
+	_, ok := c.covered[tid]
+	c.RUnlock()
+	return ok
+}
+
+// block get the block read by downstream tid
+func (c *coalescer) block(tid string) (*pollBlock, bool) {
+	c.RLock()
+	block, ok := c.blocks[tid]
+	c.RUnlock()
+	return block, ok
+}
+
+// regroup group the polls into block reads and replace the current blocks,
+// 	return the replaced and the new blocks.
+func (c *coalescer) regroup(polls []MbtcpPollStatus, newTid func() string) ([]*pollBlock, []*pollBlock) {
+	var blocks []*pollBlock
+	c.Lock()
+	defer c.Unlock()
+	for _, group := range groupPolls(polls, c.gap) {
+		tid := newTid()
+		first := group[0]
+		block := &pollBlock{
+			req: MbtcpPollStatus{
+				Name:       blockPollPrefix + tid,
+				Interval:   first.Interval,
+				IntervalMs: first.IntervalMs,
+				Cron:       first.Cron,
+				TimeZone:   first.TimeZone,
+			},
+			command: DMbtcpReadReq{
+				Tid:   tid,
+				Cmd:   first.FC,
+				IP:    first.IP,
+				Port:  first.Port,
+				Slave: first.Slave,
+				Addr:  first.Addr,
+				Len:   uint16(blockEnd(group) - int(first.Addr)),
+			},
+		}
+		for _, poll := range group {
+			if pollTid, ok := c.tids[poll.Name]; ok {
+				block.members = append(block.members, coalescedPoll{pollTid, poll.Addr, poll.Len})
+			}
+		}
+		if len(block.members) > 1 {
+			blocks = append(blocks, block)
+		}
+	}
+
+	old := make([]*pollBlock, 0, len(c.blocks))
+	for _, block := range c.blocks {
+		old = append(old, block)
+	}
+	c.blocks = make(map[string]*pollBlock)
+	c.covered = make(map[string]string)
+	for _, block := range blocks {
+		c.blocks[block.command.Tid] = block
+		for _, member := range block.members {
+			c.covered[member.tid] = block.command.Tid
+		}
+	}
+	return old, blocks
+}
+
+// byAddr sort polls by address
+type byAddr []MbtcpPollStatus
+
+func (p byAddr) Len() int      { return len(p) }
+func (p byAddr) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p byAddr) Less(i, j int) bool {
+	if p[i].Addr == p[j].Addr {
+		return p[i].Name < p[j].Name
+	}
+	return p[i].Addr < p[j].Addr
+}
+
+// blockEnd the end address (exclusive) of the polls
+func blockEnd(polls []MbtcpPollStatus) int {
+	var end int
+	for _, poll := range polls {
+		if next := int(poll.Addr) + int(poll.Len); next > end {
+			end = next
+		}
+	}
+	return end
+}
+
+// pollLimit max registers (or bits) of a block read by function code
+func pollLimit(fc int) int {
+	if fc == 1 || fc == 2 { // read bits
+		return maxCoalesceBits
+	}
+	return maxCoalesceRegisters
+}
+
+// pollGroupKey polls of the same device, function code and schedule share the key
+func pollGroupKey(poll MbtcpPollStatus) string {
+	schedule := poll.Cron + "@" + poll.TimeZone
+	if poll.Cron == "" {
+		schedule = strconv.FormatUint(pollMilliseconds(poll.Interval, poll.IntervalMs), 10)
+	}
+	return poll.IP + ":" + poll.Port + ":" + strconv.Itoa(int(poll.Slave)) + ":" + strconv.Itoa(poll.FC) + ":" + schedule
+}
+
+// groupPolls group enabled polls into blocks sorted by address,
+// 	each block within the limit of function code and gap tolerance;
+// 	blocks of a single poll are left alone.
+func groupPolls(polls []MbtcpPollStatus, gap int) [][]MbtcpPollStatus {
+	groups := make(map[string][]MbtcpPollStatus)
+	var keys []string
+	for _, poll := range polls {
+		if !poll.Enabled {
+			continue
+		}
+		key := pollGroupKey(poll)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], poll)
+	}
+	sort.Strings(keys)
+
+	var blocks [][]MbtcpPollStatus
+	for _, key := range keys {
+		group := groups[key]
+		sort.Sort(byAddr(group))
+
+		limit := pollLimit(group[0].FC)
+		start, end := 0, int(group[0].Addr)+int(group[0].Len)
+		for i := 1; i <= len(group); i++ {
+			if i < len(group) {
+				addr, next := int(group[i].Addr), int(group[i].Addr)+int(group[i].Len)
+				if next < end {
+					next = end
+				}
+				if addr <= end+gap && next-int(group[start].Addr) <= limit {
+					end = next
+					continue
+				}
+			}
+			if i-start > 1 {
+				blocks = append(blocks, group[start:i])
+			}
+			if i < len(group) {
+				start, end = i, int(group[i].Addr)+int(group[i].Len)
+			}
+		}
+	}
+	return blocks
+}
+
+// split split the response of block read into responses of member polls
+func (block *pollBlock) split(res DMbtcpRes) []DMbtcpRes {
+	responses := make([]DMbtcpRes, 0, len(block.members))
+	for _, member := range block.members {
+		r := DMbtcpRes{Tid: member.tid, Status: res.Status}
+		if res.Status == "ok" {
+			from := int(member.addr - block.command.Addr)
+			to := from + int(member.len)
+			if to > len(res.Data) {
+				r.Status = ErrInvalidBlockLength.Error()
+			} else {
+				r.Data = res.Data[from:to]
+			}
+		}
+		responses = append(responses, r)
+	}
+	return responses
+}
+
+// coalesce regroup polls into block reads and reschedule the block reads
+func (b *Service) coalesce() {
+	if !b.coalescer.enabled {
+		return
+	}
+	b.coalescer.regrouping.Lock()
+	defer b.coalescer.regrouping.Unlock()
+
+	polls, _ := b.readerMap.GetAll().([]MbtcpPollStatus)
+	old, blocks := b.coalescer.regroup(polls, b.newTid)
+	for _, block := range old {
+		b.scheduler.RemoveWithName(block.req.Name)
+	}
+	for _, block := range blocks {
+		b.schedulePoll(block.req, block.command)
+	}
+	conf.Log.WithField("blocks", len(blocks)).Debug("Coalesce polls")
+}
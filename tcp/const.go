@@ -1,12 +1,18 @@
 package tcp
 
+import "time"
+
 // plugin name
 const (
-	readerPluginName    = "ReaderPlugin"
-	writerPluginName    = "WriterPlugin"
-	schedulerPluginName = "SchedulerPlugin"
-	historyPluginName   = "HistoryPlugin"
-	filterPluginName    = "FilterPlugin"
+	readerPluginName     = "ReaderPlugin"
+	writerPluginName     = "WriterPlugin"
+	schedulerPluginName  = "SchedulerPlugin"
+	historyPluginName    = "HistoryPlugin"
+	filterPluginName     = "FilterPlugin"
+	alarmPluginName      = "AlarmPlugin"
+	tagPluginName        = "TagPlugin"
+	downstreamPluginName = "DownstreamPlugin"
+	transportPluginName  = "TransportPlugin"
 )
 
 // [psmbtcp]
@@ -14,13 +20,67 @@ const (
 	keyTCPDefaultPort          = "psmbtcp.default_port"
 	keyMinConnectionTimout     = "psmbtcp.min_connection_timeout"
 	keyPollInterval            = "psmbtcp.min_poll_interval"
+	keyPollIntervalMs          = "psmbtcp.min_poll_interval_ms"
 	keyMaxWorker               = "psmbtcp.max_worker"
 	keyMaxQueue                = "psmbtcp.max_queue"
+	keyDownstreamDriver        = "psmbtcp.downstream_driver"
+	keyUpstreamTransport       = "psmbtcp.upstream_transport"
+	keyRequestTimeout          = "psmbtcp.request_timeout"
+	keyPollBudget              = "psmbtcp.poll_budget"
+	keyDeviceMaxFailures       = "psmbtcp.device_max_failures"
+	keyDeviceBackoff           = "psmbtcp.device_backoff"
+	keyDeviceMaxBackoff        = "psmbtcp.device_max_backoff"
+	keyDeviceMaxInFlight       = "psmbtcp.device_max_in_flight"
+	keyDeviceMaxRate           = "psmbtcp.device_max_rate"
+	keyDeviceMaxQueue          = "psmbtcp.device_max_queue"
+	keyDeviceInFlight          = "psmbtcp.device_in_flight"
+	keyDeviceRate              = "psmbtcp.device_rate"
+	keyPollCoalesce            = "psmbtcp.poll_coalesce"
+	keyPollCoalesceGap         = "psmbtcp.poll_coalesce_gap"
+	keyProfileDir              = "psmbtcp.profile_dir"
 	defaultTCPDefaultPort      = "502"
 	defaultMinConnectionTimout = 200000
 	defaultPollInterval        = 1
+	defaultPollIntervalMs      = 100
 	defaultMaxWorker           = 6
 	defaultMaxQueue            = 100
+	defaultDownstreamDriver    = "" // empty: modbusd over zmq
+	defaultUpstreamTransport   = "" // empty: zmq pub/sub
+	defaultRequestTimeout      = 5000
+	defaultPollBudget          = 0 // unlimited
+	defaultDeviceMaxFailures   = 3
+	defaultDeviceBackoff       = 1  // seconds
+	defaultDeviceMaxBackoff    = 60 // seconds
+	defaultDeviceMaxInFlight   = 0  // unlimited
+	defaultDeviceMaxRate       = 0  // unlimited
+	defaultDeviceMaxQueue      = 100
+	defaultPollCoalesce        = false
+	defaultPollCoalesceGap     = 0 // adjacent or overlapping polls only
+	defaultProfileDir          = "/etc/psmbtcp/profiles"
+	sweepInterval              = 100 * time.Millisecond
+	zmqTransportName           = "ZMQ"
+)
+
+// scheduler priorities: writes > operator reads > fast polls > slow polls
+const (
+	priorityWrite    = 30
+	priorityRead     = 20
+	priorityFastPoll = 10
+	prioritySlowPoll = 0
+	fastPollInterval = 1000 // polls faster than this interval (in ms) are fast polls
+)
+
+// retry policy limits
+const (
+	maxRetryAttempts = 10               // max attempts of one-off request, including the first one
+	maxRetryBackoff  = 60 * time.Second // max backoff before a retry
+)
+
+// poll coalescing
+const (
+	blockPollPrefix      = "$block:" // scheduler job name prefix of block reads
+	maxCoalesceRegisters = 125       // modbus limit of registers per read
+	maxCoalesceBits      = 2000      // modbus limit of coils or discrete inputs per read
 )
 
 // [zmq]
@@ -34,3 +94,14 @@ const (
 	defaultZmqSubUpstream   = "ipc:///tmp/to.psmb"
 	defaultZmqSubDownstream = "ipc:///tmp/from.modbus"
 )
+
+// [zmq.router]
+const (
+	keyZmqRouterUpstream       = "zmq.router.upstream"
+	keyZmqRouterMaxPending     = "zmq.router.max_pending"
+	keyZmqRouterTimeout        = "zmq.router.timeout"
+	defaultZmqRouterUpstream   = "ipc:///tmp/rpc.psmb" // empty: disable router
+	defaultZmqRouterMaxPending = 1024
+	defaultZmqRouterTimeout    = 60000 // ms
+	routerPollTimeout          = 10 * time.Millisecond
+)
@@ -0,0 +1,94 @@
+package tcp
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	. "github.com/taka-wang/psmb"
+	"github.com/taka-wang/psmb/viper-conf"
+	zmq "github.com/takawang/zmq3"
+)
+
+// deviceMap device health map: (ip:port:slave, device status)
+type deviceMap struct {
+	sync.RWMutex
+	m map[string]MbtcpDeviceStatus
+}
+
+// newDeviceMap create device health map
+func newDeviceMap() *deviceMap {
+	return &deviceMap{m: make(map[string]MbtcpDeviceStatus)}
+}
+
+// deviceKey device key of ip, port and slave id
+func deviceKey(ip, port string, slave uint8) string {
+	return ip + ":" + port + ":" + strconv.Itoa(int(slave))
+}
+
+// update update device health by response status, return the transition event if any
+func (d *deviceMap) update(ip, port string, slave uint8, status string) *MbtcpDeviceEvent {
+	key := deviceKey(ip, port, slave)
+
+	d.Lock()
+	defer d.Unlock()
+	device, ok := d.m[key]
+	if !ok {
+		device = MbtcpDeviceStatus{IP: ip, Port: port, Slave: slave}
+	}
+	next, event := UpdateDeviceHealth(device, status, time.Now().UTC().UnixNano(),
+		deviceMaxFailures, int64(deviceBackoff), int64(deviceMaxBackoff))
+	d.m[key] = next
+	return event
+}
+
+// due check whether polls of the device should be issued now
+func (d *deviceMap) due(ip, port string, slave uint8) bool {
+	d.RLock()
+	device, ok := d.m[deviceKey(ip, port, slave)]
+	d.RUnlock()
+	return !ok || IsDevicePollDue(device, time.Now().UTC().UnixNano())
+}
+
+// all get all device status sorted by key
+func (d *deviceMap) all() []MbtcpDeviceStatus {
+	d.RLock()
+	keys := make([]string, 0, len(d.m))
+	for key := range d.m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	devices := make([]MbtcpDeviceStatus, 0, len(keys))
+	for _, key := range keys {
+		devices = append(devices, d.m[key])
+	}
+	d.RUnlock()
+	return devices
+}
+
+// PollTask poll task for scheduler,
+// 	skip the poll if served by block read, or the device is offline and backing off.
+func (b *Service) PollTask(socket *zmq.Socket, req interface{}) {
+	if command, ok := req.(DMbtcpReadReq); ok {
+		if b.coalescer.isCovered(command.Tid) { // served by block read
+			return
+		}
+		if !b.devices.due(command.IP, command.Port, command.Slave) {
+			conf.Log.WithField("ip", command.IP).Debug("Skip poll of offline device")
+			return
+		}
+	}
+	b.Task(socket, req)
+}
+
+// updateDevice update device health and publish the transition
+func (b *Service) updateDevice(ip, port string, slave uint8, status string) {
+	if event := b.devices.update(ip, port, slave, status); event != nil {
+		conf.Log.WithFields(conf.Fields{
+			"device": deviceKey(ip, port, slave),
+			"state":  event.State,
+		}).Info("Device health transition")
+		b.naiveResponder(CmdMbtcpDeviceStatus, event)
+	}
+}
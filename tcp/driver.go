@@ -0,0 +1,89 @@
+package tcp
+
+import (
+	"sync"
+
+	. "github.com/taka-wang/psmb"
+	"github.com/taka-wang/psmb/viper-conf"
+)
+
+// driverWorkers run the requests of the in-process downstream driver off the scheduler,
+// 	one worker per device, so that a slow device never blocks the scheduler or other devices.
+type driverWorkers struct {
+	sync.RWMutex
+	// driver in-process downstream driver
+	driver IDownstreamDriver
+	// queues request queues by device, empty device for requests not device specific
+	queues map[string]chan interface{}
+	// size capacity of each queue
+	size int
+	// respond feed the downstream response back to the service
+	respond func(msg []string)
+	// closed closed flag
+	closed bool
+}
+
+// newDriverWorkers init driver workers
+func newDriverWorkers(driver IDownstreamDriver, size int, respond func(msg []string)) *driverWorkers {
+	return &driverWorkers{
+		driver:  driver,
+		queues:  make(map[string]chan interface{}),
+		size:    size,
+		respond: respond,
+	}
+}
+
+// do queue the request to the worker of the device, false if the queue is full or closed
+func (d *driverWorkers) do(device string, req interface{}) bool {
+	d.Lock()
+	defer d.Unlock()
+	if d.closed {
+		return false
+	}
+	queue, ok := d.queues[device]
+	if !ok {
+		queue = make(chan interface{}, d.size)
+		d.queues[device] = queue
+		go d.work(queue)
+	}
+	select {
+	case queue <- req:
+		return true
+	default:
+		return false
+	}
+}
+
+// work execute the queued requests of a device in order
+func (d *driverWorkers) work(queue chan interface{}) {
+	for req := range queue {
+		msg, err := d.driver.Do(req)
+		if err != nil {
+			conf.Log.WithError(err).Error("Fail to execute request by downstream driver")
+			continue
+		}
+		conf.Log.WithFields(conf.Fields{
+			"cmd":  msg[0],
+			"resp": msg[1],
+		}).Debug("Recv response from downstream driver")
+		d.RLock()
+		if !d.closed {
+			d.respond(msg)
+		}
+		d.RUnlock()
+	}
+}
+
+// close stop the workers and close the driver
+func (d *driverWorkers) close() {
+	d.Lock()
+	defer d.Unlock()
+	if d.closed {
+		return
+	}
+	d.closed = true
+	for _, queue := range d.queues {
+		close(queue)
+	}
+	d.driver.Close()
+}
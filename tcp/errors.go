@@ -44,12 +44,27 @@ var (
 	// ErrInvalidPollName is the error when the poll name is empty.
 	ErrInvalidPollName = errors.New("Invalid poll name!")
 
+	// ErrInvalidCronExpr is the error when the cron expression of poll is incorrect.
+	ErrInvalidCronExpr = errors.New("Invalid cron expression!")
+
+	// ErrInvalidTimeZone is the error when the time zone of poll is unknown.
+	ErrInvalidTimeZone = errors.New("Invalid time zone!")
+
+	// ErrCronPollInterval is the error when updating the interval of a cron-based poll.
+	ErrCronPollInterval = errors.New("Cron-based poll has no interval!")
+
 	// ErrFiltersNotFound is the error
 	ErrFiltersNotFound = errors.New("Filters not found")
 
 	// ErrFilterNotFound is the error
 	ErrFilterNotFound = errors.New("Filter not found")
 
+	// ErrAlarmsNotFound is the error
+	ErrAlarmsNotFound = errors.New("Alarms not found")
+
+	// ErrInvalidAlarmName is the error when the alarm name or the poll name is empty.
+	ErrInvalidAlarmName = errors.New("Invalid alarm name!")
+
 	// ErrNoLatestData is the error when this latest history is nil
 	ErrNoLatestData = errors.New("No latest history")
 
@@ -58,4 +73,64 @@ var (
 
 	// ErrNoData is the error when the data is nil
 	ErrNoData = errors.New("No data")
+
+	// ErrRequestTimeout is the error when the one-off request is not replied before deadline.
+	ErrRequestTimeout = errors.New("timeout")
+
+	// ErrTooManyRequests is the error when too many router requests are pending.
+	ErrTooManyRequests = errors.New("Too many requests!")
+
+	// ErrSenderConflict is the error when the sender (from) of router request is bound to another identity.
+	ErrSenderConflict = errors.New("Sender is bound to another identity!")
+
+	// ErrDuplicateRequest is the error when the router request of the same sender and tid is pending.
+	ErrDuplicateRequest = errors.New("Duplicate request!")
+
+	// ErrInvalidTagName is the error when the tag name is empty.
+	ErrInvalidTagName = errors.New("Invalid tag name!")
+
+	// ErrTagNotFound is the error when the tag is not in the tag database.
+	ErrTagNotFound = errors.New("Tag not found!")
+
+	// ErrInvalidTagDevice is the error when the device of tag is invalid or not found.
+	ErrInvalidTagDevice = errors.New("Invalid tag device!")
+
+	// ErrInvalidTagAccess is the error when the tag access is neither `r` nor `rw`.
+	ErrInvalidTagAccess = errors.New("Invalid tag access!")
+
+	// ErrTagReadOnly is the error when writing a read-only tag.
+	ErrTagReadOnly = errors.New("Tag is read-only!")
+
+	// ErrTagDeviceInUse is the error when deleting a device with tags.
+	ErrTagDeviceInUse = errors.New("Tag device in use!")
+
+	// ErrInvalidBlockLength is the error when the response of block read is shorter than requested.
+	ErrInvalidBlockLength = errors.New("Invalid block read length!")
+
+	// ErrDriverNotSupport is the error when the downstream driver does not serve the command family.
+	ErrDriverNotSupport = errors.New("Downstream driver not support!")
+
+	// ErrDeviceQueueFull is the error when the request queue of the device is full.
+	ErrDeviceQueueFull = errors.New("Device queue is full!")
+
+	// ErrInvalidWriteType is the error when the value type can not be written, or does not fit the function code.
+	ErrInvalidWriteType = errors.New("Invalid write type!")
+
+	// ErrInvalidWriteValue is the error when the value is out of the range of the value type.
+	ErrInvalidWriteValue = errors.New("Invalid write value!")
+
+	// ErrInvalidProfileName is the error when the profile name is empty or not a plain file name.
+	ErrInvalidProfileName = errors.New("Invalid profile name!")
+
+	// ErrProfileNotFound is the error when the profile file is not found.
+	ErrProfileNotFound = errors.New("Profile not found!")
+
+	// ErrInvalidProfile is the error when the profile file can not be decoded or has no blocks.
+	ErrInvalidProfile = errors.New("Invalid profile!")
+
+	// ErrInvalidProfileDevice is the error when the device (i.e., IP) to instantiate the profile is empty.
+	ErrInvalidProfileDevice = errors.New("Invalid profile device!")
+
+	// ErrProfilePollExists is the error when a poll (or filter) of the profile already exists.
+	ErrProfilePollExists = errors.New("Poll of profile already exists!")
 )
@@ -68,6 +68,74 @@ func createFilterDS(cnf map[string]string) (psmb.IFilterDataStore, error) {
 	return nil, ErrInvalidPluginName
 }
 
+// createAlarmDS real factory method
+func createAlarmDS(cnf map[string]string) (psmb.IAlarmDataStore, error) {
+	ef, _ := createPlugin(cnf, alarmPluginName)
+
+	if ef != nil {
+		if fn, ok := ef.(func(map[string]string) (interface{}, error)); ok {
+			if ds, _ := fn(cnf); ds != nil { // casting
+				return ds.(psmb.IAlarmDataStore), nil
+			}
+		}
+		err := ErrCasting
+		conf.Log.WithError(err).Error("Create alarm data store")
+		return nil, err
+	}
+	return nil, ErrInvalidPluginName
+}
+
+// createDownstreamDriver real factory method
+func createDownstreamDriver(cnf map[string]string) (psmb.IDownstreamDriver, error) {
+	ef, _ := createPlugin(cnf, downstreamPluginName)
+
+	if ef != nil {
+		if fn, ok := ef.(func(map[string]string) (interface{}, error)); ok {
+			if drv, _ := fn(cnf); drv != nil { // casting
+				return drv.(psmb.IDownstreamDriver), nil
+			}
+		}
+		err := ErrCasting
+		conf.Log.WithError(err).Error("Create downstream driver")
+		return nil, err
+	}
+	return nil, ErrInvalidPluginName
+}
+
+// createUpstreamTransport real factory method
+func createUpstreamTransport(cnf map[string]string) (psmb.IUpstreamTransport, error) {
+	ef, _ := createPlugin(cnf, transportPluginName)
+
+	if ef != nil {
+		if fn, ok := ef.(func(map[string]string) (interface{}, error)); ok {
+			if tp, _ := fn(cnf); tp != nil { // casting
+				return tp.(psmb.IUpstreamTransport), nil
+			}
+		}
+		err := ErrCasting
+		conf.Log.WithError(err).Error("Create upstream transport")
+		return nil, err
+	}
+	return nil, ErrInvalidPluginName
+}
+
+// createTagDS real factory method
+func createTagDS(cnf map[string]string) (psmb.ITagDataStore, error) {
+	ef, _ := createPlugin(cnf, tagPluginName)
+
+	if ef != nil {
+		if fn, ok := ef.(func(map[string]string) (interface{}, error)); ok {
+			if ds, _ := fn(cnf); ds != nil { // casting
+				return ds.(psmb.ITagDataStore), nil
+			}
+		}
+		err := ErrCasting
+		conf.Log.WithError(err).Error("Create tag data store")
+		return nil, err
+	}
+	return nil, ErrInvalidPluginName
+}
+
 // createWriterDS real factory method
 func createHistoryDS(cnf map[string]string) (psmb.IHistoryDataStore, error) {
 	ef, _ := createPlugin(cnf, historyPluginName)
@@ -139,6 +207,26 @@ func FilterDataStoreCreator(driver string) (psmb.IFilterDataStore, error) {
 	return createFilterDS(map[string]string{filterPluginName: driver})
 }
 
+// AlarmDataStoreCreator concrete creator to create alarm data store
+func AlarmDataStoreCreator(driver string) (psmb.IAlarmDataStore, error) {
+	return createAlarmDS(map[string]string{alarmPluginName: driver})
+}
+
+// DownstreamDriverCreator concrete creator to create in-process downstream driver
+func DownstreamDriverCreator(driver string) (psmb.IDownstreamDriver, error) {
+	return createDownstreamDriver(map[string]string{downstreamPluginName: driver})
+}
+
+// UpstreamTransportCreator concrete creator to create upstream transport
+func UpstreamTransportCreator(transport string) (psmb.IUpstreamTransport, error) {
+	return createUpstreamTransport(map[string]string{transportPluginName: transport})
+}
+
+// TagDataStoreCreator concrete creator to create tag data store
+func TagDataStoreCreator(driver string) (psmb.ITagDataStore, error) {
+	return createTagDS(map[string]string{tagPluginName: driver})
+}
+
 // SchedulerCreator concrete creator to create scheduler
 func SchedulerCreator(driver string) (cron.Scheduler, error) {
 	return createScheduler(map[string]string{schedulerPluginName: driver})
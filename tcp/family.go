@@ -0,0 +1,59 @@
+package tcp
+
+// Family command family served by the proactive service,
+// 	i.e., mbtcp requests to modbusd over tcp, mbrtu requests to modbusd over rtu.
+// 	The service handles requests and responses in mbtcp form, the family converts them at the edges.
+type Family interface {
+	// Frame downstream frame 1 to modbusd
+	Frame() string
+	// Key get the config key of the family by the psmbtcp or zmq config key
+	Key(key string) string
+	// Command get the mbtcp command of the family command, false if not supported
+	Command(cmd string) (string, bool)
+	// Reply get the family command of the mbtcp command, false if not supported
+	Reply(cmd string) (string, bool)
+	// Request convert the family request of the mbtcp command into mbtcp request json string
+	Request(cmd, req string) (string, error)
+	// Response convert the mbtcp response into family response
+	Response(resp interface{}) interface{}
+	// Downstream convert the mbtcp downstream command into family downstream command
+	Downstream(command interface{}) interface{}
+}
+
+// mbtcpFamily mbtcp command family
+type mbtcpFamily struct{}
+
+// Frame downstream frame 1 to modbusd
+func (mbtcpFamily) Frame() string {
+	return "tcp"
+}
+
+// Key get the config key of the family by the psmbtcp or zmq config key
+func (mbtcpFamily) Key(key string) string {
+	return key
+}
+
+// Command get the mbtcp command of the family command, false if not supported
+func (mbtcpFamily) Command(cmd string) (string, bool) {
+	return cmd, true
+}
+
+// Reply get the family command of the mbtcp command, false if not supported
+func (mbtcpFamily) Reply(cmd string) (string, bool) {
+	return cmd, true
+}
+
+// Request convert the family request of the mbtcp command into mbtcp request json string
+func (mbtcpFamily) Request(cmd, req string) (string, error) {
+	return req, nil
+}
+
+// Response convert the mbtcp response into family response
+func (mbtcpFamily) Response(resp interface{}) interface{} {
+	return resp
+}
+
+// Downstream convert the mbtcp downstream command into family downstream command
+func (mbtcpFamily) Downstream(command interface{}) interface{} {
+	return command
+}
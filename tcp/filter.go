@@ -0,0 +1,47 @@
+package tcp
+
+import (
+	"sync"
+
+	. "github.com/taka-wang/psmb"
+)
+
+// filterStateMap runtime state of stateful filters: (filter name, filter state),
+// 	kept apart from the filter data store, so that polls never write to the store.
+type filterStateMap struct {
+	sync.RWMutex
+	m map[string]FilterState
+}
+
+// newFilterStateMap create filter state map
+func newFilterStateMap() *filterStateMap {
+	return &filterStateMap{m: make(map[string]FilterState)}
+}
+
+// get get the state of the filter, empty state if never evaluated
+func (f *filterStateMap) get(name string) FilterState {
+	f.RLock()
+	defer f.RUnlock()
+	return f.m[name]
+}
+
+// set set the state of the filter
+func (f *filterStateMap) set(name string, state FilterState) {
+	f.Lock()
+	defer f.Unlock()
+	f.m[name] = state
+}
+
+// reset reset the state of the filter, i.e., the filter is added, updated or deleted
+func (f *filterStateMap) reset(name string) {
+	f.Lock()
+	defer f.Unlock()
+	delete(f.m, name)
+}
+
+// resetAll reset the states of all filters
+func (f *filterStateMap) resetAll() {
+	f.Lock()
+	defer f.Unlock()
+	f.m = make(map[string]FilterState)
+}
@@ -0,0 +1,198 @@
+package tcp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+	. "github.com/taka-wang/psmb"
+	"github.com/taka-wang/psmb/viper-conf"
+)
+
+// profileExts supported profile file extensions by lookup order
+var profileExts = []string{".toml", ".json"}
+
+// loadProfile load the profile by name from the profile directory,
+// 	TOML profiles are converted to JSON, so both share the json field names.
+func loadProfile(dir, name string) (MbtcpProfile, error) {
+	var profile MbtcpProfile
+	if name == "" || filepath.Base(name) != name {
+		return profile, ErrInvalidProfileName
+	}
+
+	for _, ext := range profileExts {
+		bytes, err := ioutil.ReadFile(filepath.Join(dir, name+ext))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return profile, err
+		}
+
+		if ext == ".toml" {
+			var tree map[string]interface{}
+			if _, err := toml.Decode(string(bytes), &tree); err != nil {
+				conf.Log.WithError(err).Warn("Fail to decode profile")
+				return profile, ErrInvalidProfile
+			}
+			if bytes, err = json.Marshal(tree); err != nil {
+				return profile, ErrInvalidProfile
+			}
+		}
+		if err := json.Unmarshal(bytes, &profile); err != nil || len(profile.Blocks) == 0 {
+			return profile, ErrInvalidProfile
+		}
+		if profile.Name == "" {
+			profile.Name = name
+		}
+		return profile, nil
+	}
+	return profile, ErrProfileNotFound
+}
+
+// profilePolls instantiate the polls (and filters) of the profile for the device
+func profilePolls(profile MbtcpProfile, req MbtcpProfileReq) ([]MbtcpPollStatus, []MbtcpFilterStatus, error) {
+	var polls []MbtcpPollStatus
+	var filters []MbtcpFilterStatus
+	names := make(map[string]bool)
+
+	for _, block := range profile.Blocks {
+		poll := MbtcpPollStatus{
+			Name:       req.Prefix + block.Name,
+			Interval:   block.Interval,
+			IntervalMs: block.IntervalMs,
+			Cron:       block.Cron,
+			TimeZone:   block.TimeZone,
+			Enabled:    true,
+			FC:         block.FC,
+			IP:         req.IP,
+			Port:       req.Port,
+			Slave:      req.Slave,
+			Addr:       block.Addr,
+			Len:        block.Len,
+			Type:       block.Type,
+			Order:      block.Order,
+			Range:      block.Range,
+			// 64-bit integers in decimal strings
+			Int64String: block.Int64String,
+		}
+
+		// function code checker
+		if poll.FC < 1 || poll.FC > 4 {
+			return nil, nil, ErrInvalidFunctionCode
+		}
+		// protect null or duplicate block name
+		if block.Name == "" || names[poll.Name] {
+			return nil, nil, ErrInvalidPollName
+		}
+		names[poll.Name] = true
+		// check cron expression and time zone
+		if err := checkSchedule(poll); err != nil {
+			return nil, nil, err
+		}
+		// protect null port
+		if poll.Port == "" {
+			poll.Port = defaultMbPort
+		}
+		// length checker
+		if poll.Len < 1 {
+			poll.Len = 1 // set minimal length of read
+		}
+		// check interval value
+		poll.Interval, poll.IntervalMs = pollInterval(poll.Interval, poll.IntervalMs)
+		polls = append(polls, poll)
+
+		if block.Filter != nil {
+			filter := *block.Filter
+			filter.Tid, filter.From, filter.Status = 0, "", ""
+			filter.Name = poll.Name // filter of the poll
+			// swap filter args
+			if len(filter.Arg) > 1 && filter.Arg[0] > filter.Arg[1] {
+				filter.Arg[0], filter.Arg[1] = filter.Arg[1], filter.Arg[0]
+			}
+			filters = append(filters, filter)
+		}
+	}
+	return polls, filters, nil
+}
+
+// applyProfile create all polls and filters of the profile in one transaction,
+// 	roll back the added ones if any of them fails; polls are scheduled only
+// 	after all of them are added.
+func (b *Service) applyProfile(req MbtcpProfileReq) error {
+	if req.IP == "" {
+		return ErrInvalidProfileDevice
+	}
+	profile, err := loadProfile(b.profileDir, req.Profile)
+	if err != nil {
+		return err
+	}
+	polls, filters, err := profilePolls(profile, req)
+	if err != nil {
+		return err
+	}
+
+	// existing polls and filters can not be rolled back, refuse to overwrite them
+	for _, poll := range polls {
+		if _, ok := b.readerMap.GetTaskByName(poll.Name); ok {
+			return ErrProfilePollExists
+		}
+	}
+	for _, filter := range filters {
+		if _, ok := b.filterMap.Get(filter.Name); ok {
+			return ErrProfilePollExists
+		}
+	}
+
+	// rollback remove the added polls and filters
+	rollback := func(pollsAdded, filtersAdded int) {
+		for _, filter := range filters[:filtersAdded] {
+			b.filterMap.Delete(filter.Name)
+		}
+		for _, poll := range polls[:pollsAdded] {
+			b.readerMap.DeleteTaskByName(poll.Name)
+		}
+	}
+
+	// add tasks to read/poll task map
+	tids := make([]string, 0, len(polls))
+	for _, poll := range polls {
+		TidStr := b.newTid() // generate downstream tid
+		if err := b.readerMap.Add(poll.Name, TidStr, CmdMbtcpCreatePoll, poll); err != nil {
+			rollback(len(tids), 0) // maybe out of capacity
+			return err
+		}
+		tids = append(tids, TidStr)
+	}
+
+	// add filters to filter map
+	for idx, filter := range filters {
+		if err := b.filterMap.Add(filter.Name, filter); err != nil {
+			rollback(len(polls), idx)
+			return err
+		}
+		b.filterStates.reset(filter.Name)
+	}
+
+	// add commands to scheduler as regular requests
+	for idx, poll := range polls {
+		command := DMbtcpReadReq{
+			Tid:   tids[idx],
+			Cmd:   poll.FC,
+			IP:    poll.IP,
+			Port:  poll.Port,
+			Slave: poll.Slave,
+			Addr:  poll.Addr,
+			Len:   poll.Len,
+		}
+		b.schedulePoll(poll, command)
+		b.coalescer.add(poll.Name, tids[idx])
+	}
+	// regroup block reads
+	b.coalesce()
+
+	conf.Log.WithField("profile", profile.Name).WithField("polls", len(polls)).Debug("Apply profile")
+	return nil
+}
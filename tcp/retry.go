@@ -0,0 +1,114 @@
+package tcp
+
+import (
+	"sync"
+	"time"
+
+	. "github.com/taka-wang/psmb"
+)
+
+// retryTask pending one-off request with retry policy
+type retryTask struct {
+	// policy retry policy
+	policy RetryPolicy
+	// attempts the number of issued attempts
+	attempts int
+	// command downstream command to re-issue
+	command interface{}
+}
+
+// retryMap retry task map: (downstream tid, retryTask)
+type retryMap struct {
+	sync.Mutex
+	m map[string]*retryTask
+}
+
+// newRetryMap create retry task map
+func newRetryMap() *retryMap {
+	return &retryMap{m: make(map[string]*retryTask)}
+}
+
+// add track the downstream command if the retry policy allows more than one attempt,
+// 	the attempts are clamped to maxRetryAttempts.
+func (r *retryMap) add(tid string, policy *RetryPolicy, command interface{}) {
+	if policy == nil || policy.MaxAttempts <= 1 {
+		return
+	}
+	task := &retryTask{policy: *policy, attempts: 1, command: command}
+	if task.policy.MaxAttempts > maxRetryAttempts {
+		task.policy.MaxAttempts = maxRetryAttempts
+	}
+	r.Lock()
+	r.m[tid] = task
+	r.Unlock()
+}
+
+// next get the command and backoff to re-issue, the attempt is counted by issue;
+// 	false if not tracked, not retryable or out of attempts.
+func (r *retryMap) next(tid, status string) (interface{}, time.Duration, bool) {
+	if status == "ok" {
+		return nil, 0, false
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	t, ok := r.m[tid]
+	if !ok || t.attempts >= t.policy.MaxAttempts || !t.retryable(status) {
+		return nil, 0, false
+	}
+	return t.command, retryBackoff(t.policy.Backoff, t.attempts), true
+}
+
+// issue count the re-issued attempt, false if not tracked
+func (r *retryMap) issue(tid string) bool {
+	r.Lock()
+	defer r.Unlock()
+	t, ok := r.m[tid]
+	if ok {
+		t.attempts++
+	}
+	return ok
+}
+
+// retryBackoff backoff in ms doubled per issued attempt, clamped to maxRetryBackoff
+func retryBackoff(backoffMs int64, attempts int) time.Duration {
+	if backoffMs <= 0 {
+		return 0
+	}
+	if backoffMs > int64(maxRetryBackoff/time.Millisecond) {
+		return maxRetryBackoff
+	}
+	backoff := time.Duration(backoffMs) * time.Millisecond
+	for ; attempts > 1 && backoff < maxRetryBackoff; attempts-- {
+		backoff <<= 1
+	}
+	if backoff > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+	return backoff
+}
+
+// done stop tracking and return the number of attempts, zero if not tracked
+func (r *retryMap) done(tid string) int {
+	r.Lock()
+	defer r.Unlock()
+	t, ok := r.m[tid]
+	if !ok {
+		return 0
+	}
+	delete(r.m, tid)
+	return t.attempts
+}
+
+// retryable check whether the status is retryable
+func (t *retryTask) retryable(status string) bool {
+	if len(t.policy.Statuses) == 0 {
+		return true
+	}
+	for _, s := range t.policy.Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package tcp
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/taka-wang/psmb"
+	"github.com/takawang/sugar"
+)
+
+func TestRetry(t *testing.T) {
+	s := sugar.New(t)
+
+	s.Assert("Backoff should be doubled per attempt and clamped", func(logf sugar.Log) bool {
+		cases := []struct {
+			backoffMs int64
+			attempts  int
+			desire    time.Duration
+		}{
+			{100, 1, 100 * time.Millisecond},
+			{100, 3, 400 * time.Millisecond},
+			{100, 100, maxRetryBackoff},
+			{1 << 62, 1, maxRetryBackoff},
+			{1 << 40, 64, maxRetryBackoff},
+			{0, 5, 0},
+			{-1, 5, 0},
+		}
+		for _, c := range cases {
+			if got := retryBackoff(c.backoffMs, c.attempts); got != c.desire {
+				logf("backoff:%d, attempts:%d, desire:%v, got:%v", c.backoffMs, c.attempts, c.desire, got)
+				return false
+			}
+		}
+		return true
+	})
+
+	s.Assert("Attempts should be clamped", func(logf sugar.Log) bool {
+		r := newRetryMap()
+		r.add("1", &RetryPolicy{MaxAttempts: 1 << 30, Backoff: 1 << 40}, "cmd")
+		count := 1
+		for {
+			_, backoff, ok := r.next("1", "busy")
+			if !ok || !r.issue("1") {
+				break
+			}
+			if backoff <= 0 || backoff > maxRetryBackoff {
+				logf("backoff:%v", backoff)
+				return false
+			}
+			count++
+		}
+		logf("attempts:%d", count)
+		return count == maxRetryAttempts && r.done("1") == maxRetryAttempts
+	})
+}
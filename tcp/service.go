@@ -6,8 +6,8 @@ package tcp
 
 import (
 	"encoding/json"
-	"reflect"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	. "github.com/taka-wang/psmb"
@@ -25,10 +25,22 @@ var (
 	minConnTimeout int64
 	// minPollInterval minimal modbus tcp poll interval
 	minPollInterval uint64
+	// minPollIntervalMs minimal modbus tcp poll interval in ms
+	minPollIntervalMs uint64
 	// maxQueueSize the size of job queue
 	maxQueueSize int
 	// max_workers the number of workers to start
 	maxWorkers int
+	// requestTimeout default one-off request timeout in ms
+	requestTimeout int64
+	// pollBudget max polls sent at each scheduler pass
+	pollBudget int
+	// deviceMaxFailures the number of consecutive failures to take a device offline
+	deviceMaxFailures int
+	// deviceBackoff initial poll backoff of offline devices
+	deviceBackoff time.Duration
+	// deviceMaxBackoff maximal poll backoff of offline devices
+	deviceMaxBackoff time.Duration
 )
 
 func setDefaults() {
@@ -36,13 +48,30 @@ func setDefaults() {
 	conf.SetDefault(keyTCPDefaultPort, defaultTCPDefaultPort)
 	conf.SetDefault(keyMinConnectionTimout, defaultMinConnectionTimout)
 	conf.SetDefault(keyPollInterval, defaultPollInterval)
+	conf.SetDefault(keyPollIntervalMs, defaultPollIntervalMs)
 	conf.SetDefault(keyMaxWorker, defaultMaxWorker)
 	conf.SetDefault(keyMaxQueue, defaultMaxQueue)
+	conf.SetDefault(keyDownstreamDriver, defaultDownstreamDriver)
+	conf.SetDefault(keyUpstreamTransport, defaultUpstreamTransport)
+	conf.SetDefault(keyRequestTimeout, defaultRequestTimeout)
+	conf.SetDefault(keyPollBudget, defaultPollBudget)
+	conf.SetDefault(keyDeviceMaxFailures, defaultDeviceMaxFailures)
+	conf.SetDefault(keyDeviceBackoff, defaultDeviceBackoff)
+	conf.SetDefault(keyDeviceMaxBackoff, defaultDeviceMaxBackoff)
+	conf.SetDefault(keyDeviceMaxInFlight, defaultDeviceMaxInFlight)
+	conf.SetDefault(keyDeviceMaxRate, defaultDeviceMaxRate)
+	conf.SetDefault(keyDeviceMaxQueue, defaultDeviceMaxQueue)
+	conf.SetDefault(keyPollCoalesce, defaultPollCoalesce)
+	conf.SetDefault(keyPollCoalesceGap, defaultPollCoalesceGap)
+	conf.SetDefault(keyProfileDir, defaultProfileDir)
 	// set default zmq values
 	conf.SetDefault(keyZmqPubUpstream, defaultZmqPubUpstream)
 	conf.SetDefault(keyZmqPubDownstream, defaultZmqPubDownstream)
 	conf.SetDefault(keyZmqSubUpstream, defaultZmqSubUpstream)
 	conf.SetDefault(keyZmqSubDownstream, defaultZmqSubDownstream)
+	conf.SetDefault(keyZmqRouterUpstream, defaultZmqRouterUpstream)
+	conf.SetDefault(keyZmqRouterMaxPending, defaultZmqRouterMaxPending)
+	conf.SetDefault(keyZmqRouterTimeout, defaultZmqRouterTimeout)
 }
 
 func init() {
@@ -51,8 +80,14 @@ func init() {
 	defaultMbPort = conf.GetString(keyTCPDefaultPort)
 	minConnTimeout = conf.GetInt64(keyMinConnectionTimout)
 	minPollInterval = uint64(conf.GetInt(keyPollInterval))
+	minPollIntervalMs = uint64(conf.GetInt(keyPollIntervalMs))
 	maxWorkers = conf.GetInt(keyMaxWorker)
 	maxQueueSize = conf.GetInt(keyMaxQueue)
+	requestTimeout = conf.GetInt64(keyRequestTimeout)
+	pollBudget = conf.GetInt(keyPollBudget)
+	deviceMaxFailures = conf.GetInt(keyDeviceMaxFailures)
+	deviceBackoff = time.Duration(conf.GetInt64(keyDeviceBackoff)) * time.Second
+	deviceMaxBackoff = time.Duration(conf.GetInt64(keyDeviceMaxBackoff)) * time.Second
 }
 
 const (
@@ -84,14 +119,16 @@ type (
 
 	// zSockets zmq sockets
 	zSockets struct {
-		// upstream subscriber from services
-		upstream *zmq.Socket
-		// downstream subscriber from modbusd
+		// downstream socket from/to modbusd
 		downstream *zmq.Socket
 	}
 
 	// Service modbusd tcp proactive service type
 	Service struct {
+		// tid last downstream transaction id; keep first for 64-bit alignment
+		tid int64
+		// family command family served
+		family Family
 		// readerMap read/poll task map
 		readerMap IReaderTaskDataStore
 		// writerMap write task map
@@ -100,8 +137,18 @@ type (
 		historyMap IHistoryDataStore
 		// filterMap filter map
 		filterMap IFilterDataStore
+		// filterStates runtime state of stateful filters
+		filterStates *filterStateMap
+		// alarmMap alarm map
+		alarmMap IAlarmDataStore
+		// tagMap tag database
+		tagMap ITagDataStore
 		// scheduler cron scheduler
 		scheduler cron.Scheduler
+		// drivers workers of the in-process downstream driver, nil if using modbusd
+		drivers *driverWorkers
+		// upstream transport to services
+		upstream IUpstreamTransport
 		// sub ZMQ subscriber endpoints
 		sub zSockets
 		// pub ZMQ publisher endpoints
@@ -112,18 +159,51 @@ type (
 		enable bool
 		// jobChan job channel
 		jobChan chan job
+		// retries one-off requests with retry policy
+		retries *retryMap
+		// devices device health map
+		devices *deviceMap
+		// throttle per device request queues
+		throttle *throttle
+		// coalescer poll optimizer
+		coalescer *coalescer
+		// profileDir directory of device profiles
+		profileDir string
 	}
 )
 
+// Options optional plugins of the proactive service,
+// 	commands of the plugin are not supported if its name is empty.
+type Options struct {
+	// Alarm alarm data store
+	Alarm string
+	// Tag tag data store
+	Tag string
+}
+
 // NewService modbus tcp proactive serivce constructor
-func NewService(reader, writer, history, filter, sch string) (IProactiveService, error) {
+func NewService(reader, writer, history, filter, sch string, opts ...Options) (IProactiveService, error) {
+	return NewFamilyService(mbtcpFamily{}, reader, writer, history, filter, sch, opts...)
+}
+
+// NewFamilyService proactive serivce constructor of the command family
+func NewFamilyService(family Family, reader, writer, history, filter, sch string, opts ...Options) (IProactiveService, error) {
 	var readerPlugin IReaderTaskDataStore
 	var writerPlugin IWriterTaskDataStore
 	var historyPlugin IHistoryDataStore
 	var filterPlugin IFilterDataStore
+	var alarmPlugin IAlarmDataStore
+	var tagPlugin ITagDataStore
 	var schedulerPlugin cron.Scheduler
+	var driverPlugin IDownstreamDriver
+	var transportPlugin IUpstreamTransport
+	var opt Options
 	var err error
 
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
 	// factory methods
 	if readerPlugin, err = ReaderDataStoreCreator(reader); err != nil { // reader factory
 		conf.Log.WithError(err).Fatal("Fail to create reader data store")
@@ -145,48 +225,86 @@ func NewService(reader, writer, history, filter, sch string) (IProactiveService,
 		return nil, err
 	}
 
+	if opt.Alarm != "" {
+		if alarmPlugin, err = AlarmDataStoreCreator(opt.Alarm); err != nil { // alarm factory
+			conf.Log.WithError(err).Fatal("Fail to create alarm data store")
+			return nil, err
+		}
+	}
+
+	if opt.Tag != "" {
+		if tagPlugin, err = TagDataStoreCreator(opt.Tag); err != nil { // tag factory
+			conf.Log.WithError(err).Fatal("Fail to create tag data store")
+			return nil, err
+		}
+	}
+
 	if schedulerPlugin, err = SchedulerCreator(sch); err != nil { // scheduler factory
 		conf.Log.WithError(err).Fatal("Fail to create scheduler")
 		return nil, err
 	}
+	schedulerPlugin.Budget(pollBudget)
 
-	pubUpstream, err := zmq.NewSocket(zmq.PUB)
-	if err != nil {
-		conf.Log.WithError(err).Fatal("Fail to create upstream publisher")
+	if drv := conf.GetString(family.Key(keyDownstreamDriver)); drv != "" {
+		if driverPlugin, err = DownstreamDriverCreator(drv); err != nil { // downstream driver factory
+			conf.Log.WithError(err).Fatal("Fail to create downstream driver")
+			return nil, err
+		}
+		if driverPlugin.Frame() != family.Frame() { // e.g., mbrtu requests to a tcp only driver
+			conf.Log.WithFields(conf.Fields{
+				"driver": drv,
+				"frame":  family.Frame(),
+			}).Error(ErrDriverNotSupport.Error())
+			return nil, ErrDriverNotSupport
+		}
+	}
+
+	if transportPlugin, err = newUpstreamTransport(conf.GetString(family.Key(keyUpstreamTransport)), family.Key); err != nil { // upstream transport factory
+		conf.Log.WithError(err).Fatal("Fail to create upstream transport")
 		return nil, err
 	}
+
 	pubDownstream, err := zmq.NewSocket(zmq.PUB)
 	if err != nil {
 		conf.Log.WithError(err).Fatal("Fail to create downstream publisher")
 		return nil, err
 	}
-	subUpstream, err := zmq.NewSocket(zmq.SUB)
-	if err != nil {
-		conf.Log.WithError(err).Fatal("Fail to create upstream subscriber")
-		return nil, err
-	}
 	subDownstream, err := zmq.NewSocket(zmq.SUB)
 	if err != nil {
 		conf.Log.WithError(err).Fatal("Fail to create downstream subscriber")
 		return nil, err
 	}
 
-	return &Service{
-		enable:     true,
-		readerMap:  readerPlugin,
-		writerMap:  writerPlugin,
-		historyMap: historyPlugin,
-		filterMap:  filterPlugin,
-		scheduler:  schedulerPlugin,
+	b := &Service{
+		tid:          time.Now().UTC().UnixNano(), // avoid collision after restart
+		family:       family,
+		enable:       true,
+		readerMap:    readerPlugin,
+		writerMap:    writerPlugin,
+		historyMap:   historyPlugin,
+		filterMap:    filterPlugin,
+		filterStates: newFilterStateMap(),
+		alarmMap:     alarmPlugin,
+		tagMap:       tagPlugin,
+		scheduler:    schedulerPlugin,
+		upstream:     transportPlugin,
+		retries:      newRetryMap(),
+		devices:      newDeviceMap(),
+		coalescer:    newCoalescer(conf.GetBool(family.Key(keyPollCoalesce)), conf.GetInt(family.Key(keyPollCoalesceGap))),
+		profileDir:   conf.GetString(family.Key(keyProfileDir)),
 		pub: zSockets{
-			upstream:   pubUpstream,
 			downstream: pubDownstream,
 		},
 		sub: zSockets{
-			upstream:   subUpstream,
 			downstream: subDownstream,
 		},
-	}, nil
+	}
+	defaults := deviceLimit{conf.GetInt(family.Key(keyDeviceMaxInFlight)), conf.GetInt(family.Key(keyDeviceMaxRate))}
+	b.throttle = newThrottle(defaults, deviceLimits(family.Key), conf.GetInt(family.Key(keyDeviceMaxQueue)), b.resend)
+	if driverPlugin != nil {
+		b.drivers = newDriverWorkers(driverPlugin, maxQueueSize, func(msg []string) { b.dispatch(Downstream, msg) })
+	}
+	return b, nil
 }
 
 // marshal helper function to marshal structure
@@ -200,6 +318,8 @@ func marshal(r interface{}) (string, error) {
 
 // addToHistory helper function to add data to history map
 func (b *Service) addToHistory(name string, data interface{}) bool {
+	// check alarms regardless of filter
+	b.checkAlarms(name, data)
 	// apply filter before logging
 	retBool := b.applyFilter(name, data)
 	if err := b.historyMap.Add(name, data); err != nil {
@@ -230,159 +350,126 @@ func (b *Service) applyFilter(name string, data interface{}) bool {
 	}
 	filter := f.(MbtcpFilterStatus) // casting
 
-	if len(filter.Arg) == 0 {
-		conf.Log.WithError(ErrInvalidArgs).Debug("Apply filter")
-		return true // no args
-	}
-
 	var latestStr string // latest history marshalled string
-	var err error
-	if filter.Type == Change {
-		if latestStr, err = b.historyMap.GetLatest(name); err != nil {
-			conf.Log.WithError(ErrNoLatestData).Debug("Apply filter")
-			return true // no latest
+	var latestTs int64   // timestamp of latest history
+	if IsHistoryFilter(filter.Type) {
+		var err error
+		if latestStr, latestTs, err = b.historyMap.GetLatestWithTimestamp(name); err != nil {
+			conf.Log.WithError(ErrNoLatestData).Debug("Apply filter") // no latest, let the filter decide
 		}
 	}
 
-	// reflect data interface type
-	rVals := reflect.ValueOf(data)
-	switch rVals.Kind() {
-	case reflect.Array:
-		if rVals.Len() == 0 {
-			conf.Log.WithError(ErrNoData).Debug("Apply filter")
-			return true // no data to filter
-		}
-		var val float32 // first element container in data interface
-		switch rVals.Index(0).Kind() {
-		case reflect.Uint16, reflect.Uint32: //uint16, uint32:
-			val = float32(rVals.Index(0).Uint())
-		default: // reflect.Float32
-			val = float32(rVals.Index(0).Float())
-		}
+	state := b.filterStates.get(name)
+	ret, err := ApplyFilter(filter, state, data, latestStr, latestTs, time.Now().UTC().UnixNano())
+	if err != nil {
+		conf.Log.WithFields(conf.Fields{
+			"err":  err,
+			"name": name,
+		}).Debug("Apply filter")
+	}
 
-		switch filter.Type {
-		case GreaterEqual: // val >= desired value
-			if val >= filter.Arg[0] {
-				return true
-			}
-			return false
-		case Greater: // val > desired value
-			if val > filter.Arg[0] {
-				return true
-			}
-			return false
-		case Equal: // val == desired value
-			if val == filter.Arg[0] {
-				return true
-			}
-			return false
-		case Less: //  val < desired value
-			if val < filter.Arg[0] {
-				return true
-			}
-			return false
-		case LessEqual: // val <= desired value
-			if val <= filter.Arg[0] {
-				return true
-			}
-			return false
-		case InsideRange: // desired 1 < val < desired 2; desired values are sorted.
-			if len(filter.Arg) < 2 {
-				conf.Log.WithError(ErrInvalidArgs).Debug("Apply filter")
-				return true
-			}
-			if val > filter.Arg[0] && val < filter.Arg[1] {
-				return true
-			}
-			return false
-		case InsideIncRange: // desired 1 <= val <= desired 2; desired values are sorted.
-			if len(filter.Arg) < 2 {
-				conf.Log.WithError(ErrInvalidArgs).Debug("Apply filter")
-				return true
-			}
-			if val >= filter.Arg[0] && val <= filter.Arg[1] {
-				return true
-			}
-			return false
-		case OutsideRange: // val < desired 1 || val > desired 2; desired values are sorted.
-			if len(filter.Arg) < 2 {
-				conf.Log.WithError(ErrInvalidArgs).Debug("Apply filter")
-				return true
-			}
-			if val < filter.Arg[0] || val > filter.Arg[1] {
-				return true
-			}
-			return false
-		case OutsideIncRange:
-			if len(filter.Arg) < 2 { // val <= desired 1 || val >= desired 2; desired values are sorted.
-				conf.Log.WithError(ErrInvalidArgs).Debug("Apply filter")
-				return true
-			}
-			if val <= filter.Arg[0] || val >= filter.Arg[1] {
-				return true
-			}
-			return false
-		default: // change; compare with the latest history
-			// unmarshal latest data
-			var float32ArrData []float32
-			if err := json.Unmarshal([]byte(latestStr), &float32ArrData); err != nil {
-				conf.Log.WithError(ErrUnmarshal).Debug("Apply filter")
-				return true // fail to unmarshal latest
-			}
+	// update state for deadband, hysteresis and stale filters
+	if IsStatefulFilter(filter.Type) {
+		b.filterStates.set(name, UpdateFilterState(filter, state, data, latestStr, ret))
+	}
+	return ret
+}
 
-			if len(float32ArrData) == 0 {
-				conf.Log.WithError(ErrNoLatestData).Debug("Apply filter")
-				return true // empty history
-			}
-			if val == float32ArrData[0] { // compare
-				return true
-			}
-			return false
+// checkAlarms evaluate alarms bound to the poll and publish transitions.
+func (b *Service) checkAlarms(name string, data interface{}) {
+	if b.alarmMap == nil {
+		return // alarm not supported
+	}
+	alarms, ok := b.alarmMap.GetByPoll(name).([]MbtcpAlarmStatus)
+	if !ok {
+		return // no alarm
+	}
+
+	ts := time.Now().UTC().UnixNano()
+	for _, alarm := range alarms {
+		if !alarm.Enabled {
+			continue
 		}
-	case reflect.String:
-		/* we do not intend to support filter on hex string
-		str := rVals.String()
-		if str == "" {
-			return true // no data to filter
+		next, event, err := EvaluateAlarm(alarm, data, ts)
+		if err != nil {
+			conf.Log.WithFields(conf.Fields{
+				"err":  err,
+				"name": alarm.Name,
+			}).Debug("Evaluate alarm")
+			continue
+		}
+		if err := b.alarmMap.Add(next.Name, next); err != nil {
+			conf.Log.WithError(err).Warn("Fail to update alarm state")
+		}
+		if event != nil {
+			b.naiveResponder(CmdMbtcpAlarm, event)
 		}
-		*/
-		return true
-	default: // should not reach here
-		return true
 	}
 }
 
-// Task task for scheduler
+// Task task for scheduler,
+// 	queue the request of rate limited devices, send the requests ready to downstream.
 func (b *Service) Task(socket *zmq.Socket, req interface{}) {
+	ready, ok := b.throttle.enqueue(socket, req, b.queuePriority(req))
+	if !ok {
+		b.drop(req)
+	}
+	for _, command := range ready {
+		b.send(command.socket, command.req)
+	}
+}
+
+// drop reply the one-off request dropped by the full device queue and evict it;
+// 	dropped polls are left to the next tick.
+func (b *Service) drop(req interface{}) {
+	TidStr, _, _ := commandDevice(req)
+	if task, ok := b.writerMap.Get(TidStr); ok {
+		b.writerMap.Delete(TidStr)
+		b.naiveResponder(task.Cmd, MbtcpSimpleRes{Tid: task.Tid, Status: ErrDeviceQueueFull.Error(), Attempts: b.retries.done(TidStr)})
+		return
+	}
+	if t, ok := b.readerMap.GetTaskByID(TidStr); ok {
+		task := t.(ReaderTask)
+		if readReq, ok := task.Req.(MbtcpReadReq); ok { // one-off requests only
+			b.readerMap.DeleteTaskByID(TidStr)
+			b.naiveResponder(task.Cmd, MbtcpSimpleRes{Tid: readReq.Tid, Status: ErrDeviceQueueFull.Error(), Attempts: b.retries.done(TidStr)})
+		}
+	}
+}
+
+// send execute the request by the in-process downstream driver asynchronously if set, otherwise send to modbusd.
+func (b *Service) send(socket *zmq.Socket, req interface{}) {
+	TidStr, device, _ := commandDevice(req)
+	if b.drivers != nil {
+		if !b.drivers.do(device, b.family.Downstream(req)) {
+			conf.Log.WithField("device", device).Warn("Driver queue full, drop request")
+			go b.releaseTid(TidStr) // may resend by the scheduler, never under its lock
+			b.drop(req)
+		}
+		return
+	}
+
+	req = b.family.Downstream(req)
 	str, err := marshal(req)
 	if err != nil {
 		conf.Log.WithError(err).Error("Task")
 		return
 	}
 	conf.Log.WithField("msg", str).Debug("Send request")
-	socket.Send("tcp", zmq.SNDMORE) // frame 1
-	socket.Send(str, 0)             // convert to string; frame 2
+	socket.Send(b.family.Frame(), zmq.SNDMORE) // frame 1
+	socket.Send(str, 0)                        // convert to string; frame 2
 }
 
 func (b *Service) startZMQ() {
 	conf.Log.Debug("Start ZMQ")
 
-	// publishers
-	if err := b.pub.upstream.Bind(conf.GetString(keyZmqPubUpstream)); err != nil {
-		conf.Log.WithError(err).Fatal("Fail to bind upstream publisher")
-	}
-	if err := b.pub.downstream.Connect(conf.GetString(keyZmqPubDownstream)); err != nil {
+	// publisher
+	if err := b.pub.downstream.Connect(conf.GetString(b.family.Key(keyZmqPubDownstream))); err != nil {
 		conf.Log.WithError(err).Fatal("Fail to connect to downstream publisher")
 	}
 
-	// subscribers
-	if err := b.sub.upstream.Bind(conf.GetString(keyZmqSubUpstream)); err != nil {
-		conf.Log.WithError(err).Fatal("Fail to bind upstream subscriber")
-	}
-	if err := b.sub.upstream.SetSubscribe(""); err != nil {
-		conf.Log.WithError(err).Fatal("Fail to set upstream subscriber's filter")
-	}
-	if err := b.sub.downstream.Connect(conf.GetString(keyZmqSubDownstream)); err != nil {
+	// subscriber
+	if err := b.sub.downstream.Connect(conf.GetString(b.family.Key(keyZmqSubDownstream))); err != nil {
 		conf.Log.WithError(err).Fatal("Fail to connect to downstream subscriber")
 	}
 	if err := b.sub.downstream.SetSubscribe(""); err != nil {
@@ -391,42 +478,229 @@ func (b *Service) startZMQ() {
 
 	// poller
 	b.poller = zmq.NewPoller() // new poller
-	b.poller.Add(b.sub.upstream, zmq.POLLIN)
 	b.poller.Add(b.sub.downstream, zmq.POLLIN)
 }
 
 func (b *Service) stopZMQ() {
 	conf.Log.Debug("Stop ZMQ")
 
-	// publishers
-	if err := b.pub.upstream.Unbind(conf.GetString(keyZmqPubUpstream)); err != nil {
-		conf.Log.WithError(err).Debug("Fail to unbind upstream publisher")
-	}
-	if err := b.pub.downstream.Disconnect(conf.GetString(keyZmqPubDownstream)); err != nil {
+	// publisher
+	if err := b.pub.downstream.Disconnect(conf.GetString(b.family.Key(keyZmqPubDownstream))); err != nil {
 		conf.Log.WithError(err).Debug("Fail to disconnect from downstream publisher")
 	}
 
-	// subscribers
-	if err := b.sub.upstream.Unbind(conf.GetString(keyZmqSubUpstream)); err != nil {
-		conf.Log.WithError(err).Debug("Fail to unbind upstream subscriber")
-	}
-	if err := b.sub.downstream.Disconnect(conf.GetString(keyZmqSubDownstream)); err != nil {
+	// subscriber
+	if err := b.sub.downstream.Disconnect(conf.GetString(b.family.Key(keyZmqSubDownstream))); err != nil {
 		conf.Log.WithError(err).Debug("Fail to disconnect from downstream subscriber")
 	}
 }
 
-// naiveResponder naive responder to send message back to upstream.
+// naiveResponder naive responder to send message back to upstream,
+// 	in the command and response of the family.
 func (b *Service) naiveResponder(cmd string, resp interface{}) error {
-	respStr, err := marshal(resp)
+	cmd, ok := b.family.Reply(cmd)
+	if !ok {
+		return nil // not supported by the family
+	}
+	respStr, err := marshal(b.family.Response(resp))
 	if err != nil {
 		conf.Log.WithError(err).Error("Fail to marshal for naive responder!")
 		return err
 	}
 
 	conf.Log.WithField("msg", respStr).Debug("Send response")
-	b.pub.upstream.Send(cmd, zmq.SNDMORE) // task command
-	b.pub.upstream.Send(respStr, 0)       // convert to string; frame 2
-	return nil
+	return b.upstream.Send([]string{cmd, respStr})
+}
+
+// parseWriteData unmarshal data field of write request by function code,
+// 	bits in uint16 (array) and registers in dec|hex string or typed numbers.
+func parseWriteData(fc int, hex bool, valueType RegValueType, order Endian, data json.RawMessage) (interface{}, error) {
+	var uint16Data uint16
+	var stringData string
+	var uint16ArrData []uint16
+
+	switch MbCmdType(strconv.Itoa(fc)) {
+	case fc5: // write single bit; uint16
+		if err := json.Unmarshal(data, &uint16Data); err != nil {
+			return nil, ErrUnmarshal
+		}
+		return uint16Data, nil // unmarshal to uint16
+	case fc15: // write multiple bits; []uint16
+		if err := json.Unmarshal(data, &uint16ArrData); err != nil {
+			return nil, ErrUnmarshal
+		}
+		return uint16ArrData, nil // unmarshal to uint16 array
+	case fc6, fc16: // write single or multiple registers in dec|hex string or typed numbers
+		err := json.Unmarshal(data, &stringData)
+		if err == nil {
+			// check dec or hex
+			if hex {
+				uint16ArrData, err = HexStringToRegisters(stringData)
+			} else {
+				uint16ArrData, err = DecimalStringToRegisters(stringData)
+			}
+		} else {
+			uint16ArrData, err = typedRegisters(valueType, order, data)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if MbCmdType(strconv.Itoa(fc)) == fc6 {
+			if len(uint16ArrData) != 1 {
+				return nil, ErrInvalidWriteType // more than one register
+			}
+			return uint16ArrData[0], nil // retrieve only one register
+		}
+		return uint16ArrData, nil
+	default:
+		return nil, ErrInvalidFunctionCode
+	}
+}
+
+// typedRegisters encode JSON number (or number array) into registers by value type and byte order,
+// 	uint16 registers if the value type is not set; numbers are parsed from the JSON text,
+// 	so that 64-bit integers keep full precision.
+func typedRegisters(valueType RegValueType, order Endian, data json.RawMessage) ([]uint16, error) {
+	var numbers []json.Number
+	if err := json.Unmarshal(data, &numbers); err != nil {
+		var number json.Number
+		if err := json.Unmarshal(data, &number); err != nil {
+			return nil, ErrUnmarshal
+		}
+		numbers = []json.Number{number}
+	}
+
+	switch valueType {
+	case 0, RegisterArray, UInt16:
+		arr := make([]uint16, len(numbers))
+		for idx, v := range numbers {
+			i, err := strconv.ParseUint(v.String(), 10, 16)
+			if err != nil {
+				return nil, ErrInvalidWriteValue
+			}
+			arr[idx] = uint16(i)
+		}
+		return UInt16sToRegisters(arr, order)
+	case Int16:
+		arr := make([]int16, len(numbers))
+		for idx, v := range numbers {
+			i, err := strconv.ParseInt(v.String(), 10, 16)
+			if err != nil {
+				return nil, ErrInvalidWriteValue
+			}
+			arr[idx] = int16(i)
+		}
+		return Int16sToRegisters(arr, order)
+	case UInt32:
+		arr := make([]uint32, len(numbers))
+		for idx, v := range numbers {
+			i, err := strconv.ParseUint(v.String(), 10, 32)
+			if err != nil {
+				return nil, ErrInvalidWriteValue
+			}
+			arr[idx] = uint32(i)
+		}
+		return UInt32sToRegisters(arr, order)
+	case Int32:
+		arr := make([]int32, len(numbers))
+		for idx, v := range numbers {
+			i, err := strconv.ParseInt(v.String(), 10, 32)
+			if err != nil {
+				return nil, ErrInvalidWriteValue
+			}
+			arr[idx] = int32(i)
+		}
+		return Int32sToRegisters(arr, order)
+	case Float32:
+		arr := make([]float32, len(numbers))
+		for idx, v := range numbers {
+			f, err := strconv.ParseFloat(v.String(), 32)
+			if err != nil {
+				return nil, ErrInvalidWriteValue
+			}
+			arr[idx] = float32(f)
+		}
+		return Float32sToRegisters(arr, order)
+	case UInt64:
+		arr := make([]uint64, len(numbers))
+		for idx, v := range numbers {
+			i, err := strconv.ParseUint(v.String(), 10, 64)
+			if err != nil {
+				return nil, ErrInvalidWriteValue
+			}
+			arr[idx] = i
+		}
+		return UInt64sToRegisters(arr, order)
+	case Int64:
+		arr := make([]int64, len(numbers))
+		for idx, v := range numbers {
+			i, err := strconv.ParseInt(v.String(), 10, 64)
+			if err != nil {
+				return nil, ErrInvalidWriteValue
+			}
+			arr[idx] = i
+		}
+		return Int64sToRegisters(arr, order)
+	case Float64:
+		arr := make([]float64, len(numbers))
+		for idx, v := range numbers {
+			f, err := strconv.ParseFloat(v.String(), 64)
+			if err != nil {
+				return nil, ErrInvalidWriteValue
+			}
+			arr[idx] = f
+		}
+		return Float64sToRegisters(arr, order)
+	default: // hex string and scale
+		return nil, ErrInvalidWriteType
+	}
+}
+
+// bytesTo64s converts byte array to 64-bit array by value type
+func bytesTo64s(bytes []byte, valueType RegValueType, order Endian) (interface{}, error) {
+	switch valueType {
+	case Int64:
+		return BytesToInt64s(bytes, order)
+	case UInt64:
+		return BytesToUInt64s(bytes, order)
+	default: // Float64
+		return BytesToFloat64s(bytes, order)
+	}
+}
+
+// int64Strings converts 64-bit integer array to decimal string array, others are left alone
+func int64Strings(data interface{}) interface{} {
+	switch arr := data.(type) {
+	case []int64:
+		return Int64sToStrings(arr)
+	case []uint64:
+		return UInt64sToStrings(arr)
+	default:
+		return data
+	}
+}
+
+// requestTid get the tid of the raw request, zero if none
+func requestTid(req string) int64 {
+	var r struct {
+		Tid int64 `json:"tid"`
+	}
+	json.Unmarshal([]byte(req), &r)
+	return r.Tid
+}
+
+// supported check whether the optional plugin serving the command is set
+func (b *Service) supported(cmd string) bool {
+	switch cmd {
+	case CmdMbtcpCreateAlarm, CmdMbtcpDeleteAlarm, CmdMbtcpAckAlarm, CmdMbtcpShelveAlarm, CmdMbtcpGetAlarms:
+		return b.alarmMap != nil
+	case CmdMbtcpCreateTagDevice, CmdMbtcpDeleteTagDevice, CmdMbtcpGetTagDevices,
+		CmdMbtcpCreateTag, CmdMbtcpDeleteTag, CmdMbtcpGetTags,
+		CmdMbtcpReadTag, CmdMbtcpWriteTag, CmdMbtcpSubscribeTag, CmdMbtcpUnsubscribeTag:
+		return b.tagMap != nil
+	default:
+		return true
+	}
 }
 
 // ParseRequest parse requests from services,
@@ -434,6 +708,23 @@ func (b *Service) naiveResponder(cmd string, resp interface{}) error {
 func (b *Service) ParseRequest(msg []string) (interface{}, error) {
 	//conf.Log.WithField("msg", msg[0]).Debug("Parse request from upstream services")
 
+	// convert family request to mbtcp request
+	cmd, ok := b.family.Command(msg[0])
+	if !ok || !b.supported(cmd) {
+		return nil, ErrRequestNotSupport
+	}
+	body, err := b.family.Request(cmd, msg[1])
+	if err != nil {
+		var req struct {
+			Tid int64 `json:"tid"`
+		}
+		if json.Unmarshal([]byte(msg[1]), &req) != nil {
+			return nil, err
+		}
+		return req.Tid, err
+	}
+	msg = []string{cmd, body}
+
 	switch msg[0] {
 	case CmdMbtcpGetTimeout, CmdMbtcpSetTimeout:
 		var req MbtcpTimeoutReq
@@ -449,61 +740,16 @@ func (b *Service) ParseRequest(msg []string) (interface{}, error) {
 			return nil, ErrUnmarshal
 		}
 
-		var uint16Data uint16
-		var stringData string
-		var uint16ArrData []uint16
-
 		// unmarshal remaining data field
-		switch MbCmdType(strconv.Itoa(req.FC)) {
-		case fc5: // write single bit; uint16
-			if err := json.Unmarshal(data, &uint16Data); err != nil {
-				return req.Tid, ErrUnmarshal
-			}
-			req.Data = uint16Data // unmarshal to uint16
-			return req, nil
-		case fc15: // write multiple bits; []uint16
-			if err := json.Unmarshal(data, &uint16ArrData); err != nil {
-				return req.Tid, ErrUnmarshal
-			}
-			req.Data = uint16ArrData // unmarshal to uint16 array
-			return req, nil
-		case fc6: // write single register in dec|hex
-			err := json.Unmarshal(data, &stringData)
-			if err != nil {
-				return req.Tid, ErrUnmarshal
-			}
-
-			// check dec or hex
-			if req.Hex {
-				uint16ArrData, err = HexStringToRegisters(stringData)
-			} else {
-				uint16ArrData, err = DecimalStringToRegisters(stringData)
-			}
-			if err != nil {
-				return req.Tid, err
-			}
-			req.Data = uint16ArrData[0] // retrieve only one register
-			return req, nil
-		case fc16: // write multiple register in dec/hex
-			err := json.Unmarshal(data, &stringData)
-			if err != nil {
-				return req.Tid, ErrUnmarshal
-			}
-
-			// check dec or hex
-			if req.Hex {
-				uint16ArrData, err = HexStringToRegisters(stringData)
-			} else {
-				uint16ArrData, err = DecimalStringToRegisters(stringData)
-			}
-			if err != nil {
-				return req.Tid, err
-			}
-			req.Data = uint16ArrData
-			return req, nil
-		default: // should not reach here
-			return nil, ErrInvalidFunctionCode
+		value, err := parseWriteData(req.FC, req.Hex, req.Type, req.Order, data)
+		if err == ErrInvalidFunctionCode { // should not reach here
+			return nil, err
+		}
+		if err != nil {
+			return req.Tid, err
 		}
+		req.Data = value
+		return req, nil
 	case CmdMbtcpOnceRead:
 		var req MbtcpReadReq
 		if err := json.Unmarshal([]byte(msg[1]), &req); err != nil {
@@ -549,6 +795,63 @@ func (b *Service) ParseRequest(msg []string) (interface{}, error) {
 			return nil, ErrUnmarshal
 		}
 		return req, nil
+	case CmdMbtcpCreateAlarm:
+		var req MbtcpAlarmStatus
+		if err := json.Unmarshal([]byte(msg[1]), &req); err != nil {
+			return nil, ErrUnmarshal
+		}
+		return req, nil
+	case CmdMbtcpDeleteAlarm, CmdMbtcpAckAlarm, CmdMbtcpShelveAlarm, CmdMbtcpGetAlarms:
+		var req MbtcpAlarmOpReq
+		if err := json.Unmarshal([]byte(msg[1]), &req); err != nil {
+			return nil, ErrUnmarshal
+		}
+		return req, nil
+	case CmdMbtcpGetDevices:
+		var req MbtcpDevicesReq
+		if err := json.Unmarshal([]byte(msg[1]), &req); err != nil {
+			return nil, ErrUnmarshal
+		}
+		return req, nil
+	case CmdMbtcpGetQueues:
+		var req MbtcpQueuesReq
+		if err := json.Unmarshal([]byte(msg[1]), &req); err != nil {
+			return nil, ErrUnmarshal
+		}
+		return req, nil
+	case CmdMbtcpApplyProfile:
+		var req MbtcpProfileReq
+		if err := json.Unmarshal([]byte(msg[1]), &req); err != nil {
+			return nil, ErrUnmarshal
+		}
+		return req, nil
+	case CmdMbtcpCreateTagDevice:
+		var req MbtcpTagDevice
+		if err := json.Unmarshal([]byte(msg[1]), &req); err != nil {
+			return nil, ErrUnmarshal
+		}
+		return req, nil
+	case CmdMbtcpCreateTag:
+		var req MbtcpTag
+		if err := json.Unmarshal([]byte(msg[1]), &req); err != nil {
+			return nil, ErrUnmarshal
+		}
+		return req, nil
+	case CmdMbtcpWriteTag:
+		// unmarshal data field after the tag is resolved
+		var data json.RawMessage // raw []byte
+		req := MbtcpTagOpReq{Data: &data}
+		if err := json.Unmarshal([]byte(msg[1]), &req); err != nil {
+			return nil, ErrUnmarshal
+		}
+		return req, nil
+	case CmdMbtcpDeleteTagDevice, CmdMbtcpGetTagDevices, CmdMbtcpDeleteTag, CmdMbtcpGetTags,
+		CmdMbtcpReadTag, CmdMbtcpSubscribeTag, CmdMbtcpUnsubscribeTag:
+		var req MbtcpTagOpReq
+		if err := json.Unmarshal([]byte(msg[1]), &req); err != nil {
+			return nil, ErrUnmarshal
+		}
+		return req, nil
 	default: // should not reach here!!
 		return nil, ErrRequestNotSupport
 	}
@@ -558,24 +861,25 @@ func (b *Service) ParseRequest(msg []string) (interface{}, error) {
 // 	do error checking
 func (b *Service) HandleRequest(cmd string, r interface{}) error {
 	//conf.Log.WithField("msg", cmd).Debug("Handle request from upstream services")
+	cmd, _ = b.family.Command(cmd)
 
 	switch cmd {
 	case CmdMbtcpGetTimeout:
 		req := r.(MbtcpTimeoutReq)
-		TidStr := strconv.FormatInt(req.Tid, 10)        // convert tid to string
+		TidStr := b.newTid()                            // generate downstream tid
 		cmdInt, _ := strconv.Atoi(string(getMbTimeout)) // convert to modbusd command
 		command := DMbtcpTimeout{
 			Tid: TidStr,
 			Cmd: cmdInt,
 		}
 		// add request to write task map
-		b.writerMap.Add(TidStr, cmd)
+		b.writerMap.Add(TidStr, WriterTask{Cmd: cmd, Tid: req.Tid, From: req.From, Deadline: deadline(0)})
 		// add command to scheduler as emergency request
-		b.scheduler.Emergency().Do(b.Task, b.pub.downstream, command)
+		b.scheduler.Emergency().Priority(priorityWrite).Do(b.Task, b.pub.downstream, command)
 		return nil
 	case CmdMbtcpSetTimeout:
 		req := r.(MbtcpTimeoutReq)
-		TidStr := strconv.FormatInt(req.Tid, 10) // convert tid to string
+		TidStr := b.newTid() // generate downstream tid
 		cmdInt, _ := strconv.Atoi(string(setMbTimeout))
 		command := DMbtcpTimeout{
 			Tid: TidStr,
@@ -589,13 +893,24 @@ func (b *Service) HandleRequest(cmd string, r interface{}) error {
 			command.Timeout = req.Data
 		}
 		// add request to write task map
-		b.writerMap.Add(TidStr, cmd)
+		b.writerMap.Add(TidStr, WriterTask{Cmd: cmd, Tid: req.Tid, From: req.From, Deadline: deadline(0)})
 		// add command to scheduler as emergency request
-		b.scheduler.Emergency().Do(b.Task, b.pub.downstream, command)
+		b.scheduler.Emergency().Priority(priorityWrite).Do(b.Task, b.pub.downstream, command)
 		return nil
+	case CmdMbtcpWriteTag: // translate into one-off write request
+		req := r.(MbtcpTagOpReq)
+		writeReq, err := b.tagWriteRequest(req)
+		if err != nil {
+			conf.Log.WithError(err).Warn(CmdMbtcpWriteTag)
+			// send error back
+			resp := MbtcpSimpleRes{Tid: req.Tid, Status: err.Error()}
+			return b.naiveResponder(cmd, resp)
+		}
+		r = writeReq
+		fallthrough
 	case CmdMbtcpOnceWrite:
 		req := r.(MbtcpWriteReq)
-		TidStr := strconv.FormatInt(req.Tid, 10) // convert tid to string
+		TidStr := b.newTid() // generate downstream tid
 
 		// protect null port
 		if req.Port == "" {
@@ -625,13 +940,25 @@ func (b *Service) HandleRequest(cmd string, r interface{}) error {
 			Data:  req.Data,
 		}
 		// add request to write task map
-		b.writerMap.Add(TidStr, cmd)
+		b.writerMap.Add(TidStr, WriterTask{Cmd: cmd, Tid: req.Tid, From: req.From, Deadline: deadline(req.TimeoutMs)})
+		b.retries.add(TidStr, req.Retry, command)
 		// add command to scheduler as emergency request
-		b.scheduler.Emergency().Do(b.Task, b.pub.downstream, command)
+		b.scheduler.Emergency().Priority(priorityWrite).Do(b.Task, b.pub.downstream, command)
 		return nil
+	case CmdMbtcpReadTag: // translate into one-off read request
+		req := r.(MbtcpTagOpReq)
+		readReq, err := b.tagReadRequest(req)
+		if err != nil {
+			conf.Log.WithError(err).Warn(CmdMbtcpReadTag)
+			// send error back
+			resp := MbtcpSimpleRes{Tid: req.Tid, Status: err.Error()}
+			return b.naiveResponder(cmd, resp)
+		}
+		r = readReq
+		fallthrough
 	case CmdMbtcpOnceRead:
 		req := r.(MbtcpReadReq)
-		TidStr := strconv.FormatInt(req.Tid, 10) // convert tid to string
+		TidStr := b.newTid() // generate downstream tid
 
 		// function code checker
 		if req.FC < 1 || req.FC > 4 {
@@ -670,12 +997,25 @@ func (b *Service) HandleRequest(cmd string, r interface{}) error {
 			resp := MbtcpSimpleRes{Tid: req.Tid, Status: err.Error()}
 			return b.naiveResponder(cmd, resp)
 		}
+		b.readerMap.UpdateDeadlineByID(TidStr, deadline(req.TimeoutMs))
+		b.retries.add(TidStr, req.Retry, command)
 		// add command to scheduler as emergency request
-		b.scheduler.Emergency().Do(b.Task, b.pub.downstream, command)
+		b.scheduler.Emergency().Priority(priorityRead).Do(b.Task, b.pub.downstream, command)
 		return nil
+	case CmdMbtcpSubscribeTag: // translate into poll request named by the tag
+		req := r.(MbtcpTagOpReq)
+		pollReq, err := b.tagPollRequest(req)
+		if err != nil {
+			conf.Log.WithError(err).Warn(CmdMbtcpSubscribeTag)
+			// send error back
+			resp := MbtcpSimpleRes{Tid: req.Tid, Status: err.Error()}
+			return b.naiveResponder(cmd, resp)
+		}
+		r = pollReq
+		fallthrough
 	case CmdMbtcpCreatePoll:
 		req := r.(MbtcpPollStatus)
-		TidStr := strconv.FormatInt(req.Tid, 10) // convert tid to string
+		TidStr := b.newTid() // generate downstream tid
 
 		// function code checker
 		if req.FC < 1 || req.FC > 4 {
@@ -695,6 +1035,14 @@ func (b *Service) HandleRequest(cmd string, r interface{}) error {
 			return b.naiveResponder(cmd, resp)
 		}
 
+		// check cron expression and time zone
+		if err := checkSchedule(req); err != nil {
+			conf.Log.WithError(err).Warn(CmdMbtcpCreatePoll)
+			// send back
+			resp := MbtcpSimpleRes{Tid: req.Tid, Status: err.Error()}
+			return b.naiveResponder(cmd, resp)
+		}
+
 		// protect null port
 		if req.Port == "" {
 			req.Port = defaultMbPort
@@ -706,9 +1054,7 @@ func (b *Service) HandleRequest(cmd string, r interface{}) error {
 		}
 
 		// check interval value
-		if req.Interval < minPollInterval {
-			req.Interval = minPollInterval
-		}
+		req.Interval, req.IntervalMs = pollInterval(req.Interval, req.IntervalMs)
 
 		command := DMbtcpReadReq{
 			Tid:   TidStr,
@@ -729,11 +1075,14 @@ func (b *Service) HandleRequest(cmd string, r interface{}) error {
 		}
 
 		// add command to scheduler as regular request
-		b.scheduler.EveryWithName(req.Interval, req.Name).Seconds().Do(b.Task, b.pub.downstream, command)
+		b.schedulePoll(req, command)
 
 		if !req.Enabled { // if not enabled, pause the task
 			b.scheduler.PauseWithName(req.Name)
 		}
+		// regroup block reads
+		b.coalescer.add(req.Name, TidStr)
+		b.coalesce()
 		// send back
 		resp := MbtcpSimpleRes{Tid: req.Tid, Status: "ok"}
 		return b.naiveResponder(cmd, resp)
@@ -741,24 +1090,43 @@ func (b *Service) HandleRequest(cmd string, r interface{}) error {
 		req := r.(MbtcpPollOpReq)
 		status := "ok"
 
-		// check interval value
-		if req.Interval < minPollInterval {
-			req.Interval = minPollInterval
+		// check cron-based poll
+		if t, ok := b.readerMap.GetTaskByName(req.Name); ok {
+			if request, ok := t.(ReaderTask).Req.(MbtcpPollStatus); ok && request.Cron != "" {
+				err := ErrCronPollInterval
+				conf.Log.WithError(err).Warn(CmdMbtcpUpdatePoll)
+				// send error back
+				resp := MbtcpSimpleRes{Tid: req.Tid, Status: err.Error()}
+				return b.naiveResponder(cmd, resp)
+			}
 		}
 
-		// update task interval
-		if ok := b.scheduler.UpdateIntervalWithName(req.Name, req.Interval); !ok {
+		// check interval value
+		req.Interval, req.IntervalMs = pollInterval(req.Interval, req.IntervalMs)
+
+		// update task interval and priority
+		interval := pollMilliseconds(req.Interval, req.IntervalMs)
+		if ok := b.scheduler.UpdateIntervalWithName(req.Name, interval); !ok {
 			err := ErrInvalidPollName // not in scheduler
 			conf.Log.WithError(err).Warn(CmdMbtcpUpdatePoll)
 			status = err.Error() // set error status
+		} else {
+			b.scheduler.UpdatePriorityWithName(req.Name, pollPriority(interval))
 		}
 
 		// update read/poll task map
 		if status == "ok" {
-			if err := b.readerMap.UpdateIntervalByName(req.Name, req.Interval); err != nil {
+			var err error
+			if req.IntervalMs > 0 {
+				err = b.readerMap.UpdateIntervalMsByName(req.Name, req.IntervalMs)
+			} else {
+				err = b.readerMap.UpdateIntervalByName(req.Name, req.Interval)
+			}
+			if err != nil {
 				conf.Log.WithError(err).Warn(CmdMbtcpUpdatePoll)
 				status = err.Error() // set error status
 			}
+			b.coalesce() // regroup block reads
 		}
 		// send back
 		resp := MbtcpSimpleRes{Tid: req.Tid, Status: status}
@@ -778,22 +1146,29 @@ func (b *Service) HandleRequest(cmd string, r interface{}) error {
 		// send back
 		request := task.Req.(MbtcpPollStatus)
 		resp := MbtcpPollStatus{
-			Tid:      req.Tid,
-			Name:     req.Name,
-			Interval: request.Interval,
-			Enabled:  request.Enabled,
-			FC:       request.FC,
-			IP:       request.IP,
-			Port:     request.Port,
-			Slave:    request.Slave,
-			Addr:     request.Addr,
-			Len:      request.Len,
-			Type:     request.Type,
-			Order:    request.Order,
-			Range:    request.Range,
-			Status:   "ok",
+			Tid:        req.Tid,
+			Name:       req.Name,
+			Interval:   request.Interval,
+			IntervalMs: request.IntervalMs,
+			Cron:       request.Cron,
+			TimeZone:   request.TimeZone,
+			Enabled:    request.Enabled,
+			FC:         request.FC,
+			IP:         request.IP,
+			Port:       request.Port,
+			Slave:      request.Slave,
+			Addr:       request.Addr,
+			Len:        request.Len,
+			Type:       request.Type,
+			Order:      request.Order,
+			Range:      request.Range,
+			Status:     "ok",
 		}
 		return b.naiveResponder(cmd, resp)
+	case CmdMbtcpUnsubscribeTag: // translate into poll request named by the tag
+		req := r.(MbtcpTagOpReq)
+		r = MbtcpPollOpReq{Tid: req.Tid, From: req.From, Name: req.Name}
+		fallthrough
 	case CmdMbtcpDeletePoll:
 		req := r.(MbtcpPollOpReq)
 		status := "ok"
@@ -805,6 +1180,9 @@ func (b *Service) HandleRequest(cmd string, r interface{}) error {
 		}
 		// remove task from read/poll map
 		b.readerMap.DeleteTaskByName(req.Name)
+		// regroup block reads
+		b.coalescer.remove(req.Name)
+		b.coalesce()
 		// send back
 		resp := MbtcpSimpleRes{Tid: req.Tid, Status: status}
 		return b.naiveResponder(cmd, resp)
@@ -830,6 +1208,7 @@ func (b *Service) HandleRequest(cmd string, r interface{}) error {
 				conf.Log.WithError(err).Warn(CmdMbtcpTogglePoll)
 				status = err.Error() // set error status
 			}
+			b.coalesce() // regroup block reads
 		}
 		// send back
 		resp := MbtcpSimpleRes{Tid: req.Tid, Status: status}
@@ -849,6 +1228,8 @@ func (b *Service) HandleRequest(cmd string, r interface{}) error {
 		req := r.(MbtcpPollOpReq)
 		b.scheduler.Clear()     // remove all tasks from scheduler
 		b.readerMap.DeleteAll() // remove all tasks from read/poll task map
+		b.coalescer.removeAll() // remove all block reads
+		b.coalesce()
 		// send back
 		resp := MbtcpSimpleRes{Tid: req.Tid, Status: "ok"}
 		return b.naiveResponder(cmd, resp)
@@ -862,6 +1243,7 @@ func (b *Service) HandleRequest(cmd string, r interface{}) error {
 		}
 		// update read/poll task map
 		b.readerMap.UpdateAllToggles(req.Enabled)
+		b.coalesce() // regroup block reads
 		// send back
 		resp := MbtcpSimpleRes{Tid: req.Tid, Status: "ok"}
 		return b.naiveResponder(cmd, resp)
@@ -881,6 +1263,12 @@ func (b *Service) HandleRequest(cmd string, r interface{}) error {
 				continue // bypass
 			}
 
+			// check cron expression and time zone
+			if err := checkSchedule(req); err != nil {
+				conf.Log.WithError(err).Warn(CmdMbtcpImportPolls)
+				continue // bypass
+			}
+
 			// protect null port
 			if req.Port == "" {
 				req.Port = defaultMbPort
@@ -892,11 +1280,9 @@ func (b *Service) HandleRequest(cmd string, r interface{}) error {
 			}
 
 			// check interval value
-			if req.Interval < minPollInterval {
-				req.Interval = minPollInterval
-			}
+			req.Interval, req.IntervalMs = pollInterval(req.Interval, req.IntervalMs)
 
-			TidStr := strconv.FormatInt(req.Tid, 10) // convert tid to string
+			TidStr := b.newTid() // generate downstream tid
 			command := DMbtcpReadReq{
 				Tid:   TidStr,
 				Cmd:   req.FC,
@@ -911,17 +1297,20 @@ func (b *Service) HandleRequest(cmd string, r interface{}) error {
 			if err := b.readerMap.Add(req.Name, TidStr, cmd, req); err != nil {
 				// maybe out of capacity
 				conf.Log.WithError(err).Warn(CmdMbtcpImportPolls)
+				b.coalesce() // regroup imported polls
 				// send error back
 				resp := MbtcpSimpleRes{Tid: request.Tid, Status: err.Error()}
 				return b.naiveResponder(cmd, resp)
 			}
 
-			b.scheduler.EveryWithName(req.Interval, req.Name).Seconds().Do(b.Task, b.pub.downstream, command) // add command to scheduler as regular request
+			b.schedulePoll(req, command) // add command to scheduler as regular request
 
 			if !req.Enabled { // if not enabled, pause the task
 				b.scheduler.PauseWithName(req.Name)
 			}
+			b.coalescer.add(req.Name, TidStr)
 		}
+		b.coalesce() // regroup block reads
 		// send back
 		resp := MbtcpSimpleRes{Tid: request.Tid, Status: "ok"}
 		return b.naiveResponder(cmd, resp)
@@ -950,12 +1339,12 @@ func (b *Service) HandleRequest(cmd string, r interface{}) error {
 				req.Arg[1] = req.Arg[0]
 				req.Arg[0] = tmp
 			}
-
 			// add or update to filter map
 			if err := b.filterMap.Add(req.Name, req); err != nil {
 				conf.Log.WithError(err).Error(CmdMbtcpCreateFilter)
 				status = err.Error() // set error status
 			}
+			b.filterStates.reset(req.Name) // evaluate from scratch
 		}
 
 		// send back
@@ -994,6 +1383,8 @@ func (b *Service) HandleRequest(cmd string, r interface{}) error {
 			Enabled: request.Enabled,
 			Type:    request.Type,
 			Arg:     request.Arg,
+			Mode:    request.Mode,
+			Index:   request.Index,
 			Status:  status, // "ok"
 		}
 		return b.naiveResponder(cmd, resp)
@@ -1006,6 +1397,7 @@ func (b *Service) HandleRequest(cmd string, r interface{}) error {
 			status = err.Error() // set error status
 		} else {
 			b.filterMap.Delete(req.Name)
+			b.filterStates.reset(req.Name)
 		}
 		// send back
 		resp := MbtcpSimpleRes{Tid: req.Tid, Status: status}
@@ -1042,9 +1434,185 @@ func (b *Service) HandleRequest(cmd string, r interface{}) error {
 		conf.Log.WithError(err).Error(CmdMbtcpGetFilters)
 		resp := MbtcpSimpleRes{Tid: req.Tid, Status: err.Error()}
 		return b.naiveResponder(cmd, resp)
+	case CmdMbtcpCreateAlarm:
+		req := r.(MbtcpAlarmStatus)
+		status := "ok"
+		if req.Name == "" || req.Poll == "" {
+			err := ErrInvalidAlarmName
+			conf.Log.WithError(err).Warn(CmdMbtcpCreateAlarm)
+			status = err.Error() // set error status
+		} else if req.HighHigh == nil && req.High == nil && req.Low == nil && req.LowLow == nil {
+			err := ErrAlarmNoLimits
+			conf.Log.WithError(err).Warn(CmdMbtcpCreateAlarm)
+			status = err.Error() // set error status
+		} else {
+			// reset alarm state
+			req.Level, req.Active, req.Acked = Normal, false, false
+			req.ShelvedUntil, req.Value, req.TimeStamp = 0, 0, 0
+
+			// add or update to alarm map
+			if err := b.alarmMap.Add(req.Name, req); err != nil {
+				conf.Log.WithError(err).Error(CmdMbtcpCreateAlarm)
+				status = err.Error() // set error status
+			}
+		}
+		// send back
+		resp := MbtcpSimpleRes{Tid: req.Tid, Status: status}
+		return b.naiveResponder(cmd, resp)
+	case CmdMbtcpDeleteAlarm:
+		req := r.(MbtcpAlarmOpReq)
+		status := "ok"
+		if req.Name == "" {
+			err := ErrInvalidAlarmName
+			conf.Log.WithError(err).Warn(CmdMbtcpDeleteAlarm)
+			status = err.Error() // set error status
+		} else {
+			b.alarmMap.Delete(req.Name)
+		}
+		// send back
+		resp := MbtcpSimpleRes{Tid: req.Tid, Status: status}
+		return b.naiveResponder(cmd, resp)
+	case CmdMbtcpAckAlarm, CmdMbtcpShelveAlarm:
+		req := r.(MbtcpAlarmOpReq)
+		status := "ok"
+		if a, ok := b.alarmMap.Get(req.Name); !ok {
+			err := ErrInvalidAlarmName
+			conf.Log.WithError(err).Warn(cmd)
+			status = err.Error() // set error status
+		} else {
+			alarm := a.(MbtcpAlarmStatus) // type casting
+			ts := time.Now().UTC().UnixNano()
+			var event *MbtcpAlarmEvent
+			var err error
+			if cmd == CmdMbtcpAckAlarm {
+				alarm, event, err = AckAlarm(alarm, ts)
+			} else if req.Duration > 0 {
+				alarm.ShelvedUntil = ts + int64(req.Duration)*int64(time.Second)
+			} else {
+				alarm.ShelvedUntil = 0 // unshelve
+			}
+
+			if err != nil {
+				conf.Log.WithError(err).Warn(cmd)
+				status = err.Error() // set error status
+			} else if err := b.alarmMap.Add(alarm.Name, alarm); err != nil {
+				conf.Log.WithError(err).Error(cmd)
+				status = err.Error() // set error status
+			} else if event != nil {
+				b.naiveResponder(CmdMbtcpAlarm, event)
+			}
+		}
+		// send back
+		resp := MbtcpSimpleRes{Tid: req.Tid, Status: status}
+		return b.naiveResponder(cmd, resp)
+	case CmdMbtcpGetAlarms:
+		req := r.(MbtcpAlarmOpReq)
+		if reqs, ok := b.alarmMap.GetAll().([]MbtcpAlarmStatus); ok {
+			resp := MbtcpAlarmsStatus{
+				Tid:    req.Tid,
+				Status: "ok",
+				Alarms: reqs,
+			}
+			// send back
+			return b.naiveResponder(cmd, resp)
+		}
+		// send error back
+		err := ErrAlarmsNotFound
+		conf.Log.WithError(err).Error(CmdMbtcpGetAlarms)
+		resp := MbtcpSimpleRes{Tid: req.Tid, Status: err.Error()}
+		return b.naiveResponder(cmd, resp)
+	case CmdMbtcpGetDevices:
+		req := r.(MbtcpDevicesReq)
+		resp := MbtcpDevicesStatus{
+			Tid:     req.Tid,
+			Status:  "ok",
+			Devices: b.devices.all(),
+		}
+		// send back
+		return b.naiveResponder(cmd, resp)
+	case CmdMbtcpGetQueues:
+		req := r.(MbtcpQueuesReq)
+		resp := MbtcpQueuesStatus{
+			Tid:    req.Tid,
+			Status: "ok",
+			Queues: b.throttle.status(),
+		}
+		// send back
+		return b.naiveResponder(cmd, resp)
+	case CmdMbtcpApplyProfile:
+		req := r.(MbtcpProfileReq)
+		status := "ok"
+		if err := b.applyProfile(req); err != nil {
+			conf.Log.WithError(err).Warn(CmdMbtcpApplyProfile)
+			status = err.Error() // set error status
+		}
+		// send back
+		resp := MbtcpSimpleRes{Tid: req.Tid, Status: status}
+		return b.naiveResponder(cmd, resp)
+	case CmdMbtcpCreateTagDevice:
+		req := r.(MbtcpTagDevice)
+		status := "ok"
+		device, err := checkTagDevice(req)
+		if err == nil {
+			device.Tid, device.From = 0, ""
+			err = b.tagMap.AddDevice(device.Name, device) // add or update
+		}
+		if err != nil {
+			conf.Log.WithError(err).Warn(CmdMbtcpCreateTagDevice)
+			status = err.Error() // set error status
+		}
+		// send back
+		resp := MbtcpSimpleRes{Tid: req.Tid, Status: status}
+		return b.naiveResponder(cmd, resp)
+	case CmdMbtcpDeleteTagDevice:
+		req := r.(MbtcpTagOpReq)
+		status := "ok"
+		if b.tagInUse(req.Name) {
+			err := ErrTagDeviceInUse
+			conf.Log.WithError(err).Warn(CmdMbtcpDeleteTagDevice)
+			status = err.Error() // set error status
+		} else {
+			b.tagMap.DeleteDevice(req.Name)
+		}
+		// send back
+		resp := MbtcpSimpleRes{Tid: req.Tid, Status: status}
+		return b.naiveResponder(cmd, resp)
+	case CmdMbtcpGetTagDevices:
+		req := r.(MbtcpTagOpReq)
+		devices, _ := b.tagMap.GetDevices().([]MbtcpTagDevice)
+		// send back
+		resp := MbtcpTagsStatus{Tid: req.Tid, Status: "ok", Devices: devices}
+		return b.naiveResponder(cmd, resp)
+	case CmdMbtcpCreateTag:
+		req := r.(MbtcpTag)
+		status := "ok"
+		tag, err := b.checkTag(req)
+		if err == nil {
+			err = b.tagMap.Add(tag.Name, tag) // add or update
+		}
+		if err != nil {
+			conf.Log.WithError(err).Warn(CmdMbtcpCreateTag)
+			status = err.Error() // set error status
+		}
+		// send back
+		resp := MbtcpSimpleRes{Tid: req.Tid, Status: status}
+		return b.naiveResponder(cmd, resp)
+	case CmdMbtcpDeleteTag:
+		req := r.(MbtcpTagOpReq)
+		b.tagMap.Delete(req.Name)
+		// send back
+		resp := MbtcpSimpleRes{Tid: req.Tid, Status: "ok"}
+		return b.naiveResponder(cmd, resp)
+	case CmdMbtcpGetTags:
+		req := r.(MbtcpTagOpReq)
+		tags, _ := b.tagMap.GetAll().([]MbtcpTag)
+		// send back
+		resp := MbtcpTagsStatus{Tid: req.Tid, Status: "ok", Tags: tags}
+		return b.naiveResponder(cmd, resp)
 	case CmdMbtcpDeleteFilters:
 		req := r.(MbtcpFilterOpReq)
 		b.filterMap.DeleteAll()
+		b.filterStates.resetAll()
 		// send back
 		resp := MbtcpSimpleRes{Tid: req.Tid, Status: "ok"}
 		return b.naiveResponder(cmd, resp)
@@ -1076,6 +1644,7 @@ func (b *Service) HandleRequest(cmd string, r interface{}) error {
 						status = err.Error() // set error status
 						break                // break the for loop
 					}
+					b.filterStates.reset(v.Name) // evaluate from scratch
 				}
 			}
 		}
@@ -1120,11 +1689,25 @@ func (b *Service) HandleResponse(cmd string, r interface{}) error {
 		var TidStr string
 		var resp interface{}
 
+		switch MbCmdType(cmd) {
+		case setMbTimeout, getMbTimeout: // one-off timeout requests
+			TidStr = r.(DMbtcpTimeout).Tid
+		case fc5, fc6, fc15, fc16: // one-off write requests
+			TidStr = r.(DMbtcpRes).Tid
+		default: // should not reach here
+			return ErrResponseNotSupport
+		}
+
+		// check write task map
+		task, ok := b.writerMap.Get(TidStr)
+		if !ok {
+			// not in write task map!? should not reach here
+			return ErrRequestNotFound
+		}
+
 		switch MbCmdType(cmd) {
 		case setMbTimeout, getMbTimeout: // one-off timeout requests
 			res := r.(DMbtcpTimeout)
-			tid, _ := strconv.ParseInt(res.Tid, 10, 64)
-			TidStr = res.Tid
 			var int64Data int64
 
 			if MbCmdType(cmd) == getMbTimeout {
@@ -1132,45 +1715,42 @@ func (b *Service) HandleResponse(cmd string, r interface{}) error {
 			}
 
 			resp = MbtcpTimeoutRes{
-				Tid:    tid,
+				Tid:    task.Tid, // client tid
 				Status: res.Status,
 				Data:   int64Data, // getMbTimeout only
 			}
-		case fc5, fc6, fc15, fc16: // one-off write requests
+		default: // one-off write requests
 			res := r.(DMbtcpRes)
-			tid, _ := strconv.ParseInt(res.Tid, 10, 64)
-			TidStr = res.Tid
+			if b.retry(TidStr, res.Status) {
+				return nil // answer with the final result only
+			}
 			resp = MbtcpSimpleRes{
-				Tid:    tid,
-				Status: res.Status,
+				Tid:      task.Tid, // client tid
+				Status:   res.Status,
+				Attempts: b.retries.done(TidStr),
 			}
-		default: // should not reach here
-			return ErrResponseNotSupport
 		}
 
 		//
 		// send back one-off task response and remove from write task map
 		//
-		respStr, err := marshal(resp)
-		if err != nil {
-			conf.Log.WithError(err).Error("marshal")
-			return err
-		}
+		err := b.naiveResponder(task.Cmd, resp)
+		// remove from write task map!
+		b.writerMap.Delete(TidStr)
+		return err
+	case fc1, fc2, fc3, fc4: // one-off and polling requests
+		res := r.(DMbtcpRes)
 
-		// check write task map
-		if cmd, ok := b.writerMap.Get(TidStr); ok {
-			conf.Log.WithField("msg", respStr).Debug("Send response")
-			b.pub.upstream.Send(cmd, zmq.SNDMORE) // task command
-			b.pub.upstream.Send(respStr, 0)       // convert to string; frame 2
-			// remove from write task map!
-			b.writerMap.Delete(TidStr)
+		// split block read into responses of coalesced polls
+		if block, ok := b.coalescer.block(res.Tid); ok {
+			b.updateDevice(block.command.IP, block.command.Port, block.command.Slave, res.Status)
+			for _, member := range block.split(res) {
+				if err := b.HandleResponse(cmd, member); err != nil {
+					conf.Log.WithError(err).Warn("Fail to handle coalesced poll")
+				}
+			}
 			return nil
 		}
-		// not in write task map!? should not reach here
-		return ErrRequestNotFound
-	case fc1, fc2, fc3, fc4: // one-off and polling requests
-		res := r.(DMbtcpRes)
-		tid, _ := strconv.ParseInt(res.Tid, 10, 64)
 
 		// check read task table
 		t, ok := b.readerMap.GetTaskByID(res.Tid)
@@ -1181,6 +1761,26 @@ func (b *Service) HandleResponse(cmd string, r interface{}) error {
 		task := t.(ReaderTask) // type casting
 		respCmd := task.Cmd    // default response command string
 
+		// update device health
+		switch req := task.Req.(type) {
+		case MbtcpReadReq:
+			b.updateDevice(req.IP, req.Port, req.Slave, res.Status)
+		case MbtcpPollStatus:
+			if !b.coalescer.isCovered(res.Tid) { // updated by block read
+				b.updateDevice(req.IP, req.Port, req.Slave, res.Status)
+			}
+		}
+
+		var tid int64    // client tid; one-off requests only
+		var attempts int // the number of attempts; one-off requests with retry policy only
+		if readReq, ok := task.Req.(MbtcpReadReq); ok {
+			if b.retry(res.Tid, res.Status) {
+				return nil // answer with the final result only
+			}
+			tid = readReq.Tid
+			attempts = b.retries.done(res.Tid)
+		}
+
 		var response interface{}
 		var data interface{} // shared variable
 		status := "ok"       // shared variables
@@ -1190,20 +1790,21 @@ func (b *Service) HandleResponse(cmd string, r interface{}) error {
 		case fc1, fc2: // done: read bits
 
 			switch task.Cmd {
-			case CmdMbtcpOnceRead: // one-off requests
+			case CmdMbtcpOnceRead, CmdMbtcpReadTag: // one-off requests
 				if res.Status != "ok" {
 					data = nil
 				} else {
 					data = res.Data
 				}
 				response = MbtcpReadRes{
-					Tid:    tid,
-					Status: res.Status,
-					Data:   data,
+					Tid:      tid,
+					Status:   res.Status,
+					Data:     data,
+					Attempts: attempts,
 				}
 				// remove from read/poll table
 				b.readerMap.DeleteTaskByID(res.Tid)
-			case CmdMbtcpCreatePoll, CmdMbtcpImportPolls: // poll data
+			case CmdMbtcpCreatePoll, CmdMbtcpImportPolls, CmdMbtcpSubscribeTag: // poll data
 				respCmd = CmdMbtcpData // set as "mbtcp.data"
 				if res.Status != "ok" {
 					data = nil
@@ -1231,15 +1832,16 @@ func (b *Service) HandleResponse(cmd string, r interface{}) error {
 			return nil
 		case fc3, fc4: // read registers
 			switch task.Cmd {
-			case CmdMbtcpOnceRead: // one-off requests
+			case CmdMbtcpOnceRead, CmdMbtcpReadTag: // one-off requests
 				readReq := task.Req.(MbtcpReadReq) // type casting
 
 				// check modbus response status
 				if res.Status != "ok" {
 					response = MbtcpReadRes{
-						Tid:    tid,
-						Type:   readReq.Type,
-						Status: res.Status,
+						Tid:      tid,
+						Type:     readReq.Type,
+						Status:   res.Status,
+						Attempts: attempts,
 					}
 					// remove from read table
 					b.readerMap.DeleteTaskByID(res.Tid)
@@ -1251,9 +1853,10 @@ func (b *Service) HandleResponse(cmd string, r interface{}) error {
 				if err != nil {
 					conf.Log.WithError(err).Error("handleResponse: RegistersToBytes failed")
 					response = MbtcpReadRes{
-						Tid:    tid,
-						Type:   readReq.Type,
-						Status: err.Error(),
+						Tid:      tid,
+						Type:     readReq.Type,
+						Status:   err.Error(),
+						Attempts: attempts,
 					}
 					// remove from read table
 					b.readerMap.DeleteTaskByID(res.Tid)
@@ -1334,6 +1937,21 @@ func (b *Service) HandleResponse(cmd string, r interface{}) error {
 							}
 						}
 					}
+				case Int64, UInt64, Float64: // 64-bits
+					if readReq.Len%4 != 0 {
+						err := ErrInvalidLengthToConvert
+						data = nil
+						status = err.Error()
+					} else if ret, err := bytesTo64s(bytes, readReq.Type, readReq.Order); err != nil {
+						data = nil
+						status = err.Error()
+					} else {
+						data = ret
+						if readReq.Int64String {
+							data = int64Strings(ret)
+						}
+						status = res.Status
+					}
 				default: // case 0, 1(RegisterArray)
 					data = res.Data
 					status = res.Status
@@ -1341,18 +1959,19 @@ func (b *Service) HandleResponse(cmd string, r interface{}) error {
 
 				// shared response
 				response = MbtcpReadRes{
-					Tid:    tid,
-					Type:   readReq.Type,
-					Bytes:  bytes,
-					Data:   data,
-					Status: status,
+					Tid:      tid,
+					Type:     readReq.Type,
+					Bytes:    bytes,
+					Data:     data,
+					Status:   status,
+					Attempts: attempts,
 				}
 
 				// remove from read table
 				b.readerMap.DeleteTaskByID(res.Tid)
 				return b.naiveResponder(respCmd, response)
 
-			case CmdMbtcpCreatePoll, CmdMbtcpImportPolls: // poll data
+			case CmdMbtcpCreatePoll, CmdMbtcpImportPolls, CmdMbtcpSubscribeTag: // poll data
 				readReq := task.Req.(MbtcpPollStatus) // type casting
 				respCmd = CmdMbtcpData                // set as "mbtcp.data"
 
@@ -1453,11 +2072,26 @@ func (b *Service) HandleResponse(cmd string, r interface{}) error {
 							} else {
 								data = ret
 								status = res.Status
-								b.addToHistory(task.Name, data) // add to history; type: []float32
-								noFilter = b.applyFilter(task.Name, data)
+								noFilter = b.addToHistory(task.Name, data) // add to history; type: []float32
 							}
 						}
 					}
+				case Int64, UInt64, Float64: // 64-Bits
+					if readReq.Len%4 != 0 {
+						err := ErrInvalidLengthToConvert
+						data = nil
+						status = err.Error()
+					} else if ret, err := bytesTo64s(bytes, readReq.Type, readReq.Order); err != nil {
+						data = nil
+						status = err.Error()
+					} else {
+						status = res.Status
+						noFilter = b.addToHistory(task.Name, ret) // add to history; type: []int64, []uint64 or []float64
+						data = ret
+						if readReq.Int64String {
+							data = int64Strings(ret)
+						}
+					}
 				default: // case 0, 1(RegisterArray)
 					data = res.Data
 					status = res.Status
@@ -1495,6 +2129,181 @@ func (b *Service) HandleResponse(cmd string, r interface{}) error {
 	}
 }
 
+// pollInterval clamp poll interval in seconds, or in ms if set
+func pollInterval(interval, intervalMs uint64) (uint64, uint64) {
+	if intervalMs > 0 {
+		if intervalMs < minPollIntervalMs {
+			intervalMs = minPollIntervalMs
+		}
+		return interval, intervalMs
+	}
+	if interval < minPollInterval {
+		interval = minPollInterval
+	}
+	return interval, 0
+}
+
+// pollMilliseconds scheduler interval in ms of poll interval in seconds, or in ms if set
+func pollMilliseconds(interval, intervalMs uint64) uint64 {
+	if intervalMs > 0 {
+		return intervalMs
+	}
+	return interval * 1000
+}
+
+// pollLocation get the time zone of poll schedule, local time if not set
+func pollLocation(tz string) (*time.Location, error) {
+	if tz == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, ErrInvalidTimeZone
+	}
+	return loc, nil
+}
+
+// checkSchedule check cron expression and time zone of poll request, if set
+func checkSchedule(req MbtcpPollStatus) error {
+	if req.Cron == "" {
+		return nil // interval-based
+	}
+	if _, err := cron.ParseExpr(req.Cron); err != nil {
+		return ErrInvalidCronExpr
+	}
+	_, err := pollLocation(req.TimeZone)
+	return err
+}
+
+// schedulePoll add poll command to scheduler by cron expression if set, otherwise by interval;
+// 	the poll request should be checked by checkSchedule.
+func (b *Service) schedulePoll(req MbtcpPollStatus, command DMbtcpReadReq) {
+	if req.Cron != "" {
+		loc, _ := pollLocation(req.TimeZone)
+		if job, err := b.scheduler.CronWithName(req.Cron, req.Name, loc); err == nil {
+			job.Priority(schedulePriority(req)).Do(b.PollTask, b.pub.downstream, command)
+		}
+		return
+	}
+	interval := pollMilliseconds(req.Interval, req.IntervalMs)
+	b.scheduler.EveryWithName(interval, req.Name).Milliseconds().Priority(schedulePriority(req)).Do(b.PollTask, b.pub.downstream, command)
+}
+
+// schedulePriority scheduler priority of the poll schedule
+func schedulePriority(req MbtcpPollStatus) int {
+	if req.Cron != "" {
+		return prioritySlowPoll
+	}
+	return pollPriority(pollMilliseconds(req.Interval, req.IntervalMs))
+}
+
+// pollPriority scheduler priority of poll by interval in ms
+func pollPriority(interval uint64) int {
+	if interval < fastPollInterval {
+		return priorityFastPoll
+	}
+	return prioritySlowPoll
+}
+
+// deadline calculate one-off request deadline by timeout in ms,
+// 	fallback to the default timeout if not set; zero time if never timeout.
+func deadline(timeoutMs int64) time.Time {
+	if timeoutMs <= 0 {
+		timeoutMs = requestTimeout
+	}
+	if timeoutMs <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(time.Duration(timeoutMs) * time.Millisecond)
+}
+
+// sweep reply timeout status to expired one-off requests, evict them and release their in-flight commands
+func (b *Service) sweep(now time.Time) {
+	for TidStr, task := range b.writerMap.Expire(now) {
+		conf.Log.WithField("tid", task.Tid).Warn(ErrRequestTimeout.Error())
+		attempts := b.retries.done(TidStr)
+		b.naiveResponder(task.Cmd, MbtcpSimpleRes{Tid: task.Tid, Status: ErrRequestTimeout.Error(), Attempts: attempts})
+		b.releaseTid(TidStr)
+	}
+	for TidStr, task := range b.readerMap.ExpireTasks(now) {
+		var tid int64
+		if req, ok := task.Req.(MbtcpReadReq); ok {
+			tid = req.Tid
+		}
+		conf.Log.WithField("tid", tid).Warn(ErrRequestTimeout.Error())
+		attempts := b.retries.done(TidStr)
+		b.naiveResponder(task.Cmd, MbtcpSimpleRes{Tid: tid, Status: ErrRequestTimeout.Error(), Attempts: attempts})
+		b.releaseTid(TidStr)
+	}
+	// release in-flight polls never answered
+	timeout := requestTimeout
+	if timeout <= 0 {
+		timeout = defaultRequestTimeout
+	}
+	if ready := b.throttle.expire(now.Add(-time.Duration(timeout) * time.Millisecond)); len(ready) > 0 {
+		b.throttle.ready(ready)
+	}
+}
+
+// retry re-issue the one-off request through the emergency queue after backoff,
+// 	false if the status is not retryable or no attempts remain.
+func (b *Service) retry(tid, status string) bool {
+	command, backoff, ok := b.retries.next(tid, status)
+	if !ok {
+		return false
+	}
+	conf.Log.WithFields(conf.Fields{
+		"tid":     tid,
+		"status":  status,
+		"backoff": backoff,
+	}).Debug("Retry one-off request")
+	time.AfterFunc(backoff, func() {
+		if !b.isPending(tid) || !b.retries.issue(tid) {
+			return // replied with timeout and evicted by sweep
+		}
+		b.scheduler.Emergency().Priority(commandPriority(command)).Do(b.Task, b.pub.downstream, command)
+	})
+	return true
+}
+
+// isPending check whether the one-off request is still in the write or read/poll task map
+func (b *Service) isPending(tid string) bool {
+	if _, ok := b.writerMap.Get(tid); ok {
+		return true
+	}
+	_, ok := b.readerMap.GetTaskByID(tid)
+	return ok
+}
+
+// commandPriority scheduler priority of one-off downstream command
+func commandPriority(command interface{}) int {
+	if _, ok := command.(DMbtcpReadReq); ok {
+		return priorityRead
+	}
+	return priorityWrite
+}
+
+// queuePriority priority of the downstream command in the device queue, same as its scheduler job
+func (b *Service) queuePriority(command interface{}) int {
+	if r, ok := command.(DMbtcpReadReq); ok {
+		if block, ok := b.coalescer.block(r.Tid); ok { // block read
+			return schedulePriority(block.req)
+		}
+		if t, ok := b.readerMap.GetTaskByID(r.Tid); ok {
+			if poll, ok := t.(ReaderTask).Req.(MbtcpPollStatus); ok {
+				return schedulePriority(poll)
+			}
+		}
+	}
+	return commandPriority(command)
+}
+
+// newTid generate unique downstream transaction id,
+// 	so that requests with the same tid from different clients never collide.
+func (b *Service) newTid() string {
+	return strconv.FormatInt(atomic.AddInt64(&b.tid, 1), 10)
+}
+
 // Start enable proactive service
 func (b *Service) Start() {
 
@@ -1516,24 +2325,35 @@ func (b *Service) Start() {
 		}(w)
 	}
 
-	// process messages from both subscriber sockets
+	// sweep expired one-off requests
+	go func() {
+		for b.enable {
+			time.Sleep(sweepInterval)
+			b.sweep(time.Now())
+		}
+	}()
+
+	// receive requests from upstream transport
+	if err := b.upstream.Start(func(msg []string) {
+		// Check the length of multi-part message
+		if msg == nil || len(msg) != 2 {
+			conf.Log.WithError(ErrInvalidMessageLength).Warn("Drop incomplete message")
+			return
+		}
+		conf.Log.WithFields(conf.Fields{
+			"cmd": msg[0],
+			"req": msg[1],
+		}).Debug("Recv request")
+		b.dispatch(Upstream, msg)
+	}); err != nil {
+		conf.Log.WithError(err).Fatal("Fail to start upstream transport")
+	}
+
+	// process messages from downstream subscriber socket
 	for b.enable {
 		sockets, _ := b.poller.Poll(-1)
 		for _, socket := range sockets {
 			switch s := socket.Socket; s {
-			case b.sub.upstream:
-				// receive from upstream
-				msg, _ := b.sub.upstream.RecvMessage(0)
-				// Check the length of multi-part message
-				if msg == nil || len(msg) != 2 {
-					conf.Log.WithError(ErrInvalidMessageLength).Warn("Drop incomplete message")
-					return
-				}
-				conf.Log.WithFields(conf.Fields{
-					"cmd": msg[0],
-					"req": msg[1],
-				}).Debug("Recv request")
-				b.dispatch(Upstream, msg)
 			case b.sub.downstream:
 				// receive from modbusd
 				msg, _ := b.sub.downstream.RecvMessage(0)
@@ -1557,7 +2377,11 @@ func (b *Service) Stop() {
 	conf.Log.Debug("Stop proactive service")
 	b.scheduler.Stop()
 	b.enable = false
+	b.upstream.Stop()
 	b.stopZMQ()
+	if b.drivers != nil {
+		b.drivers.close()
+	}
 	// close job channel and wait for workers to complete
 	close(b.jobChan)
 }
@@ -1598,13 +2422,16 @@ func (w worker) process(j job) {
 				// for FC6, FC16 of CmdMbtcpOnceWrite
 				w.service.naiveResponder(j.msg[0], MbtcpSimpleRes{Tid: req.(int64), Status: err.Error()})
 			} else {
-				w.service.naiveResponder(j.msg[0], MbtcpSimpleRes{Status: err.Error()})
+				// carry the tid if any, so that transports correlating by tid can answer the sender
+				w.service.naiveResponder(j.msg[0], MbtcpSimpleRes{Tid: requestTid(j.msg[1]), Status: err.Error()})
 			}
 
 		}
 	default: // Downstream
 		// parse response
 		if res, err := w.service.ParseResponse(j.msg); res != nil {
+			// release the device queue
+			w.service.release(res)
 			// handle response
 			err := w.service.HandleResponse(j.msg[0], res)
 			if err != nil {
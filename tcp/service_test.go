@@ -0,0 +1,944 @@
+package tcp
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/taka-wang/psmb"
+	"github.com/taka-wang/psmb/cron"
+	malarm "github.com/taka-wang/psmb/mem-alarm"
+	mfilter "github.com/taka-wang/psmb/mem-filter"
+	mreader "github.com/taka-wang/psmb/mem-reader"
+	mtag "github.com/taka-wang/psmb/mem-tag"
+	mtransport "github.com/taka-wang/psmb/mem-transport"
+	mwriter "github.com/taka-wang/psmb/mem-writer"
+	history "github.com/taka-wang/psmb/redis-history"
+	"github.com/taka-wang/psmb/viper-conf"
+	"github.com/takawang/sugar"
+)
+
+// memTransport transport instance created by service
+var memTransport *mtransport.Transport
+
+// filterStore filter data store instance created by service
+var filterStore *countingFilter
+
+func init() {
+	Register("SharedTransport", func(c map[string]string) (interface{}, error) {
+		tp, err := mtransport.NewTransport(c)
+		memTransport = tp.(*mtransport.Transport)
+		return tp, err
+	})
+	Register("Reader", mreader.NewDataStore)
+	Register("Writer", mwriter.NewDataStore)
+	Register("History", history.NewDataStore) // connect lazily
+	Register("Filter", func(c map[string]string) (interface{}, error) {
+		ds, err := mfilter.NewDataStore(c)
+		if err != nil {
+			return nil, err
+		}
+		filterStore = &countingFilter{IFilterDataStore: ds.(IFilterDataStore)}
+		return filterStore, nil
+	})
+	Register("Alarm", malarm.NewDataStore)
+	Register("Tag", mtag.NewDataStore)
+	Register("Cron", cron.NewScheduler)
+	Register("EchoDriver", newEchoDriver)
+	Register("SlowDriver", newSlowDriver)
+}
+
+// countingFilter filter data store counting the writes
+type countingFilter struct {
+	IFilterDataStore
+	// adds number of added or updated filters
+	adds int32
+}
+
+func (f *countingFilter) Add(name string, req interface{}) error {
+	atomic.AddInt32(&f.adds, 1)
+	return f.IFilterDataStore.Add(name, req)
+}
+
+// writes get the number of added or updated filters
+func (f *countingFilter) writes() int32 {
+	return atomic.LoadInt32(&f.adds)
+}
+
+// setting get the config value in the type of the override
+func setting(key string, value interface{}) interface{} {
+	switch value.(type) {
+	case bool:
+		return conf.GetBool(key)
+	case int:
+		return conf.GetInt(key)
+	case map[string]string:
+		return conf.GetStringMapString(key)
+	default:
+		return conf.GetString(key)
+	}
+}
+
+// startService start the service over the shared transport and echo driver with config overrides,
+// 	return the transport and the stop function to stop the service and restore the config.
+func startService(t *testing.T, overrides map[string]interface{}) (*mtransport.Transport, func()) {
+	settings := map[string]interface{}{
+		keyUpstreamTransport: "SharedTransport",
+		keyDownstreamDriver:  "EchoDriver",
+	}
+	for key, value := range overrides {
+		settings[key] = value
+	}
+	previous := make(map[string]interface{})
+	for key, value := range settings {
+		previous[key] = setting(key, value)
+		conf.Set(key, value)
+	}
+	reset := func() {
+		for key, value := range previous {
+			conf.Set(key, value)
+		}
+	}
+
+	srv, err := NewService("Reader", "Writer", "History", "Filter", "Cron", Options{Alarm: "Alarm", Tag: "Tag"})
+	if err != nil {
+		reset()
+		t.Fatal(err)
+	}
+	go srv.Start()
+	return memTransport, func() {
+		srv.Stop()
+		reset()
+	}
+}
+
+// echo driver instance created by service
+var echo *echoDriver
+
+// echoDriver fake downstream driver, echo the read addresses as data,
+// 	never answer reading address 99, fail twice before reading address 98, stuck for a second reading address 97.
+type echoDriver struct {
+	sync.Mutex
+	// attempts (tid, attempts)
+	attempts map[string]int
+	// reads (addr:len, reads)
+	reads map[string]int
+	// writes (addr, last written data)
+	writes map[uint16]interface{}
+	// latency delay of every response
+	latency time.Duration
+}
+
+func newEchoDriver(c map[string]string) (interface{}, error) {
+	echo = &echoDriver{attempts: make(map[string]int), reads: make(map[string]int), writes: make(map[uint16]interface{})}
+	return echo, nil
+}
+
+// newSlowDriver echo driver answering slower than the minimal poll interval
+func newSlowDriver(c map[string]string) (interface{}, error) {
+	drv, err := newEchoDriver(c)
+	echo.latency = 150 * time.Millisecond
+	return drv, err
+}
+
+// written get the last written data of the address
+func (d *echoDriver) written(addr uint16) interface{} {
+	d.Lock()
+	defer d.Unlock()
+	return d.writes[addr]
+}
+
+// count get the number of reads of the address and length
+func (d *echoDriver) count(addr, length uint16) int {
+	d.Lock()
+	defer d.Unlock()
+	return d.reads[strconv.Itoa(int(addr))+":"+strconv.Itoa(int(length))]
+}
+
+func (d *echoDriver) Do(req interface{}) ([]string, error) {
+	var cmd int
+	res := DMbtcpRes{Status: "ok"}
+	time.Sleep(d.latency)
+	switch r := req.(type) {
+	case DMbtcpReadReq:
+		if r.Addr == 99 {
+			return nil, errors.New("no response")
+		}
+		if r.Addr == 97 {
+			time.Sleep(time.Second)
+		}
+		cmd, res.Tid = r.Cmd, r.Tid
+		for idx := uint16(0); idx < r.Len; idx++ {
+			res.Data = append(res.Data, r.Addr+idx)
+		}
+		d.Lock()
+		d.attempts[r.Tid]++
+		d.reads[strconv.Itoa(int(r.Addr))+":"+strconv.Itoa(int(r.Len))]++
+		if r.Addr == 98 && d.attempts[r.Tid] < 3 {
+			res.Status, res.Data = "busy", nil
+		}
+		d.Unlock()
+	case DMbtcpWriteReq:
+		cmd, res.Tid = r.Cmd, r.Tid
+		d.Lock()
+		d.writes[r.Addr] = r.Data
+		d.Unlock()
+	case DMbtcpTimeout:
+		cmd, res.Tid = r.Cmd, r.Tid
+	}
+	bytes, _ := json.Marshal(res)
+	return []string{strconv.Itoa(cmd), string(bytes)}, nil
+}
+
+func (d *echoDriver) Frame() string { return "tcp" }
+
+func (d *echoDriver) Close() {}
+
+// recvCmd receive the next reply of the command, skip others
+func recvCmd(mem *mtransport.Transport, cmd string, timeout time.Duration) ([]string, error) {
+	for {
+		msg, err := mem.Recv(timeout)
+		if err != nil || msg[0] == cmd {
+			return msg, err
+		}
+	}
+}
+
+// getQueue get the queue status of the first limited device
+func getQueue(mem *mtransport.Transport, tid int) (MbtcpQueueStatus, error) {
+	mem.Request(CmdMbtcpGetQueues, `{"tid":`+strconv.Itoa(tid)+`}`)
+	msg, err := recvCmd(mem, CmdMbtcpGetQueues, 2*time.Second)
+	if err != nil {
+		return MbtcpQueueStatus{}, err
+	}
+	var res MbtcpQueuesStatus
+	if err := json.Unmarshal([]byte(msg[1]), &res); err != nil {
+		return MbtcpQueueStatus{}, err
+	}
+	if len(res.Queues) == 0 {
+		return MbtcpQueueStatus{}, errors.New(res.Status)
+	}
+	return res.Queues[0], nil
+}
+
+// deletePoll delete the poll and wait for the reply,
+// 	so that no request is left to the stopped service.
+func deletePoll(mem *mtransport.Transport, req string) {
+	mem.Request(CmdMbtcpDeletePoll, req)
+	recvCmd(mem, CmdMbtcpDeletePoll, 2*time.Second)
+}
+
+func TestOptions(t *testing.T) {
+	s := sugar.New(t)
+
+	s.Assert("Commands of plugins not set should not be supported", func(logf sugar.Log) bool {
+		srv, err := NewService("Reader", "Writer", "History", "Filter", "Cron")
+		if err != nil {
+			logf("err:%v", err)
+			return false
+		}
+		_, alarmErr := srv.ParseRequest([]string{CmdMbtcpGetAlarms, `{"tid":1}`})
+		_, tagErr := srv.ParseRequest([]string{CmdMbtcpGetTags, `{"tid":2}`})
+		_, pollErr := srv.ParseRequest([]string{CmdMbtcpGetPolls, `{"tid":3}`})
+		logf("alarm:%v, tag:%v, poll:%v", alarmErr, tagErr, pollErr)
+		return alarmErr == ErrRequestNotSupport && tagErr == ErrRequestNotSupport && pollErr == nil
+	})
+}
+
+func TestTransactions(t *testing.T) {
+	s := sugar.New(t)
+
+	mem, stop := startService(t, nil)
+	defer stop()
+
+	s.Assert("Read requests with the same tid from two clients should not cross-talk", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpOnceRead, `{"tid":7,"from":"a","fc":3,"ip":"127.0.0.1","slave":1,"addr":1,"len":1}`)
+		mem.Request(CmdMbtcpOnceRead, `{"tid":7,"from":"b","fc":3,"ip":"127.0.0.1","slave":1,"addr":2,"len":1}`)
+
+		got := make(map[uint16]bool)
+		for idx := 0; idx < 2; idx++ {
+			msg, err := mem.Recv(2 * time.Second)
+			if err != nil {
+				logf("err:%v", err)
+				return false
+			}
+			logf("msg:%v", msg)
+			var res struct {
+				Tid  int64    `json:"tid"`
+				Data []uint16 `json:"data"`
+			}
+			json.Unmarshal([]byte(msg[1]), &res)
+			if msg[0] != CmdMbtcpOnceRead || res.Tid != 7 || len(res.Data) != 1 {
+				return false
+			}
+			got[res.Data[0]] = true
+		}
+		return got[1] && got[2]
+	})
+
+	s.Assert("Write requests with the same tid from two clients should not cross-talk", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpOnceWrite, `{"tid":8,"from":"a","fc":6,"ip":"127.0.0.1","slave":1,"addr":1,"data":"1"}`)
+		mem.Request(CmdMbtcpGetTimeout, `{"tid":8,"from":"b"}`)
+
+		got := make(map[string]bool)
+		for idx := 0; idx < 2; idx++ {
+			msg, err := mem.Recv(2 * time.Second)
+			if err != nil {
+				logf("err:%v", err)
+				return false
+			}
+			logf("msg:%v", msg)
+			var res MbtcpSimpleRes
+			json.Unmarshal([]byte(msg[1]), &res)
+			if res.Tid != 8 || res.Status != "ok" {
+				return false
+			}
+			got[msg[0]] = true
+		}
+		return got[CmdMbtcpOnceWrite] && got[CmdMbtcpGetTimeout]
+	})
+
+	s.Assert("Requests failed to parse should be replied with their tid", func(logf sugar.Log) bool {
+		mem.Request("mbtcp.unknown", `{"tid":70}`)
+		mem.Request(CmdMbtcpOnceRead, `{"tid":71,"fc":"3"}`)
+		got := make(map[string]bool)
+		for idx := 0; idx < 2; idx++ {
+			msg, err := mem.Recv(2 * time.Second)
+			logf("msg:%v, err:%v", msg, err)
+			if err != nil {
+				return false
+			}
+			got[msg[1]] = true
+		}
+		return got[`{"tid":70,"status":"`+ErrRequestNotSupport.Error()+`"}`] && got[`{"tid":71,"status":"`+ErrUnmarshal.Error()+`"}`]
+	})
+
+	s.Assert("Typed write requests should be encoded by type and byte order", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpOnceWrite, `{"tid":80,"fc":16,"ip":"127.0.0.1","slave":1,"addr":40,"type":8,"order":4,"data":[5000.234,2123.456]}`)
+		if msg, err := recvCmd(mem, CmdMbtcpOnceWrite, 2*time.Second); err != nil || msg[1] != `{"tid":80,"status":"ok"}` {
+			logf("msg:%v, err:%v", msg, err)
+			return false
+		}
+		bytes, _ := json.Marshal(echo.written(40))
+		logf("data:%s", bytes)
+		return string(bytes) == "[16863,17820,46924,17668]" // CDAB of 459C41DF4504B74C
+	})
+
+	s.Assert("Typed write requests out of range should fail", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpOnceWrite, `{"tid":81,"fc":6,"ip":"127.0.0.1","slave":1,"addr":41,"type":5,"data":-40000}`)
+		msg, err := recvCmd(mem, CmdMbtcpOnceWrite, 2*time.Second)
+		logf("msg:%v, err:%v", msg, err)
+		return err == nil && msg[1] == `{"tid":81,"status":"Invalid write value!"}`
+	})
+
+	s.Assert("64-bit typed write requests should not lose precision", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpOnceWrite, `{"tid":82,"fc":16,"ip":"127.0.0.1","slave":1,"addr":42,"type":10,"order":1,"data":18446744073709551615}`)
+		if msg, err := recvCmd(mem, CmdMbtcpOnceWrite, 2*time.Second); err != nil || msg[1] != `{"tid":82,"status":"ok"}` {
+			logf("msg:%v, err:%v", msg, err)
+			return false
+		}
+		bytes, _ := json.Marshal(echo.written(42))
+		logf("data:%s", bytes)
+		return string(bytes) == "[65535,65535,65535,65535]"
+	})
+
+	s.Assert("64-bit read requests should respond in decimal strings", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpOnceRead, `{"tid":83,"fc":3,"ip":"127.0.0.1","slave":1,"addr":0,"len":4,"type":10,"order":1,"int64_string":true}`)
+		msg, err := recvCmd(mem, CmdMbtcpOnceRead, 2*time.Second)
+		logf("msg:%v, err:%v", msg, err)
+		return err == nil && strings.Contains(msg[1], `"data":["4295098371"]`) // 0x0000000100020003
+	})
+
+	s.Assert("Unanswered read request should timeout", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpOnceRead, `{"tid":9,"from":"a","fc":3,"ip":"127.0.0.1","slave":1,"addr":99,"len":1,"timeout_ms":100}`)
+		msg, err := mem.Recv(2 * time.Second)
+		logf("msg:%v, err:%v", msg, err)
+		return err == nil && msg[0] == CmdMbtcpOnceRead && msg[1] == `{"tid":9,"status":"timeout"}`
+	})
+
+	s.Assert("Retryable failures should be retried and answered once", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpOnceRead, `{"tid":10,"fc":3,"ip":"127.0.0.1","slave":1,"addr":98,"len":1,"retry":{"max_attempts":3,"backoff_ms":10,"statuses":["busy"]}}`)
+		msg, err := mem.Recv(2 * time.Second)
+		logf("msg:%v, err:%v", msg, err)
+		var res MbtcpReadRes
+		if err != nil || json.Unmarshal([]byte(msg[1]), &res) != nil {
+			return false
+		}
+		if res.Tid != 10 || res.Status != "ok" || res.Attempts != 3 {
+			return false
+		}
+		_, err = mem.Recv(300 * time.Millisecond) // no more replies
+		return err == mtransport.ErrTimeout
+	})
+
+	s.Assert("Out of attempts should answer the last failure", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpOnceRead, `{"tid":11,"fc":3,"ip":"127.0.0.1","slave":1,"addr":98,"len":1,"retry":{"max_attempts":2}}`)
+		msg, err := mem.Recv(2 * time.Second)
+		logf("msg:%v, err:%v", msg, err)
+		return err == nil && msg[1] == `{"tid":11,"status":"busy","attempts":2}`
+	})
+
+	s.Assert("Consecutive failures should take the device offline", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpOnceRead, `{"tid":12,"fc":3,"ip":"127.0.0.1","slave":1,"addr":98,"len":1}`)
+		msg, err := mem.Recv(2 * time.Second) // transition before reply
+		logf("msg:%v, err:%v", msg, err)
+		var event MbtcpDeviceEvent
+		if err != nil || msg[0] != CmdMbtcpDeviceStatus || json.Unmarshal([]byte(msg[1]), &event) != nil {
+			return false
+		}
+		if event.State != DeviceOffline || event.Failures != 3 || event.Status != "busy" {
+			return false
+		}
+		msg, err = mem.Recv(2 * time.Second)
+		return err == nil && msg[1] == `{"tid":12,"status":"busy"}`
+	})
+
+	s.Assert("Devices should be reported with health", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpGetDevices, `{"tid":13}`)
+		msg, err := mem.Recv(2 * time.Second)
+		logf("msg:%v, err:%v", msg, err)
+		var res MbtcpDevicesStatus
+		if err != nil || json.Unmarshal([]byte(msg[1]), &res) != nil {
+			return false
+		}
+		if res.Tid != 13 || res.Status != "ok" || len(res.Devices) != 1 {
+			return false
+		}
+		device := res.Devices[0]
+		return device.IP == "127.0.0.1" && device.Port == "502" && device.State == DeviceOffline && device.NextPoll > 0
+	})
+
+	s.Assert("Timed out requests should not be retried", func(logf sugar.Log) bool {
+		before := echo.count(98, 2)
+		mem.Request(CmdMbtcpOnceRead, `{"tid":22,"fc":3,"ip":"127.0.0.1","slave":6,"addr":98,"len":2,"timeout_ms":100,"retry":{"max_attempts":3,"backoff_ms":300}}`)
+		msg, err := recvCmd(mem, CmdMbtcpOnceRead, 2*time.Second)
+		if err != nil || msg[1] != `{"tid":22,"status":"timeout","attempts":1}` {
+			logf("msg:%v, err:%v", msg, err)
+			return false
+		}
+		time.Sleep(500 * time.Millisecond)
+		logf("reads:%d", echo.count(98, 2)-before)
+		return echo.count(98, 2)-before == 1
+	})
+
+	s.Assert("Polls should support millisecond intervals", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpCreatePoll, `{"tid":14,"name":"fast","interval_ms":100,"enabled":true,"fc":3,"ip":"127.0.0.1","slave":2,"addr":5,"len":1}`)
+		if msg, err := mem.Recv(2 * time.Second); err != nil || msg[1] != `{"tid":14,"status":"ok"}` {
+			logf("msg:%v, err:%v", msg, err)
+			return false
+		}
+		defer deletePoll(mem, `{"tid":15,"name":"fast"}`)
+
+		count := 0
+		for end := time.Now().Add(650 * time.Millisecond); time.Now().Before(end); {
+			if msg, err := mem.Recv(100 * time.Millisecond); err == nil && msg[0] == CmdMbtcpData {
+				count++
+			}
+		}
+		logf("poll data: %d", count)
+		return count >= 4
+	})
+
+	s.Assert("Cron-based polls should round-trip through import and export", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpCreatePoll, `{"tid":16,"name":"bad","cron":"* * *","enabled":true,"fc":3,"ip":"127.0.0.1","slave":2,"addr":5}`)
+		if msg, err := recvCmd(mem, CmdMbtcpCreatePoll, 2*time.Second); err != nil || msg[1] != `{"tid":16,"status":"Invalid cron expression!"}` {
+			logf("msg:%v, err:%v", msg, err)
+			return false
+		}
+		mem.Request(CmdMbtcpCreatePoll, `{"tid":17,"name":"bad","cron":"0 8 * * *","timezone":"Mars/Olympus","enabled":true,"fc":3,"ip":"127.0.0.1","slave":2,"addr":5}`)
+		if msg, err := recvCmd(mem, CmdMbtcpCreatePoll, 2*time.Second); err != nil || msg[1] != `{"tid":17,"status":"Invalid time zone!"}` {
+			logf("msg:%v, err:%v", msg, err)
+			return false
+		}
+
+		mem.Request(CmdMbtcpImportPolls, `{"tid":18,"polls":[{"name":"shift","cron":"0 8 * * MON-FRI","timezone":"UTC","enabled":true,"fc":3,"ip":"127.0.0.1","slave":2,"addr":5,"len":1}]}`)
+		if msg, err := recvCmd(mem, CmdMbtcpImportPolls, 2*time.Second); err != nil || msg[1] != `{"tid":18,"status":"ok"}` {
+			logf("msg:%v, err:%v", msg, err)
+			return false
+		}
+		defer deletePoll(mem, `{"tid":21,"name":"shift"}`)
+
+		mem.Request(CmdMbtcpUpdatePoll, `{"tid":19,"name":"shift","interval":3}`)
+		if msg, err := recvCmd(mem, CmdMbtcpUpdatePoll, 2*time.Second); err != nil || msg[1] != `{"tid":19,"status":"Cron-based poll has no interval!"}` {
+			logf("msg:%v, err:%v", msg, err)
+			return false
+		}
+
+		mem.Request(CmdMbtcpExportPolls, `{"tid":20}`)
+		msg, err := recvCmd(mem, CmdMbtcpExportPolls, 2*time.Second)
+		logf("msg:%v, err:%v", msg, err)
+		var res MbtcpPollsStatus
+		if err != nil || json.Unmarshal([]byte(msg[1]), &res) != nil {
+			return false
+		}
+		for _, poll := range res.Polls {
+			if poll.Name == "shift" {
+				return poll.Cron == "0 8 * * MON-FRI" && poll.TimeZone == "UTC"
+			}
+		}
+		return false
+	})
+}
+
+func TestThrottle(t *testing.T) {
+	s := sugar.New(t)
+
+	mem, stop := startService(t, map[string]interface{}{
+		keyDeviceInFlight: map[string]string{"127.0.0.1:1502": "1"},
+		keyDeviceRate:     map[string]string{"127.0.0.1:1502": "5"},
+		keyDeviceMaxQueue: 2,
+	})
+	defer stop()
+
+	s.Assert("Requests of rate limited device should be spaced", func(logf sugar.Log) bool {
+		start := time.Now()
+		for tid := 30; tid < 33; tid++ {
+			mem.Request(CmdMbtcpOnceRead, `{"tid":`+strconv.Itoa(tid)+`,"fc":3,"ip":"127.0.0.1","port":"1502","slave":1,"addr":1,"len":1}`)
+		}
+		for idx := 0; idx < 3; idx++ {
+			if msg, err := recvCmd(mem, CmdMbtcpOnceRead, 2*time.Second); err != nil {
+				logf("msg:%v, err:%v", msg, err)
+				return false
+			}
+		}
+		elapsed := time.Since(start)
+		logf("elapsed:%v", elapsed)
+		return elapsed >= 400*time.Millisecond
+	})
+
+	s.Assert("Queues of rate limited devices should be reported", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpGetQueues, `{"tid":33}`)
+		msg, err := recvCmd(mem, CmdMbtcpGetQueues, 2*time.Second)
+		logf("msg:%v, err:%v", msg, err)
+		return err == nil && msg[1] == `{"tid":33,"status":"ok","queues":[{"device":"127.0.0.1:1502","depth":0,"in_flight":0,"dropped":0,"max_in_flight":1,"max_rate":5}]}`
+	})
+
+	s.Assert("Requests beyond the device queue should be answered at once", func(logf sugar.Log) bool {
+		time.Sleep(200 * time.Millisecond) // out of the rate limit window
+		for tid := 34; tid < 38; tid++ {
+			mem.Request(CmdMbtcpOnceRead, `{"tid":`+strconv.Itoa(tid)+`,"fc":3,"ip":"127.0.0.1","port":"1502","slave":1,"addr":1,"len":1,"timeout_ms":1500}`)
+		}
+		status := make(map[string]int)
+		for idx := 0; idx < 4; idx++ {
+			msg, err := recvCmd(mem, CmdMbtcpOnceRead, 2*time.Second)
+			if err != nil {
+				logf("err:%v", err)
+				return false
+			}
+			var res MbtcpSimpleRes
+			json.Unmarshal([]byte(msg[1]), &res)
+			status[res.Status]++
+		}
+		logf("status:%v", status)
+		if status["ok"] != 3 || status[ErrDeviceQueueFull.Error()] != 1 {
+			return false
+		}
+		// the dropped request is evicted, never timeout
+		if msg, err := recvCmd(mem, CmdMbtcpOnceRead, 1800*time.Millisecond); err != mtransport.ErrTimeout {
+			logf("msg:%v", msg)
+			return false
+		}
+		queue, err := getQueue(mem, 38)
+		logf("queue:%v, err:%v", queue, err)
+		return err == nil && queue.Dropped == 1 && queue.Depth == 0
+	})
+
+	s.Assert("In-flight requests should be capped and released on timeout", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpOnceRead, `{"tid":39,"fc":3,"ip":"127.0.0.1","port":"1502","slave":1,"addr":99,"len":1,"timeout_ms":500}`)
+		time.Sleep(100 * time.Millisecond)
+		mem.Request(CmdMbtcpOnceRead, `{"tid":40,"fc":3,"ip":"127.0.0.1","port":"1502","slave":1,"addr":1,"len":1}`)
+		time.Sleep(100 * time.Millisecond)
+		queue, err := getQueue(mem, 41)
+		logf("queue:%v, err:%v", queue, err)
+		if err != nil || queue.InFlight != 1 || queue.Depth != 1 {
+			return false
+		}
+
+		if msg, err := recvCmd(mem, CmdMbtcpOnceRead, 2*time.Second); err != nil || msg[1] != `{"tid":39,"status":"timeout"}` {
+			logf("msg:%v, err:%v", msg, err)
+			return false
+		}
+		if msg, err := recvCmd(mem, CmdMbtcpOnceRead, 2*time.Second); err != nil || !strings.HasPrefix(msg[1], `{"tid":40,"status":"ok"`) {
+			logf("msg:%v, err:%v", msg, err)
+			return false
+		}
+		queue, err = getQueue(mem, 42)
+		logf("queue:%v, err:%v", queue, err)
+		return err == nil && queue.InFlight == 0 && queue.Depth == 0
+	})
+}
+
+func TestSlowPoll(t *testing.T) {
+	s := sugar.New(t)
+
+	mem, stop := startService(t, map[string]interface{}{
+		keyDownstreamDriver: "SlowDriver",
+		keyDeviceInFlight:   map[string]string{"127.0.0.1:1503": "2"},
+	})
+	defer stop()
+
+	s.Assert("Overlapping ticks of a poll slower than its interval should be released", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpCreatePoll, `{"tid":90,"name":"slow","interval_ms":100,"enabled":true,"fc":3,"ip":"127.0.0.1","port":"1503","slave":1,"addr":1,"len":1}`)
+		if msg, err := recvCmd(mem, CmdMbtcpCreatePoll, 2*time.Second); err != nil || msg[1] != `{"tid":90,"status":"ok"}` {
+			logf("msg:%v, err:%v", msg, err)
+			return false
+		}
+
+		count := 0
+		for end := time.Now().Add(1500 * time.Millisecond); time.Now().Before(end); {
+			if _, err := recvCmd(mem, CmdMbtcpData, 200*time.Millisecond); err == nil {
+				count++
+			}
+		}
+		deletePoll(mem, `{"tid":91,"name":"slow"}`)
+		// the ticks queued before deletion are drained one by one
+		queue, err := getQueue(mem, 92)
+		for end := time.Now().Add(2 * time.Second); err == nil && queue.InFlight+queue.Depth > 0 && time.Now().Before(end); {
+			time.Sleep(100 * time.Millisecond)
+			queue, err = getQueue(mem, 92)
+		}
+		logf("poll data:%d, queue:%v, err:%v", count, queue, err)
+		return count >= 6 && err == nil && queue.InFlight == 0 && queue.Depth == 0
+	})
+}
+
+func TestStuckDevice(t *testing.T) {
+	s := sugar.New(t)
+
+	mem, stop := startService(t, nil)
+	defer stop()
+
+	s.Assert("A stuck device should not delay requests to other devices", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpOnceRead, `{"tid":84,"fc":3,"ip":"127.0.0.2","slave":1,"addr":97,"len":1}`)
+		time.Sleep(50 * time.Millisecond) // stuck first
+		start := time.Now()
+		mem.Request(CmdMbtcpOnceRead, `{"tid":85,"fc":3,"ip":"127.0.0.1","slave":1,"addr":3,"len":1}`)
+		msg, err := mem.Recv(2 * time.Second)
+		elapsed := time.Since(start)
+		logf("msg:%v, err:%v, elapsed:%v", msg, err, elapsed)
+		if err != nil || !strings.Contains(msg[1], `"tid":85`) || elapsed > 500*time.Millisecond {
+			return false
+		}
+		msg, err = mem.Recv(2 * time.Second)
+		logf("msg:%v, err:%v", msg, err)
+		return err == nil && strings.Contains(msg[1], `"tid":84`)
+	})
+}
+
+func TestCoalesce(t *testing.T) {
+	s := sugar.New(t)
+
+	mem, stop := startService(t, map[string]interface{}{
+		keyPollCoalesce:    true,
+		keyPollCoalesceGap: 2,
+	})
+	defer stop()
+
+	s.Assert("Adjacent polls should be read in one block and decoded per poll", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpImportPolls, `{"tid":40,"polls":[
+			{"name":"t1","interval_ms":200,"enabled":true,"fc":3,"ip":"127.0.0.1","slave":3,"addr":10,"len":2,"type":4},
+			{"name":"t2","interval_ms":200,"enabled":true,"fc":3,"ip":"127.0.0.1","slave":3,"addr":12,"len":2,"type":2},
+			{"name":"t3","interval_ms":200,"enabled":true,"fc":3,"ip":"127.0.0.1","slave":3,"addr":16,"len":1},
+			{"name":"t4","interval_ms":200,"enabled":true,"fc":3,"ip":"127.0.0.1","slave":3,"addr":200,"len":1},
+			{"name":"t5","interval_ms":300,"enabled":true,"fc":3,"ip":"127.0.0.1","slave":3,"addr":17,"len":1}]}`)
+		if msg, err := recvCmd(mem, CmdMbtcpImportPolls, 2*time.Second); err != nil || msg[1] != `{"tid":40,"status":"ok"}` {
+			logf("msg:%v, err:%v", msg, err)
+			return false
+		}
+		defer func() {
+			mem.Request(CmdMbtcpDeletePolls, `{"tid":41}`)
+			recvCmd(mem, CmdMbtcpDeletePolls, 2*time.Second)
+		}()
+
+		got := make(map[string]string)
+		for len(got) < 5 {
+			msg, err := recvCmd(mem, CmdMbtcpData, 2*time.Second)
+			if err != nil {
+				logf("err:%v", err)
+				return false
+			}
+			var data MbtcpPollData
+			json.Unmarshal([]byte(msg[1]), &data)
+			bytes, _ := json.Marshal(data.Data)
+			got[data.Name] = string(bytes)
+		}
+		logf("data:%v", got)
+		if got["t1"] != "[10,11]" || got["t2"] != `"000c000d"` || got["t3"] != "[16]" || got["t4"] != "[200]" || got["t5"] != "[17]" {
+			return false
+		}
+		logf("block:%d, t1:%d, t4:%d", echo.count(10, 7), echo.count(10, 2), echo.count(200, 1))
+		return echo.count(10, 7) > 0 && echo.count(10, 2) == 0 && echo.count(200, 1) > 0
+	})
+
+	s.Assert("Disabled polls should leave the block", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpImportPolls, `{"tid":42,"polls":[
+			{"name":"a1","interval_ms":200,"enabled":true,"fc":1,"ip":"127.0.0.1","slave":4,"addr":0,"len":8},
+			{"name":"a2","interval_ms":200,"enabled":true,"fc":1,"ip":"127.0.0.1","slave":4,"addr":8,"len":8}]}`)
+		recvCmd(mem, CmdMbtcpImportPolls, 2*time.Second)
+		defer func() {
+			mem.Request(CmdMbtcpDeletePolls, `{"tid":44}`)
+			recvCmd(mem, CmdMbtcpDeletePolls, 2*time.Second)
+		}()
+		mem.Request(CmdMbtcpTogglePoll, `{"tid":43,"name":"a2","enabled":false}`)
+		recvCmd(mem, CmdMbtcpTogglePoll, 2*time.Second)
+
+		before := echo.count(0, 8)
+		time.Sleep(500 * time.Millisecond)
+		logf("block:%d, a1:%d", echo.count(0, 16), echo.count(0, 8)-before)
+		return echo.count(0, 8)-before >= 2
+	})
+}
+
+func TestTag(t *testing.T) {
+	s := sugar.New(t)
+
+	mem, stop := startService(t, nil)
+	defer stop()
+
+	s.Assert("Tags of unknown device should be rejected", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpCreateTag, `{"tid":50,"name":"temp","device":"plc","fc":3,"addr":20}`)
+		msg, err := recvCmd(mem, CmdMbtcpCreateTag, 2*time.Second)
+		logf("msg:%v, err:%v", msg, err)
+		return err == nil && msg[1] == `{"tid":50,"status":"Invalid tag device!"}`
+	})
+
+	s.Assert("Devices and tags should be created", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpCreateTagDevice, `{"tid":51,"name":"plc","ip":"127.0.0.1","slave":5}`)
+		if msg, err := recvCmd(mem, CmdMbtcpCreateTagDevice, 2*time.Second); err != nil || msg[1] != `{"tid":51,"status":"ok"}` {
+			logf("msg:%v, err:%v", msg, err)
+			return false
+		}
+		mem.Request(CmdMbtcpCreateTag, `{"tid":52,"name":"temp","device":"plc","fc":3,"addr":20,"type":4}`)
+		recvCmd(mem, CmdMbtcpCreateTag, 2*time.Second)
+		mem.Request(CmdMbtcpCreateTag, `{"tid":53,"name":"setpoint","device":"plc","fc":3,"addr":30,"type":4,"access":"rw"}`)
+		recvCmd(mem, CmdMbtcpCreateTag, 2*time.Second)
+
+		mem.Request(CmdMbtcpGetTags, `{"tid":54}`)
+		msg, err := recvCmd(mem, CmdMbtcpGetTags, 2*time.Second)
+		logf("msg:%v, err:%v", msg, err)
+		return err == nil && msg[1] == `{"tid":54,"status":"ok","tags":[`+
+			`{"name":"setpoint","device":"plc","fc":3,"addr":30,"len":1,"type":4,"access":"rw"},`+
+			`{"name":"temp","device":"plc","fc":3,"addr":20,"len":1,"type":4,"access":"r"}]}`
+	})
+
+	s.Assert("Devices in use should not be deleted", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpDeleteTagDevice, `{"tid":55,"name":"plc"}`)
+		msg, err := recvCmd(mem, CmdMbtcpDeleteTagDevice, 2*time.Second)
+		logf("msg:%v, err:%v", msg, err)
+		return err == nil && msg[1] == `{"tid":55,"status":"Tag device in use!"}`
+	})
+
+	s.Assert("Tags should be read by name", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpReadTag, `{"tid":56,"name":"temp"}`)
+		msg, err := recvCmd(mem, CmdMbtcpReadTag, 2*time.Second)
+		if err != nil {
+			logf("err:%v", err)
+			return false
+		}
+		var res MbtcpReadRes
+		json.Unmarshal([]byte(msg[1]), &res)
+		bytes, _ := json.Marshal(res.Data)
+		logf("msg:%v", msg)
+		return res.Tid == 56 && res.Status == "ok" && string(bytes) == "[20]"
+	})
+
+	s.Assert("Read only tags should not be written", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpWriteTag, `{"tid":57,"name":"temp","data":1}`)
+		msg, err := recvCmd(mem, CmdMbtcpWriteTag, 2*time.Second)
+		logf("msg:%v, err:%v", msg, err)
+		return err == nil && msg[1] == `{"tid":57,"status":"Tag is read-only!"}`
+	})
+
+	s.Assert("Tags should be written by name", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpWriteTag, `{"tid":58,"name":"setpoint","data":"22"}`)
+		msg, err := recvCmd(mem, CmdMbtcpWriteTag, 2*time.Second)
+		logf("msg:%v, err:%v", msg, err)
+		return err == nil && msg[1] == `{"tid":58,"status":"ok"}`
+	})
+
+	s.Assert("Subscribed tags should be published by name", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpSubscribeTag, `{"tid":59,"name":"temp","interval_ms":200}`)
+		if msg, err := recvCmd(mem, CmdMbtcpSubscribeTag, 2*time.Second); err != nil || msg[1] != `{"tid":59,"status":"ok"}` {
+			logf("msg:%v, err:%v", msg, err)
+			return false
+		}
+		defer func() {
+			mem.Request(CmdMbtcpUnsubscribeTag, `{"tid":60,"name":"temp"}`)
+			recvCmd(mem, CmdMbtcpUnsubscribeTag, 2*time.Second)
+		}()
+		msg, err := recvCmd(mem, CmdMbtcpData, 2*time.Second)
+		logf("msg:%v, err:%v", msg, err)
+		if err != nil {
+			return false
+		}
+		var data MbtcpPollData
+		json.Unmarshal([]byte(msg[1]), &data)
+		return data.Name == "temp"
+	})
+}
+
+func TestFilterState(t *testing.T) {
+	s := sugar.New(t)
+
+	mem, stop := startService(t, nil)
+	defer stop()
+
+	// published count the data published within the duration
+	published := func(d time.Duration) int {
+		count := 0
+		for end := time.Now().Add(d); time.Now().Before(end); {
+			if _, err := recvCmd(mem, CmdMbtcpData, 100*time.Millisecond); err == nil {
+				count++
+			}
+		}
+		return count
+	}
+
+	mem.Request(CmdMbtcpCreateFilter, `{"tid":50,"name":"db","enabled":true,"type":10,"arg":[1]}`)
+	recvCmd(mem, CmdMbtcpCreateFilter, 2*time.Second)
+	mem.Request(CmdMbtcpCreatePoll, `{"tid":51,"name":"db","interval_ms":100,"enabled":true,"fc":3,"ip":"127.0.0.1","slave":5,"addr":5,"len":1}`)
+	recvCmd(mem, CmdMbtcpCreatePoll, 2*time.Second)
+	defer func() {
+		deletePoll(mem, `{"tid":59,"name":"db"}`)
+		mem.Request(CmdMbtcpDeleteFilters, `{"tid":60}`)
+		recvCmd(mem, CmdMbtcpDeleteFilters, 2*time.Second)
+	}()
+
+	s.Assert("Deadband filter should publish the unchanged value once", func(logf sugar.Log) bool {
+		count := published(600 * time.Millisecond)
+		logf("published:%d", count)
+		return count == 1
+	})
+
+	s.Assert("Filter state should not be exported", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpExportFilters, `{"tid":52}`)
+		msg, err := recvCmd(mem, CmdMbtcpExportFilters, 2*time.Second)
+		logf("msg:%v, err:%v", msg, err)
+		return err == nil && strings.Contains(msg[1], `"name":"db"`) && !strings.Contains(msg[1], "published")
+	})
+
+	s.Assert("Updated filter should be evaluated from scratch", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpCreateFilter, `{"tid":53,"name":"db","enabled":true,"type":10,"arg":[2]}`)
+		recvCmd(mem, CmdMbtcpCreateFilter, 2*time.Second)
+		count := published(600 * time.Millisecond)
+		logf("published:%d", count)
+		return count == 1
+	})
+
+	s.Assert("Stale filter should count the polls without writing the filter store", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpCreateFilter, `{"tid":54,"name":"db","enabled":true,"type":14,"arg":[3]}`)
+		recvCmd(mem, CmdMbtcpCreateFilter, 2*time.Second)
+		before := filterStore.writes()
+		count := published(600 * time.Millisecond)
+		logf("published:%d, writes:%d", count, filterStore.writes()-before)
+		return filterStore.writes() == before
+	})
+}
+
+func TestProfile(t *testing.T) {
+	s := sugar.New(t)
+
+	dir, err := ioutil.TempDir("", "profiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "meter.toml"), []byte(`
+name = "meter"
+
+[[blocks]]
+name = "voltage"
+fc = 3
+addr = 100
+len = 2
+type = 4
+interval_ms = 200
+
+[[blocks]]
+name = "current"
+fc = 4
+addr = 200
+interval = 1
+  [blocks.filter]
+  enabled = true
+  type = 10
+  arg = [0.5]
+`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{"blocks":[
+		{"name":"ok","fc":3,"addr":1},
+		{"name":"bad","fc":9,"addr":2}]}`), 0644)
+
+	mem, stop := startService(t, map[string]interface{}{
+		keyProfileDir: dir,
+	})
+	defer stop()
+
+	s.Assert("Profile should create prefixed polls and filters", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpApplyProfile, `{"tid":70,"profile":"meter","prefix":"site1_","ip":"127.0.0.1","slave":7}`)
+		if msg, err := recvCmd(mem, CmdMbtcpApplyProfile, 2*time.Second); err != nil || msg[1] != `{"tid":70,"status":"ok"}` {
+			logf("msg:%v, err:%v", msg, err)
+			return false
+		}
+		mem.Request(CmdMbtcpGetPolls, `{"tid":71}`)
+		msg, err := recvCmd(mem, CmdMbtcpGetPolls, 2*time.Second)
+		if err != nil {
+			logf("err:%v", err)
+			return false
+		}
+		var polls MbtcpPollsStatus
+		json.Unmarshal([]byte(msg[1]), &polls)
+		names := make(map[string]MbtcpPollStatus)
+		for _, poll := range polls.Polls {
+			names[poll.Name] = poll
+		}
+		logf("polls:%v", names)
+		if len(polls.Polls) != 2 || names["site1_voltage"].Addr != 100 || names["site1_current"].Slave != 7 {
+			return false
+		}
+		mem.Request(CmdMbtcpGetFilter, `{"tid":72,"name":"site1_current"}`)
+		msg, err = recvCmd(mem, CmdMbtcpGetFilter, 2*time.Second)
+		logf("msg:%v, err:%v", msg, err)
+		var filter MbtcpFilterStatus
+		json.Unmarshal([]byte(msg[1]), &filter)
+		return err == nil && filter.Status == "ok" && filter.Enabled
+	})
+
+	s.Assert("Profile should not overwrite existing polls", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpApplyProfile, `{"tid":73,"profile":"meter","prefix":"site1_","ip":"127.0.0.1","slave":8}`)
+		msg, err := recvCmd(mem, CmdMbtcpApplyProfile, 2*time.Second)
+		logf("msg:%v, err:%v", msg, err)
+		return err == nil && msg[1] == `{"tid":73,"status":"Poll of profile already exists!"}`
+	})
+
+	s.Assert("Invalid profile should create nothing", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpApplyProfile, `{"tid":74,"profile":"broken","prefix":"site2_","ip":"127.0.0.1","slave":9}`)
+		if msg, err := recvCmd(mem, CmdMbtcpApplyProfile, 2*time.Second); err != nil || msg[1] != `{"tid":74,"status":"Invalid function code!"}` {
+			logf("msg:%v, err:%v", msg, err)
+			return false
+		}
+		mem.Request(CmdMbtcpGetPoll, `{"tid":75,"name":"site2_ok"}`)
+		msg, err := recvCmd(mem, CmdMbtcpGetPoll, 2*time.Second)
+		logf("msg:%v, err:%v", msg, err)
+		return err == nil && msg[1] == `{"tid":75,"status":"Invalid poll name!"}`
+	})
+
+	s.Assert("Unknown profile should be rejected", func(logf sugar.Log) bool {
+		mem.Request(CmdMbtcpApplyProfile, `{"tid":76,"profile":"../meter","ip":"127.0.0.1"}`)
+		msg, err := recvCmd(mem, CmdMbtcpApplyProfile, 2*time.Second)
+		logf("msg:%v, err:%v", msg, err)
+		return err == nil && msg[1] == `{"tid":76,"status":"Invalid profile name!"}`
+	})
+
+	mem.Request(CmdMbtcpDeletePolls, `{"tid":77}`)
+	recvCmd(mem, CmdMbtcpDeletePolls, 2*time.Second)
+	mem.Request(CmdMbtcpDeleteFilters, `{"tid":78}`)
+	recvCmd(mem, CmdMbtcpDeleteFilters, 2*time.Second)
+}
@@ -0,0 +1,201 @@
+package tcp
+
+import (
+	"encoding/json"
+
+	. "github.com/taka-wang/psmb"
+)
+
+// tag access
+const (
+	tagReadOnly  = "r"
+	tagReadWrite = "rw"
+)
+
+// tagLength default register (or bit) length of tag by type
+func tagLength(tag MbtcpTag) uint16 {
+	if tag.Len > 0 {
+		return tag.Len
+	}
+	switch tag.Type {
+	case Scale, UInt32, Int32, Float32: // 32-bits
+		if tag.FC == 3 || tag.FC == 4 {
+			return 2
+		}
+	case Int64, UInt64, Float64: // 64-bits
+		if tag.FC == 3 || tag.FC == 4 {
+			return 4
+		}
+	}
+	return 1
+}
+
+// checkTagDevice check and normalize device definition
+func checkTagDevice(device MbtcpTagDevice) (MbtcpTagDevice, error) {
+	if device.Name == "" || device.IP == "" {
+		return device, ErrInvalidTagDevice
+	}
+	// protect null port
+	if device.Port == "" {
+		device.Port = defaultMbPort
+	}
+	return device, nil
+}
+
+// checkTag check and normalize tag definition
+func (b *Service) checkTag(tag MbtcpTag) (MbtcpTag, error) {
+	if tag.Name == "" {
+		return tag, ErrInvalidTagName
+	}
+	if _, ok := b.tagMap.GetDevice(tag.Device); !ok {
+		return tag, ErrInvalidTagDevice
+	}
+	// function code checker
+	if tag.FC < 1 || tag.FC > 4 {
+		return tag, ErrInvalidFunctionCode
+	}
+	switch tag.Access {
+	case "":
+		tag.Access = tagReadOnly
+	case tagReadOnly, tagReadWrite:
+	default:
+		return tag, ErrInvalidTagAccess
+	}
+	tag.Len = tagLength(tag)
+	tag.Tid, tag.From, tag.Status = 0, "", ""
+	return tag, nil
+}
+
+// lookupTag get the tag and its device by tag name
+func (b *Service) lookupTag(name string) (MbtcpTag, MbtcpTagDevice, error) {
+	t, ok := b.tagMap.Get(name)
+	if !ok {
+		return MbtcpTag{}, MbtcpTagDevice{}, ErrTagNotFound
+	}
+	tag := t.(MbtcpTag)
+	d, ok := b.tagMap.GetDevice(tag.Device)
+	if !ok {
+		return tag, MbtcpTagDevice{}, ErrInvalidTagDevice
+	}
+	device := d.(MbtcpTagDevice)
+	if tag.Order == 0 { // device default
+		tag.Order = device.Order
+	}
+	return tag, device, nil
+}
+
+// tagInUse check whether any tag is defined on the device
+func (b *Service) tagInUse(device string) bool {
+	tags, _ := b.tagMap.GetAll().([]MbtcpTag)
+	for _, tag := range tags {
+		if tag.Device == device {
+			return true
+		}
+	}
+	return false
+}
+
+// tagReadRequest translate tag read into one-off read request
+func (b *Service) tagReadRequest(req MbtcpTagOpReq) (MbtcpReadReq, error) {
+	tag, device, err := b.lookupTag(req.Name)
+	if err != nil {
+		return MbtcpReadReq{}, err
+	}
+	return MbtcpReadReq{
+		Tid:       req.Tid,
+		From:      req.From,
+		FC:        tag.FC,
+		IP:        device.IP,
+		Port:      device.Port,
+		Slave:     device.Slave,
+		Addr:      tag.Addr,
+		Len:       tag.Len,
+		Type:      tag.Type,
+		Order:     tag.Order,
+		Range:     tag.Range,
+		TimeoutMs: req.TimeoutMs,
+		// 64-bit integers in decimal strings
+		Int64String: tag.Int64String,
+	}, nil
+}
+
+// tagWriteRequest translate tag write into one-off write request,
+// 	single or multiple coils (registers) by the length of tag.
+func (b *Service) tagWriteRequest(req MbtcpTagOpReq) (MbtcpWriteReq, error) {
+	tag, device, err := b.lookupTag(req.Name)
+	if err != nil {
+		return MbtcpWriteReq{}, err
+	}
+	if tag.Access != tagReadWrite {
+		return MbtcpWriteReq{}, ErrTagReadOnly
+	}
+
+	var fc int
+	switch tag.FC {
+	case 1: // coils
+		fc = 15
+		if tag.Len == 1 {
+			fc = 5
+		}
+	case 3: // holding registers
+		fc = 16
+		if tag.Len == 1 {
+			fc = 6
+		}
+	default: // discrete inputs and input registers
+		return MbtcpWriteReq{}, ErrTagReadOnly
+	}
+
+	var data interface{}
+	if raw, ok := req.Data.(*json.RawMessage); ok && raw != nil {
+		if data, err = parseWriteData(fc, req.Hex, tag.Type, tag.Order, *raw); err != nil {
+			return MbtcpWriteReq{}, err
+		}
+	} else {
+		return MbtcpWriteReq{}, ErrUnmarshal
+	}
+
+	return MbtcpWriteReq{
+		Tid:       req.Tid,
+		From:      req.From,
+		FC:        fc,
+		IP:        device.IP,
+		Port:      device.Port,
+		Slave:     device.Slave,
+		Addr:      tag.Addr,
+		Len:       tag.Len,
+		Data:      data,
+		Type:      tag.Type,
+		Order:     tag.Order,
+		TimeoutMs: req.TimeoutMs,
+	}, nil
+}
+
+// tagPollRequest translate tag subscription into poll request named by the tag
+func (b *Service) tagPollRequest(req MbtcpTagOpReq) (MbtcpPollStatus, error) {
+	tag, device, err := b.lookupTag(req.Name)
+	if err != nil {
+		return MbtcpPollStatus{}, err
+	}
+	return MbtcpPollStatus{
+		Tid:        req.Tid,
+		From:       req.From,
+		Name:       tag.Name,
+		Interval:   req.Interval,
+		IntervalMs: req.IntervalMs,
+		Cron:       req.Cron,
+		TimeZone:   req.TimeZone,
+		Enabled:    true,
+		FC:         tag.FC,
+		IP:         device.IP,
+		Port:       device.Port,
+		Slave:      device.Slave,
+		Addr:       tag.Addr,
+		Len:        tag.Len,
+		Type:       tag.Type,
+		Order:      tag.Order,
+		Range:      tag.Range,
+		// 64-bit integers in decimal strings
+		Int64String: tag.Int64String,
+	}, nil
+}
@@ -0,0 +1,289 @@
+package tcp
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	. "github.com/taka-wang/psmb"
+	"github.com/taka-wang/psmb/viper-conf"
+	zmq "github.com/takawang/zmq3"
+)
+
+// deviceLimit per device (ip:port) limits, zero: unlimited
+type deviceLimit struct {
+	// maxInFlight max requests sent but not answered
+	maxInFlight int
+	// maxRate max requests per second
+	maxRate int
+}
+
+// unlimited check whether the device has no limit
+func (l deviceLimit) unlimited() bool {
+	return l.maxInFlight <= 0 && l.maxRate <= 0
+}
+
+// queuedCommand downstream command waiting for sending
+type queuedCommand struct {
+	socket *zmq.Socket
+	req    interface{}
+	// priority scheduler priority of the command, higher sent first
+	priority int
+}
+
+// inFlightCommand downstream commands of the same tid sent but not answered,
+// 	i.e., the overlapping ticks of a poll.
+type inFlightCommand struct {
+	device string
+	// sent the sending time per command, oldest first
+	sent []time.Time
+}
+
+// deviceQueue per device request queue
+type deviceQueue struct {
+	limit deviceLimit
+	// pending queued commands by priority, FIFO within the same priority
+	pending  []queuedCommand
+	inFlight int
+	dropped  int
+	// last the time of the last sending
+	last time.Time
+	// timer rate limited drain, nil if not scheduled
+	timer *time.Timer
+}
+
+// throttle per device request queues: (ip:port, queue)
+type throttle struct {
+	sync.Mutex
+	queues map[string]*deviceQueue
+	// inFlight (downstream tid, in-flight commands)
+	inFlight map[string]*inFlightCommand
+	// defaults default device limits
+	defaults deviceLimit
+	// limits per device limit overrides
+	limits map[string]deviceLimit
+	// maxQueue max queued requests per device
+	maxQueue int
+	// ready send the commands drained asynchronously
+	ready func([]queuedCommand)
+}
+
+// newThrottle create per device request queues with the default and per device limits
+func newThrottle(defaults deviceLimit, limits map[string]deviceLimit, maxQueue int, ready func([]queuedCommand)) *throttle {
+	return &throttle{
+		queues:   make(map[string]*deviceQueue),
+		inFlight: make(map[string]*inFlightCommand),
+		defaults: defaults,
+		limits:   limits,
+		maxQueue: maxQueue,
+		ready:    ready,
+	}
+}
+
+// deviceLimits load per device limits from config of the family keys: (ip:port, limit)
+func deviceLimits(key func(string) string) map[string]deviceLimit {
+	limits := make(map[string]deviceLimit)
+	for device, v := range conf.GetStringMapString(key(keyDeviceInFlight)) {
+		limit := limits[device]
+		limit.maxInFlight, _ = strconv.Atoi(v)
+		limits[device] = limit
+	}
+	for device, v := range conf.GetStringMapString(key(keyDeviceRate)) {
+		limit := limits[device]
+		limit.maxRate, _ = strconv.Atoi(v)
+		limits[device] = limit
+	}
+	return limits
+}
+
+// commandDevice get the downstream tid and device (ip:port) of the command
+func commandDevice(req interface{}) (string, string, bool) {
+	switch r := req.(type) {
+	case DMbtcpReadReq:
+		return r.Tid, r.IP + ":" + r.Port, true
+	case DMbtcpWriteReq:
+		return r.Tid, r.IP + ":" + r.Port, true
+	default: // not device specific
+		return "", "", false
+	}
+}
+
+// limit get the limit of the device; the caller should hold the lock.
+func (t *throttle) limit(device string) deviceLimit {
+	if limit, ok := t.limits[device]; ok {
+		return limit
+	}
+	return t.defaults
+}
+
+// enqueue queue the command of limited devices by priority,
+// 	return the commands ready to send now, false if the command is dropped by the full queue.
+func (t *throttle) enqueue(socket *zmq.Socket, req interface{}, priority int) ([]queuedCommand, bool) {
+	command := queuedCommand{socket, req, priority}
+	_, device, ok := commandDevice(req)
+	if !ok {
+		return []queuedCommand{command}, true
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	limit := t.limit(device)
+	if limit.unlimited() {
+		return []queuedCommand{command}, true
+	}
+
+	q, ok := t.queues[device]
+	if !ok {
+		q = &deviceQueue{limit: limit}
+		t.queues[device] = q
+	}
+	if t.maxQueue > 0 && len(q.pending) >= t.maxQueue {
+		q.dropped++
+		conf.Log.WithField("device", device).Warn(ErrDeviceQueueFull.Error())
+		return t.drain(device, time.Now()), false
+	}
+	// after the commands of the same or higher priority
+	idx := sort.Search(len(q.pending), func(i int) bool { return q.pending[i].priority < priority })
+	q.pending = append(q.pending, queuedCommand{})
+	copy(q.pending[idx+1:], q.pending[idx:])
+	q.pending[idx] = command
+	return t.drain(device, time.Now()), true
+}
+
+// done release the in-flight command answered by downstream,
+// 	return the commands ready to send now.
+func (t *throttle) done(tid string) []queuedCommand {
+	t.Lock()
+	defer t.Unlock()
+	command, ok := t.inFlight[tid]
+	if !ok {
+		return nil
+	}
+	// answered in order
+	if command.sent = command.sent[1:]; len(command.sent) == 0 {
+		delete(t.inFlight, tid)
+	}
+	t.queues[command.device].inFlight--
+	return t.drain(command.device, time.Now())
+}
+
+// expire release in-flight commands sent before the deadline and never answered,
+// 	return the commands ready to send now.
+func (t *throttle) expire(deadline time.Time) []queuedCommand {
+	t.Lock()
+	defer t.Unlock()
+	devices := make(map[string]bool)
+	for tid, command := range t.inFlight {
+		sent := command.sent[:0]
+		for _, at := range command.sent {
+			if at.Before(deadline) {
+				t.queues[command.device].inFlight--
+				devices[command.device] = true
+			} else {
+				sent = append(sent, at)
+			}
+		}
+		if command.sent = sent; len(sent) == 0 {
+			delete(t.inFlight, tid)
+		}
+	}
+
+	var ready []queuedCommand
+	now := time.Now()
+	for device := range devices {
+		ready = append(ready, t.drain(device, now)...)
+	}
+	return ready
+}
+
+// drain pop the commands of the device within limits,
+// 	schedule the next drain if rate limited; the caller should hold the lock.
+func (t *throttle) drain(device string, now time.Time) []queuedCommand {
+	q := t.queues[device]
+	var ready []queuedCommand
+	for len(q.pending) > 0 {
+		if q.limit.maxInFlight > 0 && q.inFlight >= q.limit.maxInFlight {
+			break
+		}
+		if q.limit.maxRate > 0 {
+			if wait := time.Second/time.Duration(q.limit.maxRate) - now.Sub(q.last); wait > 0 {
+				if q.timer == nil {
+					q.timer = time.AfterFunc(wait, func() {
+						t.Lock()
+						q.timer = nil
+						ready := t.drain(device, time.Now())
+						t.Unlock()
+						if len(ready) > 0 {
+							t.ready(ready)
+						}
+					})
+				}
+				break
+			}
+		}
+
+		command := q.pending[0]
+		q.pending = q.pending[1:]
+		tid, _, _ := commandDevice(command.req)
+		if _, ok := t.inFlight[tid]; !ok {
+			t.inFlight[tid] = &inFlightCommand{device: device}
+		}
+		t.inFlight[tid].sent = append(t.inFlight[tid].sent, now)
+		q.inFlight++
+		q.last = now
+		ready = append(ready, command)
+	}
+	return ready
+}
+
+// status get the queue status of limited devices sorted by device
+func (t *throttle) status() []MbtcpQueueStatus {
+	t.Lock()
+	devices := make([]string, 0, len(t.queues))
+	for device := range t.queues {
+		devices = append(devices, device)
+	}
+	sort.Strings(devices)
+	queues := make([]MbtcpQueueStatus, 0, len(devices))
+	for _, device := range devices {
+		q := t.queues[device]
+		queues = append(queues, MbtcpQueueStatus{
+			Device:      device,
+			Depth:       len(q.pending),
+			InFlight:    q.inFlight,
+			Dropped:     q.dropped,
+			MaxInFlight: q.limit.maxInFlight,
+			MaxRate:     q.limit.maxRate,
+		})
+	}
+	t.Unlock()
+	return queues
+}
+
+// release release the in-flight command of the downstream response
+func (b *Service) release(res interface{}) {
+	var tid string
+	switch r := res.(type) {
+	case DMbtcpRes:
+		tid = r.Tid
+	default: // not device specific
+		return
+	}
+	b.releaseTid(tid)
+}
+
+// releaseTid release the in-flight command of the downstream tid, i.e., answered or timed out
+func (b *Service) releaseTid(tid string) {
+	if ready := b.throttle.done(tid); len(ready) > 0 {
+		b.throttle.ready(ready)
+	}
+}
+
+// resend send the commands drained asynchronously by the scheduler as emergency requests,
+// 	so that the downstream socket is only used by the scheduler.
+func (b *Service) resend(commands []queuedCommand) {
+	for _, command := range commands {
+		b.scheduler.Emergency().Priority(command.priority).Do(b.send, command.socket, command.req)
+	}
+}
@@ -0,0 +1,60 @@
+package tcp
+
+import (
+	"testing"
+
+	. "github.com/taka-wang/psmb"
+	"github.com/takawang/sugar"
+)
+
+func TestThrottleQueue(t *testing.T) {
+	s := sugar.New(t)
+
+	s.Assert("Queued commands should be sent by priority, in order within a priority", func(logf sugar.Log) bool {
+		q := newThrottle(deviceLimit{maxInFlight: 1}, nil, 0, func([]queuedCommand) {})
+		read := func(tid string) DMbtcpReadReq {
+			return DMbtcpReadReq{Tid: tid, IP: "127.0.0.1", Port: "502"}
+		}
+		if ready, ok := q.enqueue(nil, read("1"), priorityRead); !ok || len(ready) != 1 {
+			logf("ready:%v, ok:%v", ready, ok)
+			return false
+		}
+		for _, c := range []struct {
+			tid      string
+			priority int
+		}{
+			{"2", prioritySlowPoll},
+			{"3", priorityRead},
+			{"4", priorityWrite},
+			{"5", priorityFastPoll},
+			{"6", priorityWrite},
+			{"7", prioritySlowPoll},
+		} {
+			if ready, ok := q.enqueue(nil, read(c.tid), c.priority); !ok || len(ready) != 0 {
+				logf("ready:%v, ok:%v", ready, ok)
+				return false
+			}
+		}
+
+		var order []string
+		for tid := "1"; ; {
+			ready := q.done(tid)
+			if len(ready) != 1 {
+				break
+			}
+			tid, _, _ = commandDevice(ready[0].req)
+			order = append(order, tid)
+		}
+		logf("order:%v", order)
+		desire := []string{"4", "6", "3", "5", "2", "7"}
+		if len(order) != len(desire) {
+			return false
+		}
+		for idx := range desire {
+			if order[idx] != desire[idx] {
+				return false
+			}
+		}
+		return true
+	})
+}
@@ -0,0 +1,386 @@
+package tcp
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/taka-wang/psmb"
+	"github.com/taka-wang/psmb/viper-conf"
+	zmq "github.com/takawang/zmq3"
+)
+
+// @Implement IUpstreamTransport contract implicitly
+
+// zmqTransport default upstream transport over ZMQ PUB/SUB,
+// 	with an optional ROUTER endpoint for addressed request/reply.
+type zmqTransport struct {
+	// mutex for publisher and routing tables; zmq socket is not thread-safe
+	sync.Mutex
+	// pub upstream publisher to services
+	pub *zmq.Socket
+	// sub upstream subscriber from services
+	sub *zmq.Socket
+	// router upstream request/reply endpoint, nil if disabled
+	router *zmq.Socket
+	// senders bound identity of senders with pending requests: (from, sender)
+	senders map[string]*boundSender
+	// pending pending router requests: (router tid, request)
+	pending map[string]routedRequest
+	// keys router tids of pending requests: ((from, tid), router tid)
+	keys map[routeKey]string
+	// outbox router replies, sent by the receiver goroutine
+	outbox chan []string
+	// enable receiver flag
+	enable bool
+	// key config key of the command family
+	key func(string) string
+}
+
+// routerHeader common fields of requests and responses for routing
+type routerHeader struct {
+	Tid  json.Number `json:"tid"`
+	From string      `json:"from"`
+}
+
+// routerReply error reply to the router request sender
+type routerReply struct {
+	Tid    json.RawMessage `json:"tid"`
+	Status string          `json:"status"`
+}
+
+// routeKey router request key: (from, client tid)
+type routeKey struct {
+	from string
+	tid  string
+}
+
+// routedRequest pending router request
+type routedRequest struct {
+	key routeKey
+	// cmd request command
+	cmd string
+	// deadline reply timeout to the sender if not answered before
+	deadline time.Time
+	// tid client tid in raw json
+	tid json.RawMessage
+	// envelope identity frames of the sender
+	envelope []string
+}
+
+// boundSender the identity bound to the sender (from) until all its requests are answered
+type boundSender struct {
+	identity string
+	pending  int
+}
+
+// newZmqTransport create zmq upstream transport with endpoints of the family config keys
+func newZmqTransport(key func(string) string) (IUpstreamTransport, error) {
+	pub, err := zmq.NewSocket(zmq.PUB)
+	if err != nil {
+		conf.Log.WithError(err).Fatal("Fail to create upstream publisher")
+		return nil, err
+	}
+	sub, err := zmq.NewSocket(zmq.SUB)
+	if err != nil {
+		conf.Log.WithError(err).Fatal("Fail to create upstream subscriber")
+		return nil, err
+	}
+	t := &zmqTransport{
+		pub:     pub,
+		sub:     sub,
+		senders: make(map[string]*boundSender),
+		pending: make(map[string]routedRequest),
+		keys:    make(map[routeKey]string),
+		outbox:  make(chan []string, conf.GetInt(key(keyZmqRouterMaxPending))),
+		key:     key,
+	}
+	if conf.GetString(key(keyZmqRouterUpstream)) != "" {
+		if t.router, err = zmq.NewSocket(zmq.ROUTER); err != nil {
+			conf.Log.WithError(err).Fatal("Fail to create upstream router")
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Start bind endpoints and receive requests from services
+func (t *zmqTransport) Start(handler func(msg []string)) error {
+	conf.Log.Debug("Start ZMQ upstream transport")
+
+	if err := t.pub.Bind(conf.GetString(t.key(keyZmqPubUpstream))); err != nil {
+		conf.Log.WithError(err).Error("Fail to bind upstream publisher")
+		return err
+	}
+	if err := t.sub.Bind(conf.GetString(t.key(keyZmqSubUpstream))); err != nil {
+		conf.Log.WithError(err).Error("Fail to bind upstream subscriber")
+		return err
+	}
+	if err := t.sub.SetSubscribe(""); err != nil {
+		conf.Log.WithError(err).Error("Fail to set upstream subscriber's filter")
+		return err
+	}
+
+	poller := zmq.NewPoller()
+	poller.Add(t.sub, zmq.POLLIN)
+	if t.router != nil {
+		if err := t.router.Bind(conf.GetString(t.key(keyZmqRouterUpstream))); err != nil {
+			conf.Log.WithError(err).Error("Fail to bind upstream router")
+			return err
+		}
+		poller.Add(t.router, zmq.POLLIN)
+	}
+
+	t.enable = true
+	go func() {
+		for t.enable {
+			sockets, _ := poller.Poll(routerPollTimeout)
+			for _, socket := range sockets {
+				switch s := socket.Socket; s {
+				case t.sub:
+					if msg, err := t.sub.RecvMessage(0); err == nil && t.enable {
+						handler(msg)
+					}
+				case t.router:
+					if msg, err := t.router.RecvMessage(0); err == nil && t.enable {
+						handler(t.route(msg))
+					}
+				}
+			}
+			t.expire(time.Now())
+			t.flush()
+		}
+	}()
+	return nil
+}
+
+// route remember the envelope of router request by sender and client tid,
+// 	return the request frames without envelope; the client tid is replaced by
+// 	a unique router tid, so that senders with the same tid never collide.
+func (t *zmqTransport) route(msg []string) []string {
+	// [identity, (empty delimiter), cmd, json]
+	if len(msg) < 3 {
+		return nil // drop by handler
+	}
+	envelope, frames := msg[:len(msg)-2], msg[len(msg)-2:]
+
+	var h routerHeader
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(frames[1]), &h); err != nil || h.Tid == "" {
+		return frames // reply to publisher by default
+	}
+	if err := json.Unmarshal([]byte(frames[1]), &body); err != nil {
+		return frames
+	}
+	from := h.From
+	if from == "" {
+		from = envelope[0] // identity
+	}
+	key := routeKey{from, h.Tid.String()}
+
+	t.Lock()
+	defer t.Unlock()
+	if len(t.pending) >= conf.GetInt(t.key(keyZmqRouterMaxPending)) {
+		conf.Log.WithError(ErrTooManyRequests).Warn("Reply router request by publisher")
+		return frames
+	}
+	sender, ok := t.senders[from]
+	if ok && sender.identity != envelope[0] {
+		t.reject(envelope, frames[0], body["tid"], ErrSenderConflict)
+		return nil
+	}
+	if _, dup := t.keys[key]; dup {
+		t.reject(envelope, frames[0], body["tid"], ErrDuplicateRequest)
+		return nil
+	}
+
+	TidStr := strconv.FormatInt(NewUpstreamTid(), 10)
+	req := routedRequest{
+		key:      key,
+		cmd:      frames[0],
+		deadline: time.Now().Add(time.Duration(conf.GetInt64(t.key(keyZmqRouterTimeout))) * time.Millisecond),
+		tid:      body["tid"],
+		envelope: append([]string{}, envelope...),
+	}
+	body["tid"] = json.RawMessage(TidStr)
+	bytes, err := json.Marshal(body)
+	if err != nil {
+		return frames
+	}
+
+	if !ok {
+		sender = &boundSender{identity: envelope[0]}
+		t.senders[from] = sender
+	}
+	sender.pending++
+	t.keys[key] = TidStr
+	t.pending[TidStr] = req
+	return []string{frames[0], string(bytes)}
+}
+
+// reject reply the error to the router request sender; the caller should hold the lock.
+func (t *zmqTransport) reject(envelope []string, cmd string, tid json.RawMessage, err error) {
+	conf.Log.WithError(err).Warn("Reject router request")
+	bytes, _ := json.Marshal(routerReply{Tid: tid, Status: err.Error()})
+	select {
+	case t.outbox <- append(append([]string{}, envelope...), cmd, string(bytes)):
+	default:
+		conf.Log.WithError(ErrTooManyRequests).Warn("Drop router reply")
+	}
+}
+
+// answer stop tracking the router request of the router tid,
+// 	unbind the sender if all its requests are answered.
+func (t *zmqTransport) answer(TidStr string) (routedRequest, bool) {
+	t.Lock()
+	defer t.Unlock()
+	req, ok := t.pending[TidStr]
+	if ok {
+		t.release(TidStr, req)
+	}
+	return req, ok
+}
+
+// release stop tracking the router request, unbind the sender if it has no pending request;
+// 	the caller should hold the lock.
+func (t *zmqTransport) release(TidStr string, req routedRequest) {
+	delete(t.pending, TidStr)
+	delete(t.keys, req.key)
+	if sender := t.senders[req.key.from]; sender != nil {
+		if sender.pending--; sender.pending <= 0 {
+			delete(t.senders, req.key.from)
+		}
+	}
+}
+
+// expire reply timeout with the client tid to the senders of router requests
+// 	not answered before deadline, and stop tracking them.
+func (t *zmqTransport) expire(now time.Time) {
+	t.Lock()
+	defer t.Unlock()
+	for TidStr, req := range t.pending {
+		if now.After(req.deadline) {
+			t.release(TidStr, req)
+			t.reject(req.envelope, req.cmd, req.tid, ErrRequestTimeout)
+		}
+	}
+}
+
+// flush send queued router replies
+func (t *zmqTransport) flush() {
+	for {
+		select {
+		case msg := <-t.outbox:
+			if _, err := t.router.SendMessage(msg); err != nil {
+				conf.Log.WithError(err).Warn("Fail to send router reply")
+			}
+		default:
+			return
+		}
+	}
+}
+
+// Stop unbind endpoints
+func (t *zmqTransport) Stop() {
+	conf.Log.Debug("Stop ZMQ upstream transport")
+	t.enable = false
+
+	if err := t.pub.Unbind(conf.GetString(t.key(keyZmqPubUpstream))); err != nil {
+		conf.Log.WithError(err).Debug("Fail to unbind upstream publisher")
+	}
+	if err := t.sub.Unbind(conf.GetString(t.key(keyZmqSubUpstream))); err != nil {
+		conf.Log.WithError(err).Debug("Fail to unbind upstream subscriber")
+	}
+	if t.router != nil {
+		if err := t.router.Unbind(conf.GetString(t.key(keyZmqRouterUpstream))); err != nil {
+			conf.Log.WithError(err).Debug("Fail to unbind upstream router")
+		}
+	}
+}
+
+// Send reply to the router request sender only,
+// 	otherwise publish frames to services.
+func (t *zmqTransport) Send(msg []string) error {
+	if len(msg) != 2 {
+		return ErrInvalidMessageLength
+	}
+
+	var h routerHeader
+	if t.router != nil && json.Unmarshal([]byte(msg[1]), &h) == nil && h.Tid != "" {
+		if req, ok := t.answer(h.Tid.String()); ok {
+			// restore the client tid
+			reply := strings.Replace(msg[1], `"tid":`+h.Tid.String(), `"tid":`+string(req.tid), 1)
+			select {
+			case t.outbox <- append(append([]string{}, req.envelope...), msg[0], reply):
+				return nil
+			default:
+				return ErrTooManyRequests
+			}
+		}
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	if _, err := t.pub.Send(msg[0], zmq.SNDMORE); err != nil { // frame 1
+		return err
+	}
+	_, err := t.pub.Send(msg[1], 0) // frame 2
+	return err
+}
+
+// multiTransport fan out to several upstream transports
+type multiTransport []IUpstreamTransport
+
+// newUpstreamTransport create upstream transport(s) by comma separated names,
+// 	the built-in zmq transport is named `ZMQ` and used by default.
+func newUpstreamTransport(names string, key func(string) string) (IUpstreamTransport, error) {
+	var transports multiTransport
+	for _, name := range strings.Split(names, ",") {
+		var tp IUpstreamTransport
+		var err error
+		switch name = strings.TrimSpace(name); name {
+		case "", zmqTransportName:
+			tp, err = newZmqTransport(key)
+		default:
+			tp, err = UpstreamTransportCreator(name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		transports = append(transports, tp)
+	}
+	if len(transports) == 1 {
+		return transports[0], nil
+	}
+	return transports, nil
+}
+
+// Start start all transports with the same handler
+func (m multiTransport) Start(handler func(msg []string)) error {
+	for _, tp := range m {
+		if err := tp.Start(handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stop stop all transports
+func (m multiTransport) Stop() {
+	for _, tp := range m {
+		tp.Stop()
+	}
+}
+
+// Send send frames to all transports, return the first error
+func (m multiTransport) Send(msg []string) error {
+	var ret error
+	for _, tp := range m {
+		if err := tp.Send(msg); err != nil && ret == nil {
+			ret = err
+		}
+	}
+	return ret
+}
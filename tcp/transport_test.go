@@ -0,0 +1,131 @@
+package tcp
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/taka-wang/psmb"
+	"github.com/takawang/sugar"
+)
+
+// nextReply pop the queued router reply, nil if none
+func nextReply(t *zmqTransport) []string {
+	select {
+	case msg := <-t.outbox:
+		return msg
+	default:
+		return nil
+	}
+}
+
+// routerTid get the router tid of the routed request
+func routerTid(frames []string) string {
+	var h routerHeader
+	if len(frames) != 2 || json.Unmarshal([]byte(frames[1]), &h) != nil {
+		return ""
+	}
+	return h.Tid.String()
+}
+
+func TestRouter(t *testing.T) {
+	s := sugar.New(t)
+
+	tp, err := newZmqTransport(mbtcpFamily{}.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := tp.(*zmqTransport)
+
+	s.Assert("Router requests with the same tid from two clients should not cross-talk", func(logf sugar.Log) bool {
+		a := routerTid(router.route([]string{"idA", "", CmdMbtcpOnceRead, `{"tid":7,"fc":3}`}))
+		b := routerTid(router.route([]string{"idB", "", CmdMbtcpOnceRead, `{"tid":7,"fc":3}`}))
+		logf("a:%s, b:%s", a, b)
+		if a == "" || b == "" || a == b || a == "7" {
+			return false
+		}
+
+		router.Send([]string{CmdMbtcpOnceRead, `{"tid":` + b + `,"status":"b"}`})
+		router.Send([]string{CmdMbtcpOnceRead, `{"tid":` + a + `,"status":"a"}`})
+		rb, ra := nextReply(router), nextReply(router)
+		logf("b:%v, a:%v", rb, ra)
+		return len(rb) == 4 && rb[0] == "idB" && rb[3] == `{"tid":7,"status":"b"}` &&
+			len(ra) == 4 && ra[0] == "idA" && ra[3] == `{"tid":7,"status":"a"}`
+	})
+
+	s.Assert("Publisher replies with the client tid of router request should not be diverted", func(logf sugar.Log) bool {
+		a := routerTid(router.route([]string{"idA", "", CmdMbtcpOnceRead, `{"tid":8,"fc":3}`}))
+		router.Send([]string{CmdMbtcpOnceRead, `{"tid":8,"status":"pub"}`})
+		if msg := nextReply(router); msg != nil {
+			logf("msg:%v", msg)
+			return false
+		}
+		router.Send([]string{CmdMbtcpOnceRead, `{"tid":` + a + `,"status":"ok"}`})
+		msg := nextReply(router)
+		logf("msg:%v", msg)
+		return len(msg) == 4 && msg[0] == "idA" && msg[3] == `{"tid":8,"status":"ok"}`
+	})
+
+	s.Assert("Sender bound to another identity should be rejected", func(logf sugar.Log) bool {
+		a := routerTid(router.route([]string{"idA", "", CmdMbtcpOnceRead, `{"tid":9,"from":"web"}`}))
+		if frames := router.route([]string{"idB", "", CmdMbtcpOnceRead, `{"tid":10,"from":"web"}`}); frames != nil {
+			logf("frames:%v", frames)
+			return false
+		}
+		msg := nextReply(router)
+		logf("msg:%v", msg)
+		if len(msg) != 4 || msg[0] != "idB" || msg[3] != `{"tid":10,"status":"`+ErrSenderConflict.Error()+`"}` {
+			return false
+		}
+
+		// the sender is unbound once answered
+		router.Send([]string{CmdMbtcpOnceRead, `{"tid":` + a + `,"status":"ok"}`})
+		nextReply(router)
+		b := routerTid(router.route([]string{"idB", "", CmdMbtcpOnceRead, `{"tid":10,"from":"web"}`}))
+		router.Send([]string{CmdMbtcpOnceRead, `{"tid":` + b + `,"status":"ok"}`})
+		msg = nextReply(router)
+		logf("msg:%v", msg)
+		return len(msg) == 4 && msg[0] == "idB"
+	})
+
+	s.Assert("Pending requests of the same sender and tid should be rejected", func(logf sugar.Log) bool {
+		a := routerTid(router.route([]string{"idA", "", CmdMbtcpOnceRead, `{"tid":11,"from":"web"}`}))
+		if frames := router.route([]string{"idA", "", CmdMbtcpOnceRead, `{"tid":11,"from":"web"}`}); frames != nil {
+			return false
+		}
+		msg := nextReply(router)
+		logf("msg:%v", msg)
+		router.Send([]string{CmdMbtcpOnceRead, `{"tid":` + a + `,"status":"ok"}`})
+		nextReply(router)
+		return len(msg) == 4 && msg[3] == `{"tid":11,"status":"`+ErrDuplicateRequest.Error()+`"}`
+	})
+
+	s.Assert("Expired requests should be replied with the client tid and unbind the sender", func(logf sugar.Log) bool {
+		routerTid(router.route([]string{"idA", "", CmdMbtcpOnceRead, `{"tid":12,"from":"web"}`}))
+		router.expire(time.Now())
+		if msg := nextReply(router); msg != nil {
+			logf("msg:%v", msg)
+			return false
+		}
+		router.expire(time.Now().Add(time.Hour))
+		msg := nextReply(router)
+		logf("msg:%v", msg)
+		if len(msg) != 4 || msg[0] != "idA" || msg[2] != CmdMbtcpOnceRead || msg[3] != `{"tid":12,"status":"`+ErrRequestTimeout.Error()+`"}` {
+			return false
+		}
+
+		// reconnect with another identity
+		b := routerTid(router.route([]string{"idB", "", CmdMbtcpOnceRead, `{"tid":12,"from":"web"}`}))
+		router.Send([]string{CmdMbtcpOnceRead, `{"tid":` + b + `,"status":"ok"}`})
+		msg = nextReply(router)
+		logf("msg:%v", msg)
+		return len(msg) == 4 && msg[0] == "idB" && msg[3] == `{"tid":12,"status":"ok"}`
+	})
+
+	s.Assert("Answered requests should leave no route", func(logf sugar.Log) bool {
+		router.Lock()
+		defer router.Unlock()
+		logf("senders:%d, pending:%d, keys:%d", len(router.senders), len(router.pending), len(router.keys))
+		return len(router.senders) == 0 && len(router.pending) == 0 && len(router.keys) == 0
+	})
+}
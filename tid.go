@@ -0,0 +1,15 @@
+package psmb
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// upstreamTid last upstream transaction id, shared by all transports of the process
+var upstreamTid = time.Now().UTC().UnixNano() // avoid collision after restart
+
+// NewUpstreamTid generate unique transaction id for transports correlating replies by tid,
+// 	transports fanned out by the same service never generate the same tid.
+func NewUpstreamTid() int64 {
+	return atomic.AddInt64(&upstreamTid, 1)
+}
@@ -0,0 +1,34 @@
+package psmb
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/takawang/sugar"
+)
+
+func TestUpstreamTid(t *testing.T) {
+
+	s := sugar.New(t)
+
+	s.Assert("Tids generated concurrently should be unique", func(logf sugar.Log) bool {
+		var mutex sync.Mutex
+		var wg sync.WaitGroup
+		tids := make(map[int64]bool)
+		for idx := 0; idx < 4; idx++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for count := 0; count < 100; count++ {
+					tid := NewUpstreamTid()
+					mutex.Lock()
+					tids[tid] = true
+					mutex.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		logf("tids:%d", len(tids))
+		return len(tids) == 400
+	})
+}
@@ -27,6 +27,12 @@ type (
 
 	// FilterType filter type
 	FilterType int
+
+	// FilterMode defines which elements of the data a filter is applied to
+	FilterMode int
+
+	// AlarmLevel alarm limit level
+	AlarmLevel int
 )
 
 // MarshalJSON implements the Marshaler interface on JSONableByteSlice (i.e., uint8/byte array).
@@ -76,6 +82,27 @@ const (
 	MidLittleEndian
 )
 
+// 64-bits Endian
+const (
+	_ Endian = iota // ignore first value by assigning to blank identifier
+	// ABCDEFGH 64-bit words may be represented in big-endian format
+	ABCDEFGH
+	// HGFEDCBA 64-bit words may be represented in little-endian format
+	HGFEDCBA
+	// BADCFEHG 64-bit words may be represented in byte swapped 16-bit words
+	BADCFEHG
+	// GHEFCDAB 64-bit words may be represented in reversed 16-bit words
+	GHEFCDAB
+	// CDABGHEF 64-bit words may be represented in mid-little-endian (CDAB) 32-bit halves
+	CDABGHEF
+	// EFGHABCD 64-bit words may be represented in swapped 32-bit halves
+	EFGHABCD
+	// DCBAHGFE 64-bit words may be represented in little-endian (DCBA) 32-bit halves
+	DCBAHGFE
+	// FEHGBADC 64-bit words may be represented in swapped 32-bit halves of byte swapped 16-bit words
+	FEHGBADC
+)
+
 // Register value type for read function
 const (
 	_ RegValueType = iota // ignore first value by assigning to blank identifier
@@ -95,6 +122,12 @@ const (
 	Int32
 	// Float32 float32 array
 	Float32
+	// Int64 int64 array
+	Int64
+	// UInt64 uint64 array
+	UInt64
+	// Float64 float64 array
+	Float64
 )
 
 // Filter value type
@@ -119,4 +152,56 @@ const (
 	OutsideRange
 	// OutsideIncRange outside range (include)
 	OutsideIncRange
+	// Deadband absolute deadband against the last published value
+	Deadband
+	// PercentDeadband percentage deadband against the last published value
+	PercentDeadband
+	// Hysteresis enter/exit thresholds against the last published state
+	Hysteresis
+	// RateOfChange rate of change (units per second) against the latest history
+	RateOfChange
+	// Stale value unchanged for N polls
+	Stale
+)
+
+// Filter mode
+const (
+	// FirstElement apply filter to the first element only (default)
+	FirstElement FilterMode = iota
+	// AnyElement pass if any element matches
+	AnyElement
+	// AllElements pass if all elements match
+	AllElements
+	// IndexElement apply filter to the element at the specified index
+	IndexElement
+)
+
+// Alarm limit level
+const (
+	// Normal value inside all limits
+	Normal AlarmLevel = iota
+	// High value >= high limit
+	High
+	// HighHigh value >= high-high limit
+	HighHigh
+	// Low value <= low limit
+	Low
+	// LowLow value <= low-low limit
+	LowLow
+)
+
+// Alarm transition
+const (
+	// AlarmActive alarm becomes active
+	AlarmActive = "active"
+	// AlarmCleared alarm becomes cleared
+	AlarmCleared = "cleared"
+)
+
+// Device health state
+const (
+	// DeviceOnline device responds normally
+	DeviceOnline = "online"
+	// DeviceOffline device fails consecutively, polls back off
+	DeviceOffline = "offline"
 )
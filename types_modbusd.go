@@ -65,4 +65,54 @@ type (
 		// Timeout set timeout request and get timeout response only.
 		Timeout int64 `json:"timeout,omitempty"`
 	}
+
+	// DMbrtuReadReq downstream modbus rtu read request
+	DMbrtuReadReq struct {
+		// Tid unique transaction id in `string` format
+		Tid string `json:"tid"`
+		// Cmd modbusd command type: https://github.com/taka-wang/modbusd#command-mapping-table
+		Cmd int `json:"cmd"`
+		// Device serial device path of the modbus rtu slave, ex: /dev/ttyUSB0
+		Device string `json:"device"`
+		// Baud serial baud rate
+		Baud int `json:"baud"`
+		// Parity serial parity: N, E or O
+		Parity string `json:"parity"`
+		// DataBits serial data bits
+		DataBits int `json:"data_bits"`
+		// StopBits serial stop bits
+		StopBits int `json:"stop_bits"`
+		// Slave device id of the modbus rtu slave
+		Slave uint8 `json:"slave"`
+		// Addr start address for read
+		Addr uint16 `json:"addr"`
+		// Len the length of registers or bits
+		Len uint16 `json:"len"`
+	}
+
+	// DMbrtuWriteReq downstream modbus rtu write single bit/register request
+	DMbrtuWriteReq struct {
+		// Tid unique transaction id in `string` format
+		Tid string `json:"tid"`
+		// Cmd modbusd command type: https://github.com/taka-wang/modbusd#command-mapping-table
+		Cmd int `json:"cmd"`
+		// Device serial device path of the modbus rtu slave, ex: /dev/ttyUSB0
+		Device string `json:"device"`
+		// Baud serial baud rate
+		Baud int `json:"baud"`
+		// Parity serial parity: N, E or O
+		Parity string `json:"parity"`
+		// DataBits serial data bits
+		DataBits int `json:"data_bits"`
+		// StopBits serial stop bits
+		StopBits int `json:"stop_bits"`
+		// Slave device id of the modbus rtu slave
+		Slave uint8 `json:"slave"`
+		// Addr start address for write
+		Addr uint16 `json:"addr"`
+		// Len omit for fc5, fc6
+		Len uint16 `json:"len,omitempty"`
+		// Data should be []uint16, uint16 (FC5, FC6)
+		Data interface{} `json:"data"`
+	}
 )
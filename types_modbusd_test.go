@@ -133,4 +133,50 @@ func TestDownstreamStruct(t *testing.T) {
 		logf(r1)
 		return true
 	})
+
+	s.Title("modbus rtu downstream struct tests")
+
+	s.Assert("`rtu read` request test", func(logf sugar.Log) bool {
+		req := DMbrtuReadReq{
+			Tid:      "123456",
+			Cmd:      3,
+			Device:   "/dev/ttyUSB0",
+			Baud:     9600,
+			Parity:   "N",
+			DataBits: 8,
+			StopBits: 1,
+			Slave:    22,
+			Addr:     250,
+			Len:      10,
+		}
+		reqStr, err := json.Marshal(req)
+		if err != nil {
+			return false
+		}
+		logf(string(reqStr))
+		return true
+	})
+
+	s.Assert("`rtu multiple write` request test", func(logf sugar.Log) bool {
+		req := DMbrtuWriteReq{
+			Tid:      "123456",
+			Cmd:      16,
+			Device:   "/dev/ttyUSB0",
+			Baud:     9600,
+			Parity:   "N",
+			DataBits: 8,
+			StopBits: 1,
+			Slave:    22,
+			Addr:     250,
+			Len:      4,
+			Data:     []uint16{1, 2, 3, 4},
+		}
+		reqStr, err := json.Marshal(req)
+		if err != nil {
+			return false
+		}
+		logf(string(reqStr))
+		return true
+	})
 }
+
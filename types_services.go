@@ -25,6 +25,20 @@ type (
 		Type  RegValueType `json:"type,omitempty"`
 		Order Endian       `json:"order,omitempty"`
 		Range *ScaleRange  `json:"range,omitempty"` // point to struct can be omitted in json encode
+		// Int64String 64-bit integers in decimal strings, JSON numbers beyond 2^53 lose precision
+		Int64String bool `json:"int64_string,omitempty"`
+		// TimeoutMs reply timeout in ms, zero: default timeout
+		TimeoutMs int64 `json:"timeout_ms,omitempty"`
+		// Retry retry policy, nil: no retry
+		Retry *RetryPolicy `json:"retry,omitempty"`
+	}
+
+	// RetryPolicy one-off request retry policy.
+	// Backoff is doubled for each retry, empty Statuses means any failure is retryable.
+	RetryPolicy struct {
+		MaxAttempts int      `json:"max_attempts"`
+		Backoff     int64    `json:"backoff_ms,omitempty"`
+		Statuses    []string `json:"statuses,omitempty"`
 	}
 
 	// MbtcpWriteReq write coil/register request
@@ -39,6 +53,14 @@ type (
 		Len   uint16      `json:"len,omitempty"`
 		Hex   bool        `json:"hex,omitempty"`
 		Data  interface{} `json:"data"`
+		// Type value type of numeric register data (FC6, FC16), zero: uint16 registers
+		Type RegValueType `json:"type,omitempty"`
+		// Order byte order of numeric register data (FC6, FC16)
+		Order Endian `json:"order,omitempty"`
+		// TimeoutMs reply timeout in ms, zero: default timeout
+		TimeoutMs int64 `json:"timeout_ms,omitempty"`
+		// Retry retry policy, nil: no retry
+		Retry *RetryPolicy `json:"retry,omitempty"`
 	}
 
 	// MbtcpTimeoutReq set/get TCP connection timeout request (1.3, 1.4)
@@ -58,21 +80,26 @@ type (
 
 	// MbtcpPollStatus polling coil/register request;
 	MbtcpPollStatus struct {
-		Tid      int64        `json:"tid,omitempty"`
-		From     string       `json:"from,omitempty"`
-		Name     string       `json:"name"`
-		Interval uint64       `json:"interval"`
-		Enabled  bool         `json:"enabled"`
-		FC       int          `json:"fc"`
-		IP       string       `json:"ip"`
-		Port     string       `json:"port,omitempty"`
-		Slave    uint8        `json:"slave"`
-		Addr     uint16       `json:"addr"`
-		Status   string       `json:"status,omitempty"` // 2.3.2 response only
-		Len      uint16       `json:"len,omitempty"`
-		Type     RegValueType `json:"type,omitempty"`
-		Order    Endian       `json:"order,omitempty"`
-		Range    *ScaleRange  `json:"range,omitempty"` // point to struct can be omitted in json encode
+		Tid        int64        `json:"tid,omitempty"`
+		From       string       `json:"from,omitempty"`
+		Name       string       `json:"name"`
+		Interval   uint64       `json:"interval"`
+		IntervalMs uint64       `json:"interval_ms,omitempty"` // overrides interval (in seconds) if set
+		Cron       string       `json:"cron,omitempty"`        // cron expression, overrides intervals if set
+		TimeZone   string       `json:"timezone,omitempty"`    // time zone of cron expression, default: local
+		Enabled    bool         `json:"enabled"`
+		FC         int          `json:"fc"`
+		IP         string       `json:"ip"`
+		Port       string       `json:"port,omitempty"`
+		Slave      uint8        `json:"slave"`
+		Addr       uint16       `json:"addr"`
+		Status     string       `json:"status,omitempty"` // 2.3.2 response only
+		Len        uint16       `json:"len,omitempty"`
+		Type       RegValueType `json:"type,omitempty"`
+		Order      Endian       `json:"order,omitempty"`
+		Range      *ScaleRange  `json:"range,omitempty"` // point to struct can be omitted in json encode
+		// Int64String 64-bit integers in decimal strings, JSON numbers beyond 2^53 lose precision
+		Int64String bool `json:"int64_string,omitempty"`
 	}
 
 	// MbtcpPollData read coil/register response (1.1).
@@ -90,11 +117,12 @@ type (
 
 	// MbtcpPollOpReq generic modbus tcp poll operation request
 	MbtcpPollOpReq struct {
-		Tid      int64  `json:"tid"`
-		From     string `json:"from,omitempty"`
-		Name     string `json:"name,omitempty"`
-		Interval uint64 `json:"interval,omitempty"`
-		Enabled  bool   `json:"enabled,omitempty"`
+		Tid        int64  `json:"tid"`
+		From       string `json:"from,omitempty"`
+		Name       string `json:"name,omitempty"`
+		Interval   uint64 `json:"interval,omitempty"`
+		IntervalMs uint64 `json:"interval_ms,omitempty"` // overrides interval (in seconds) if set
+		Enabled    bool   `json:"enabled,omitempty"`
 	}
 
 	// MbtcpPollsStatus requests status
@@ -113,6 +141,8 @@ type (
 		Enabled bool       `json:"enabled"`
 		Type    FilterType `json:"type,omitempty"`
 		Arg     []float32  `json:"arg,omitempty"`
+		Mode    FilterMode `json:"mode,omitempty"`
+		Index   int        `json:"index,omitempty"`
 		Status  string     `json:"status,omitempty"`
 	}
 
@@ -132,6 +162,286 @@ type (
 		Filters []MbtcpFilterStatus `json:"filters"`
 	}
 
+	// MbtcpAlarmStatus alarm rule and state.
+	// 	Limits are optional, leave them empty to disable.
+	MbtcpAlarmStatus struct {
+		Tid          int64      `json:"tid,omitempty"`
+		From         string     `json:"from,omitempty"`
+		Name         string     `json:"name"`
+		Poll         string     `json:"poll"`
+		Index        int        `json:"index,omitempty"`
+		Enabled      bool       `json:"enabled"`
+		HighHigh     *float32   `json:"hh,omitempty"`
+		High         *float32   `json:"h,omitempty"`
+		Low          *float32   `json:"l,omitempty"`
+		LowLow       *float32   `json:"ll,omitempty"`
+		Severity     int        `json:"severity,omitempty"`
+		Latch        bool       `json:"latch,omitempty"`
+		Level        AlarmLevel `json:"level,omitempty"`
+		Active       bool       `json:"active,omitempty"`
+		Acked        bool       `json:"acked,omitempty"`
+		ShelvedUntil int64      `json:"shelved_until,omitempty"`
+		Value        float64    `json:"value,omitempty"`
+		TimeStamp    int64      `json:"ts,omitempty"`
+		Status       string     `json:"status,omitempty"`
+	}
+
+	// MbtcpAlarmOpReq generic modbus tcp alarm operation request
+	MbtcpAlarmOpReq struct {
+		Tid  int64  `json:"tid"`
+		From string `json:"from,omitempty"`
+		Name string `json:"name,omitempty"`
+		// Duration shelve duration in second, 0 to unshelve
+		Duration uint64 `json:"duration,omitempty"`
+	}
+
+	// MbtcpAlarmsStatus alarms status
+	MbtcpAlarmsStatus struct {
+		Tid    int64              `json:"tid"`
+		From   string             `json:"from,omitempty"`
+		Status string             `json:"status"`
+		Alarms []MbtcpAlarmStatus `json:"alarms"`
+	}
+
+	// MbtcpAlarmEvent alarm transition published on the `mbtcp.alarm` frame
+	MbtcpAlarmEvent struct {
+		TimeStamp int64      `json:"ts"`
+		Name      string     `json:"name"`
+		Poll      string     `json:"poll"`
+		State     string     `json:"state"`
+		Level     AlarmLevel `json:"level"`
+		Severity  int        `json:"severity,omitempty"`
+		Value     float64    `json:"value"`
+		Acked     bool       `json:"acked,omitempty"`
+	}
+
+	// MbtcpTagDevice device definition of tags
+	MbtcpTagDevice struct {
+		Tid    int64  `json:"tid,omitempty"`
+		From   string `json:"from,omitempty"`
+		Name   string `json:"name"`
+		IP     string `json:"ip"`
+		Port   string `json:"port,omitempty"`
+		Slave  uint8  `json:"slave"`
+		Order  Endian `json:"order,omitempty"` // default byte order of tags
+		Status string `json:"status,omitempty"`
+	}
+
+	// MbtcpTag named point mapped onto device registers
+	MbtcpTag struct {
+		Tid    int64        `json:"tid,omitempty"`
+		From   string       `json:"from,omitempty"`
+		Name   string       `json:"name"`
+		Device string       `json:"device"`
+		FC     int          `json:"fc"` // read function code
+		Addr   uint16       `json:"addr"`
+		Len    uint16       `json:"len,omitempty"` // default by type
+		Type   RegValueType `json:"type,omitempty"`
+		Order  Endian       `json:"order,omitempty"` // overrides device order
+		Range  *ScaleRange  `json:"range,omitempty"`
+		Units  string       `json:"units,omitempty"`
+		Access string       `json:"access,omitempty"` // "r" or "rw"
+		Status string       `json:"status,omitempty"`
+		// Int64String 64-bit integers in decimal strings
+		Int64String bool `json:"int64_string,omitempty"`
+	}
+
+	// MbtcpTagOpReq tag operation request: read, write, subscribe or delete by name.
+	// 	Data for write only; schedule for subscribe only.
+	MbtcpTagOpReq struct {
+		Tid        int64       `json:"tid"`
+		From       string      `json:"from,omitempty"`
+		Name       string      `json:"name,omitempty"`
+		Hex        bool        `json:"hex,omitempty"`
+		Data       interface{} `json:"data,omitempty"`
+		Interval   uint64      `json:"interval,omitempty"`
+		IntervalMs uint64      `json:"interval_ms,omitempty"`
+		Cron       string      `json:"cron,omitempty"`
+		TimeZone   string      `json:"timezone,omitempty"`
+		TimeoutMs  int64       `json:"timeout_ms,omitempty"`
+	}
+
+	// MbtcpTagsStatus tags and devices of tag database
+	MbtcpTagsStatus struct {
+		Tid     int64            `json:"tid"`
+		From    string           `json:"from,omitempty"`
+		Status  string           `json:"status"`
+		Devices []MbtcpTagDevice `json:"devices,omitempty"`
+		Tags    []MbtcpTag       `json:"tags,omitempty"`
+	}
+
+	// MbtcpProfileBlock register block of device profile, instantiated as a poll
+	// 	(and the filter of the poll if any).
+	MbtcpProfileBlock struct {
+		Name       string             `json:"name"`
+		Interval   uint64             `json:"interval,omitempty"`
+		IntervalMs uint64             `json:"interval_ms,omitempty"`
+		Cron       string             `json:"cron,omitempty"`
+		TimeZone   string             `json:"timezone,omitempty"`
+		FC         int                `json:"fc"`
+		Addr       uint16             `json:"addr"`
+		Len        uint16             `json:"len,omitempty"`
+		Type       RegValueType       `json:"type,omitempty"`
+		Order      Endian             `json:"order,omitempty"`
+		Range      *ScaleRange        `json:"range,omitempty"`
+		Filter     *MbtcpFilterStatus `json:"filter,omitempty"`
+		// Int64String 64-bit integers in decimal strings
+		Int64String bool `json:"int64_string,omitempty"`
+	}
+
+	// MbtcpProfile device profile, i.e., register blocks of a device model
+	MbtcpProfile struct {
+		Name   string              `json:"name,omitempty"`
+		Blocks []MbtcpProfileBlock `json:"blocks"`
+	}
+
+	// MbtcpProfileReq instantiate the profile for the device,
+	// 	polls (and filters) are named by the prefix and block name.
+	MbtcpProfileReq struct {
+		Tid     int64  `json:"tid"`
+		From    string `json:"from,omitempty"`
+		Profile string `json:"profile"`
+		Prefix  string `json:"prefix"`
+		IP      string `json:"ip"`
+		Port    string `json:"port,omitempty"`
+		Slave   uint8  `json:"slave"`
+	}
+
+	// MbtcpDevicesReq device health operation request
+	MbtcpDevicesReq struct {
+		Tid  int64  `json:"tid"`
+		From string `json:"from,omitempty"`
+	}
+
+	// MbtcpDeviceStatus device (IP:port:slave) health status
+	MbtcpDeviceStatus struct {
+		IP       string `json:"ip"`
+		Port     string `json:"port"`
+		Slave    uint8  `json:"slave"`
+		State    string `json:"state"`
+		Failures int    `json:"failures"` // consecutive failures
+		// LastStatus last response status
+		LastStatus string `json:"last_status,omitempty"`
+		// LastSuccess last success timestamp in nanoseconds
+		LastSuccess int64 `json:"last_success,omitempty"`
+		// NextPoll polls are skipped until this timestamp in nanoseconds, offline only
+		NextPoll int64 `json:"next_poll,omitempty"`
+	}
+
+	// MbtcpDevicesStatus devices health status
+	MbtcpDevicesStatus struct {
+		Tid     int64               `json:"tid"`
+		From    string              `json:"from,omitempty"`
+		Status  string              `json:"status"`
+		Devices []MbtcpDeviceStatus `json:"devices"`
+	}
+
+	// MbtcpQueuesReq device queue operation request
+	MbtcpQueuesReq struct {
+		Tid  int64  `json:"tid"`
+		From string `json:"from,omitempty"`
+	}
+
+	// MbtcpQueueStatus request queue status of rate limited device (IP:port)
+	MbtcpQueueStatus struct {
+		Device      string `json:"device"`
+		Depth       int    `json:"depth"`     // queued requests
+		InFlight    int    `json:"in_flight"` // requests sent but not answered
+		Dropped     int    `json:"dropped"`   // requests dropped by full queue
+		MaxInFlight int    `json:"max_in_flight"`
+		MaxRate     int    `json:"max_rate"` // requests per second
+	}
+
+	// MbtcpQueuesStatus device queues status
+	MbtcpQueuesStatus struct {
+		Tid    int64              `json:"tid"`
+		From   string             `json:"from,omitempty"`
+		Status string             `json:"status"`
+		Queues []MbtcpQueueStatus `json:"queues"`
+	}
+
+	// MbtcpDeviceEvent device health transition published on the `mbtcp.device.status` frame
+	MbtcpDeviceEvent struct {
+		TimeStamp int64  `json:"ts"`
+		IP        string `json:"ip"`
+		Port      string `json:"port"`
+		Slave     uint8  `json:"slave"`
+		State     string `json:"state"`
+		Failures  int    `json:"failures"`
+		Status    string `json:"status"` // response status caused the transition
+	}
+
+	// MbrtuReadReq read coil/register request over modbus RTU.
+	// Serial fields left empty are filled with the configured defaults.
+	MbrtuReadReq struct {
+		Tid      int64        `json:"tid"`
+		From     string       `json:"from,omitempty"`
+		FC       int          `json:"fc"`
+		Device   string       `json:"device,omitempty"`
+		Baud     int          `json:"baud,omitempty"`
+		Parity   string       `json:"parity,omitempty"`
+		DataBits int          `json:"data_bits,omitempty"`
+		StopBits int          `json:"stop_bits,omitempty"`
+		Slave    uint8        `json:"slave"`
+		Addr     uint16       `json:"addr"`
+		Len      uint16       `json:"len,omitempty"`
+		Type     RegValueType `json:"type,omitempty"`
+		Order    Endian       `json:"order,omitempty"`
+		Range    *ScaleRange  `json:"range,omitempty"` // point to struct can be omitted in json encode
+	}
+
+	// MbrtuWriteReq write coil/register request over modbus RTU
+	MbrtuWriteReq struct {
+		Tid      int64       `json:"tid"`
+		From     string      `json:"from,omitempty"`
+		FC       int         `json:"fc"`
+		Device   string      `json:"device,omitempty"`
+		Baud     int         `json:"baud,omitempty"`
+		Parity   string      `json:"parity,omitempty"`
+		DataBits int         `json:"data_bits,omitempty"`
+		StopBits int         `json:"stop_bits,omitempty"`
+		Slave    uint8       `json:"slave"`
+		Addr     uint16      `json:"addr"`
+		Len      uint16      `json:"len,omitempty"`
+		Hex      bool        `json:"hex,omitempty"`
+		Data     interface{} `json:"data"`
+	}
+
+	// MbrtuPollStatus polling coil/register request over modbus RTU;
+	MbrtuPollStatus struct {
+		Tid        int64        `json:"tid,omitempty"`
+		From       string       `json:"from,omitempty"`
+		Name       string       `json:"name"`
+		Interval   uint64       `json:"interval"`
+		IntervalMs uint64       `json:"interval_ms,omitempty"` // overrides interval (in seconds) if set
+		Cron       string       `json:"cron,omitempty"`        // cron expression, overrides intervals if set
+		TimeZone   string       `json:"timezone,omitempty"`    // time zone of cron expression, default: local
+		Enabled    bool         `json:"enabled"`
+		FC         int          `json:"fc"`
+		Device     string       `json:"device,omitempty"`
+		Baud       int          `json:"baud,omitempty"`
+		Parity     string       `json:"parity,omitempty"`
+		DataBits   int          `json:"data_bits,omitempty"`
+		StopBits   int          `json:"stop_bits,omitempty"`
+		Slave      uint8        `json:"slave"`
+		Addr       uint16       `json:"addr"`
+		Status     string       `json:"status,omitempty"` // response only
+		Len        uint16       `json:"len,omitempty"`
+		Type       RegValueType `json:"type,omitempty"`
+		Order      Endian       `json:"order,omitempty"`
+		Range      *ScaleRange  `json:"range,omitempty"` // point to struct can be omitted in json encode
+		// Int64String 64-bit integers in decimal strings, JSON numbers beyond 2^53 lose precision
+		Int64String bool `json:"int64_string,omitempty"`
+	}
+
+	// MbrtuPollsStatus requests status over modbus RTU
+	MbrtuPollsStatus struct {
+		Tid    int64             `json:"tid,omitempty"`
+		From   string            `json:"from,omitempty"`
+		Status string            `json:"status,omitempty"`
+		Polls  []MbrtuPollStatus `json:"polls"`
+	}
+
 	//
 	// response ============================
 	//
@@ -146,6 +456,8 @@ type (
 		// Bytes FC3, FC4 and Type 2~8 only
 		Bytes JSONableByteSlice `json:"bytes,omitempty"`
 		Data  interface{}       `json:"data,omitempty"` // universal data container
+		// Attempts the number of attempts, with retry policy only
+		Attempts int `json:"attempts,omitempty"`
 	}
 
 	// MbtcpWriteRes == MbtcpSimpleRes
@@ -159,8 +471,9 @@ type (
 
 	// MbtcpSimpleRes generic modbus tcp response
 	MbtcpSimpleRes struct {
-		Tid    int64  `json:"tid,omitempty"`
-		Status string `json:"status"`
+		Tid      int64  `json:"tid,omitempty"`
+		Status   string `json:"status"`
+		Attempts int    `json:"attempts,omitempty"` // with retry policy only
 	}
 
 	// MbtcpPollRes == MbtcpSimpleRes
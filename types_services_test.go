@@ -530,4 +530,53 @@ func TestUpstreamStructTest(t *testing.T) {
 		return true
 	})
 
+	s.Title("One-off modbus rtu struct tests")
+
+	s.Assert("`mbrtu.once.read` request test", func(logf sugar.Log) bool {
+		input :=
+			`{
+                "from": "web",
+                "tid": 123456,
+                "fc" : 3,
+                "device": "/dev/ttyUSB0",
+                "baud": 19200,
+                "parity": "E",
+                "stop_bits": 1,
+                "slave": 1,
+                "addr": 10,
+                "len": 4,
+                "type": 8,
+                "order": 1
+            }`
+		var r1 MbrtuReadReq
+		if err := json.Unmarshal([]byte(input), &r1); err != nil {
+			logf("json err:", err)
+			return false
+		}
+		logf(r1)
+		return r1.Device == "/dev/ttyUSB0" && r1.Baud == 19200 && r1.Parity == "E" && r1.StopBits == 1
+	})
+
+	s.Assert("`mbrtu.poll.create` request test", func(logf sugar.Log) bool {
+		input :=
+			`{
+                "from": "web",
+                "tid": 123456,
+                "name": "rtu_poll",
+                "interval": 2,
+                "enabled": true,
+                "fc" : 4,
+                "device": "/dev/ttyS1",
+                "slave": 3,
+                "addr": 100,
+                "len": 2
+            }`
+		var r1 MbrtuPollStatus
+		if err := json.Unmarshal([]byte(input), &r1); err != nil {
+			logf("json err:", err)
+			return false
+		}
+		logf(r1)
+		return r1.Name == "rtu_poll" && r1.Device == "/dev/ttyS1" && r1.Baud == 0
+	})
 }
@@ -83,6 +83,11 @@ func GetBool(key string) bool {
 	return base.v.GetBool(key)
 }
 
+// GetStringMapString returns the value associated with the key as a map of strings
+func GetStringMapString(key string) map[string]string {
+	return base.v.GetStringMapString(key)
+}
+
 /*
 // GetFloat64 returns the value associated with the key as a float64
 func GetFloat64(key string) float64 {
@@ -36,6 +36,8 @@ func TestLogger(t *testing.T) {
 		logf(b)
 		d := GetDuration("redis.idel_timeout")
 		logf(d)
+		m := GetStringMapString("psmbtcp.device_rate")
+		logf(m)
 
 		return true
 	})